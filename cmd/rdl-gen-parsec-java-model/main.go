@@ -626,7 +626,7 @@ func (gen *javaModelGenerator) generateStructFields(fields []*rdl.StructFieldDef
 
 			if genAnnotations {
 				if len(f.Annotations) == 0 {
-					f.Annotations = utils.GetUserDefinedTypeAnnotations(f.Type, gen.schema.Types)
+					f.Annotations = utils.GetUserDefinedTypeAnnotations(f.Type, gen.schema)
 				}
 				fannotations = append(fannotations, f.Annotations)
 				for extendedKey, value := range f.Annotations {
@@ -731,7 +731,7 @@ func (gen *javaModelGenerator) generateStructFieldType(rdlType rdl.TypeRef, opti
 }
 
 func (gen *javaModelGenerator) generateStructFieldParamType(rdlType rdl.TypeRef, optional bool, items rdl.TypeRef, keys rdl.TypeRef) {
-	annotations := utils.GetUserDefinedTypeAnnotations(rdlType, gen.schema.Types)
+	annotations := utils.GetUserDefinedTypeAnnotations(rdlType, gen.schema)
 	if len(annotations) > 0 {
 		gen.appendToBody("\n")
 		for extendedKey, value := range annotations {
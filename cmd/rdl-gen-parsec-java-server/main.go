@@ -1027,7 +1027,7 @@ func (gen *javaServerGenerator) handlerSignature(r *rdl.Resource) string {
 		k := v.Name
 		pdecl := ""
 		if len(v.Annotations) == 0 {
-			v.Annotations = utils.GetUserDefinedTypeAnnotations(v.Type, gen.schema.Types)
+			v.Annotations = utils.GetUserDefinedTypeAnnotations(v.Type, gen.schema)
 		}
 		if v.QueryParam != "" {
 			pdecl = gen.extendedValueAnnotation(v.Annotations) + fmt.Sprintf("@QueryParam(%q) ", v.QueryParam) + defaultValueAnnotation(v.Default)
@@ -1147,7 +1147,7 @@ func (gen *javaServerGenerator) extendedValueAnnotation(annotations map[rdl.Exte
 func (gen *javaServerGenerator) generateImportClass(r *rdl.Resource) {
 	for _, v := range r.Inputs {
 		if len(v.Annotations) == 0 {
-			v.Annotations = utils.GetUserDefinedTypeAnnotations(v.Type, gen.schema.Types)
+			v.Annotations = utils.GetUserDefinedTypeAnnotations(v.Type, gen.schema)
 		}
 		for extendedKey, value := range v.Annotations {
 			key := strings.TrimLeft(string(extendedKey), AnnotationPrefix)
@@ -1303,7 +1303,7 @@ func javaName(name rdl.Identifier) string {
 func (gen *javaServerGenerator) generateStructFieldType(rdlType rdl.TypeRef, r *rdl.Resource) string {
 	t := gen.registry.FindType(rdlType)
 	subItems := t.ArrayTypeDef.Items
-	annotations := utils.GetUserDefinedTypeAnnotations(subItems, gen.schema.Types)
+	annotations := utils.GetUserDefinedTypeAnnotations(subItems, gen.schema)
 	pdecl := ""
 	if len(annotations) > 0 {
 		pdecl = gen.extendedValueAnnotation(annotations)
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"encoding/json"
 	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
 	"os"
 )
 
@@ -57,6 +58,96 @@ func TestNoTypes(test *testing.T) {
 	}
 }
 
+func TestOpenAPISchemaOverride(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("geo")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Point").
+		Comment("A point").
+		Field("lat", "Float64", false, nil, "").
+		Field("lon", "Float64", false, nil, "").
+		OpenAPISchemaOverride(map[string]interface{}{"type": "string", "format": "geohash"}).
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Place").
+		Field("name", "String", false, nil, "").
+		Build())
+	swaggerData, err := swagger(gentest.MustBuild(sb), false, "", "", "")
+	checkErrInTest(err, "cannot generate swagger", test)
+
+	j, err := json.Marshal(swaggerData.Definitions["Point"])
+	checkErrInTest(err, "cannot marshal swagger", test)
+	if expected := `{"format":"geohash","type":"string"}`; string(j) != expected {
+		test.Errorf("override not used verbatim, real: \n%s\n, expected: \n%s\n", string(j), expected)
+	}
+	j, err = json.Marshal(swaggerData.Definitions["Place"])
+	checkErrInTest(err, "cannot marshal swagger", test)
+	if expected := `{"properties":{"name":{"type":"string","example":""}},"required":["name"]}`; string(j) != expected {
+		test.Errorf("type without override not translated, real: \n%s\n, expected: \n%s\n", string(j), expected)
+	}
+}
+
+func TestAPIKeySecurityScheme(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("reports")
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/reports").APIKeyAuth("header", "X-API-Key").Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/status").Build())
+	swaggerData, err := swagger(gentest.MustBuild(sb), false, "", "", "")
+	checkErrInTest(err, "cannot generate swagger", test)
+
+	j, err := json.Marshal(swaggerData.SecurityDefinitions)
+	checkErrInTest(err, "cannot marshal swagger", test)
+	if expected := `{"X-API-Key":{"type":"apiKey","in":"header","name":"X-API-Key"}}`; string(j) != expected {
+		test.Errorf("security definitions not generated as expected, real: \n%s\n, expected: \n%s\n", string(j), expected)
+	}
+	j, err = json.Marshal(swaggerData.Paths["/reports"]["get"].Security)
+	checkErrInTest(err, "cannot marshal swagger", test)
+	if expected := `[{"X-API-Key":[]}]`; string(j) != expected {
+		test.Errorf("operation security not generated as expected, real: \n%s\n, expected: \n%s\n", string(j), expected)
+	}
+	if swaggerData.Paths["/status"]["get"].Security != nil {
+		test.Errorf("security set for an operation without authentication")
+	}
+
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/exports").APIKeyAuth("query", "X-API-Key").Build())
+	if _, err := swagger(gentest.MustBuild(sb), false, "", "", ""); err == nil {
+		test.Errorf("expected an error for conflicting security schemes")
+	}
+}
+
+func TestServerSentEvents(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("feeds")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Post").Field("id", "String", false, nil, "").Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/feed").
+		SSEEvent("created", "Post").
+		SSEEvent("deleted", "String").
+		Build())
+	swaggerData, err := swagger(gentest.MustBuild(sb), false, "", "", "")
+	checkErrInTest(err, "cannot generate swagger", test)
+
+	action := swaggerData.Paths["/feed"]["get"]
+	if len(action.Produces) != 1 || action.Produces[0] != "text/event-stream" {
+		test.Errorf("event stream produced as %v", action.Produces)
+	}
+	j, err := json.Marshal(action.Responses["200"].Schema)
+	checkErrInTest(err, "cannot marshal swagger", test)
+	if expected := `{"$ref":"#/definitions/ServerSentEvent"}`; string(j) != expected {
+		test.Errorf("event stream response not generated as expected, real: \n%s\n, expected: \n%s\n", string(j), expected)
+	}
+	for name, expected := range map[string]string{
+		"ServerSentEvent": `{"properties":{"event":{"type":"string","enum":["created","deleted"]}},"required":["event"],"type":"object","description":"A server-sent event, its data depending on its event name","discriminator":"event"}`,
+		"created":         `{"description":"The \"created\" server-sent event","allOf":[{"$ref":"#/definitions/ServerSentEvent"},{"properties":{"data":{"$ref":"#/definitions/Post"}},"required":["data"],"type":"object"}]}`,
+		"deleted":         `{"description":"The \"deleted\" server-sent event","allOf":[{"$ref":"#/definitions/ServerSentEvent"},{"properties":{"data":{"type":"string"}},"required":["data"],"type":"object"}]}`,
+	} {
+		j, err := json.Marshal(swaggerData.Definitions[name])
+		checkErrInTest(err, "cannot marshal swagger", test)
+		if string(j) != expected {
+			test.Errorf("definition %s not generated as expected, real: \n%s\n, expected: \n%s\n", name, string(j), expected)
+		}
+	}
+
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/posts").SSEEvent("created", "String").Build())
+	if _, err := swagger(gentest.MustBuild(sb), false, "", "", ""); err == nil {
+		test.Errorf("expected an error for conflicting event data")
+	}
+}
+
 func checkErrInTest(err error, msg string, test *testing.T) {
 	if err != nil {
 		test.Error(msg)
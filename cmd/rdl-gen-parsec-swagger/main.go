@@ -17,6 +17,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -148,8 +149,10 @@ func swagger(schema *rdl.Schema, genParsecError bool, swaggerScheme string, fina
 	if schema.Comment != "" {
 		swag.Info.Description = schema.Comment
 	}
+	sseEvents := make(map[string]rdl.TypeRef)
 	if len(schema.Resources) > 0 {
 		paths := make(map[string]map[string]*SwaggerAction)
+		securityDefs := make(map[string]*SwaggerSecurityScheme)
 		for _, r := range schema.Resources {
 			path := r.Path
 			actions, ok := paths[path]
@@ -228,7 +231,29 @@ func swagger(schema *rdl.Schema, genParsecError bool, swaggerScheme string, fina
 					addSwaggerResponse(reg, responses, rdl.TypeRef(errType), sym, errdef.Comment)
 				}
 			}
+			if len(r.SSEEvents) > 0 {
+				action.Produces = []string{"text/event-stream"}
+				responses[rdl.StatusCode(expected)].Schema = &SwaggerType{Ref: "#/definitions/" + ServerSentEventDefinition}
+				for _, ev := range r.SSEEvents {
+					if prev, ok := sseEvents[ev.EventName]; ok && prev != ev.PayloadType {
+						return nil, fmt.Errorf("server-sent event %s declared with both %s and %s data", ev.EventName, prev, ev.PayloadType)
+					}
+					sseEvents[ev.EventName] = ev.PayloadType
+				}
+			}
 			action.Responses = responses
+			if key := r.APIKeyAuth; key != nil {
+				scheme := key.Scheme
+				if scheme == "" {
+					scheme = key.Name
+				}
+				def := &SwaggerSecurityScheme{Type: "apiKey", In: key.In, Name: key.Name}
+				if prev, ok := securityDefs[scheme]; ok && *prev != *def {
+					return nil, fmt.Errorf("API key security scheme %s declared as both %s %s and %s %s", scheme, prev.In, prev.Name, def.In, def.Name)
+				}
+				securityDefs[scheme] = def
+				action.Security = []map[string][]string{{scheme: {}}}
+			}
 			//responses -> r.expected and r.exceptions
 			//security -> r.auth
 			//r.outputs?
@@ -239,6 +264,9 @@ func swagger(schema *rdl.Schema, genParsecError bool, swaggerScheme string, fina
 			paths[path] = actions
 		}
 		swag.Paths = paths
+		if len(securityDefs) > 0 {
+			swag.SecurityDefinitions = securityDefs
+		}
 	}
 
 	//always generate Definitions for ResourceError
@@ -251,6 +279,10 @@ func swagger(schema *rdl.Schema, genParsecError bool, swaggerScheme string, fina
 		}
 	}
 
+	if err := addServerSentEvents(reg, defs, sseEvents); err != nil {
+		return nil, err
+	}
+
 	genResourceError(defs)
 
 	if genParsecError {
@@ -262,6 +294,53 @@ func swagger(schema *rdl.Schema, genParsecError bool, swaggerScheme string, fina
 	return swag, nil
 }
 
+// ServerSentEventDefinition is the definition of the events streamed by the
+// resources with server-sent events, discriminated by their event name: each
+// event has a definition named after it, holding its data.
+const ServerSentEventDefinition = "ServerSentEvent"
+
+func addServerSentEvents(reg rdl.TypeRegistry, defs map[string]*SwaggerType, events map[string]rdl.TypeRef) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range append([]string{ServerSentEventDefinition}, names...) {
+		if _, ok := defs[name]; ok {
+			return fmt.Errorf("server-sent event definition %s conflicts with a type of the same name", name)
+		}
+	}
+	props := orderedmap.New()
+	props.Set("event", &SwaggerType{Type: "string", Enum: names})
+	defs[ServerSentEventDefinition] = &SwaggerType{
+		Type:          "object",
+		Description:   "A server-sent event, its data depending on its event name",
+		Discriminator: "event",
+		Required:      []string{"event"},
+		Properties:    props,
+	}
+	for _, name := range names {
+		ptype, pformat, ref := makeSwaggerTypeRef(reg, events[name])
+		data := &SwaggerType{Type: ptype, Format: pformat}
+		if ref != nil {
+			data.Ref = ref.Ref
+		}
+		dataProps := orderedmap.New()
+		dataProps.Set("data", data)
+		defs[name] = &SwaggerType{
+			Description: fmt.Sprintf("The %q server-sent event", name),
+			AllOf: []*SwaggerType{
+				{Ref: "#/definitions/" + ServerSentEventDefinition},
+				{Type: "object", Required: []string{"data"}, Properties: dataProps},
+			},
+		}
+	}
+	return nil
+}
+
 func genResourceError(defs map[string]*SwaggerType) {
 	props := orderedmap.New()
 	codeType := new(SwaggerType)
@@ -372,6 +451,10 @@ func makeSwaggerTypeDef(reg rdl.TypeRegistry, t *rdl.Type) *SwaggerType {
 	switch t.Variant {
 	case rdl.TypeVariantStructTypeDef:
 		typedef := t.StructTypeDef
+		if typedef.OpenAPISchemaOverride != nil {
+			st.Override = typedef.OpenAPISchemaOverride
+			break
+		}
 		st.Description = typedef.Comment
 		props := orderedmap.New()
 		var required []string
@@ -521,14 +604,15 @@ func makeSwaggerTypeDef(reg rdl.TypeRegistry, t *rdl.Type) *SwaggerType {
 
 // SwaggerDoc is a representation of the top level object in swagger 2.0
 type SwaggerDoc struct {
-	Swagger     string                               `json:"swagger"`
-	Info        *SwaggerInfo                         `json:"info"`
-	Host        string                               `json:"host,omitempty" rdl:"optional"`
-	BasePath    string                               `json:"basePath"`
-	Schemes     []string                             `json:"schemes"`
-	Paths       map[string]map[string]*SwaggerAction `json:"paths,omitempty"`
-	Security    *map[string][]string                 `json:"security,omitempty"`
-	Definitions map[string]*SwaggerType              `json:"definitions,omitempty"`
+	Swagger             string                               `json:"swagger"`
+	Info                *SwaggerInfo                         `json:"info"`
+	Host                string                               `json:"host,omitempty" rdl:"optional"`
+	BasePath            string                               `json:"basePath"`
+	Schemes             []string                             `json:"schemes"`
+	Paths               map[string]map[string]*SwaggerAction `json:"paths,omitempty"`
+	Security            *map[string][]string                 `json:"security,omitempty"`
+	SecurityDefinitions map[string]*SwaggerSecurityScheme    `json:"securityDefinitions,omitempty"`
+	Definitions         map[string]*SwaggerType              `json:"definitions,omitempty"`
 }
 
 // SwaggerInfo -
@@ -564,7 +648,14 @@ type SwaggerAction struct {
 	Produces    []string                    `json:"produces,omitempty"`
 	Parameters  []*SwaggerParameter         `json:"parameters,omitempty"`
 	Responses   map[string]*SwaggerResponse `json:"responses,omitempty"`
-	Security    map[string][]string         `json:"security,omitempty"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+// SwaggerSecurityScheme -
+type SwaggerSecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
 }
 
 // SwaggerParameter -
@@ -600,6 +691,18 @@ type SwaggerType struct {
 	Enum                 []string               `json:"enum,omitempty"`
 	AdditionalProperties *SwaggerType           `json:"additionalProperties,omitempty"`
 	Example              interface{}            `json:"example,omitempty"`
+	Discriminator        string                 `json:"discriminator,omitempty"`
+	AllOf                []*SwaggerType         `json:"allOf,omitempty"`
+	Override             map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON writes the schema override of the type verbatim, if it has one.
+func (st *SwaggerType) MarshalJSON() ([]byte, error) {
+	if st.Override != nil {
+		return json.Marshal(st.Override)
+	}
+	type swaggerType SwaggerType
+	return json.Marshal((*swaggerType)(st))
 }
 
 /*
@@ -0,0 +1,267 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package avro exports the types of RDL schemas as Avro schemas.
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+type avroRecord struct {
+	Type      string       `json:"type"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	Doc       string       `json:"doc,omitempty"`
+	Fields    []*avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Doc     string          `json:"doc,omitempty"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type avroEnum struct {
+	Type      string   `json:"type"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Doc       string   `json:"doc,omitempty"`
+	Symbols   []string `json:"symbols"`
+}
+
+type avroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type avroMap struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+type avroLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+type avroWriter struct {
+	registry  rdl.TypeRegistry
+	namespace string
+}
+
+// GenerateAvro writes the types of the schema as a JSON array of Avro named
+// types, in the namespace of the schema. Structs become records, with their
+// inherited fields first, and enums become enums. The other types are not
+// named in Avro, so they are inlined where they are referenced: arrays as
+// arrays, maps as maps, unions as unions, and the other types as the Avro type
+// of their base type. Optional fields are unions with null, defaulting to
+// null unless they have a default value.
+//
+// Avro unions cannot contain the same type twice, nor other unions: unions
+// with several variants of the same base type are an error, as are fields of
+// types without Avro equivalent, that is Any, Struct and maps not keyed by
+// strings.
+func GenerateAvro(s *rdl.Schema, w io.Writer) error {
+	aw := &avroWriter{registry: rdl.NewTypeRegistry(s), namespace: string(s.Namespace)}
+	types := make([]interface{}, 0)
+	for _, t := range s.Types {
+		switch t.Variant {
+		case rdl.TypeVariantStructTypeDef:
+			record, err := aw.record(t)
+			if err != nil {
+				return err
+			}
+			types = append(types, record)
+		case rdl.TypeVariantEnumTypeDef:
+			types = append(types, aw.enum(t))
+		case rdl.TypeVariantUnionTypeDef:
+			//unions are not named, check them here for the benefit of unused ones
+			if _, err := aw.avroType(rdl.TypeRef(t.UnionTypeDef.Name), "", ""); err != nil {
+				return err
+			}
+		}
+	}
+	j, err := json.MarshalIndent(types, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+func (aw *avroWriter) record(t *rdl.Type) (*avroRecord, error) {
+	st := t.StructTypeDef
+	record := &avroRecord{Type: "record", Name: string(st.Name), Namespace: aw.namespace, Doc: st.Comment, Fields: make([]*avroField, 0)}
+	for _, f := range utils.FlattenedFields(aw.registry, t) {
+		fieldType, err := aw.avroType(f.Type, f.Items, f.Keys)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+		}
+		field := &avroField{Name: string(f.Name), Type: fieldType, Doc: f.Comment}
+		if f.Default != nil {
+			if field.Default, err = json.Marshal(f.Default); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+			}
+		}
+		if f.Optional {
+			//the type of the default value of a union is its first type
+			if f.Default != nil {
+				field.Type = append(variants(fieldType), "null")
+			} else {
+				field.Type = append([]interface{}{"null"}, variants(fieldType)...)
+				field.Default = json.RawMessage("null")
+			}
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// variants returns the types of a union, or the type itself if it is not a
+// union.
+func variants(avroType interface{}) []interface{} {
+	if union, ok := avroType.([]interface{}); ok {
+		return union
+	}
+	return []interface{}{avroType}
+}
+
+func (aw *avroWriter) enum(t *rdl.Type) *avroEnum {
+	et := t.EnumTypeDef
+	enum := &avroEnum{Type: "enum", Name: string(et.Name), Namespace: aw.namespace, Doc: et.Comment}
+	for _, e := range et.Elements {
+		enum.Symbols = append(enum.Symbols, string(e.Symbol))
+	}
+	return enum
+}
+
+// fullName returns the name of a named type qualified by the namespace.
+func (aw *avroWriter) fullName(name rdl.TypeName) string {
+	if aw.namespace != "" {
+		return aw.namespace + "." + string(name)
+	}
+	return string(name)
+}
+
+// avroType returns the Avro type of a reference to an RDL type: the full name
+// of records and enums, and the inlined type otherwise.
+func (aw *avroWriter) avroType(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) (interface{}, error) {
+	t := aw.registry.FindType(ref)
+	if t == nil {
+		return nil, fmt.Errorf("unknown type %s", ref)
+	}
+	if t.Variant == rdl.TypeVariantAliasTypeDef {
+		return aw.avroType(t.AliasTypeDef.Type, items, keys)
+	}
+	switch aw.registry.BaseType(t) {
+	case rdl.BaseTypeArray:
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items == "" {
+			items = "Any"
+		}
+		itemType, err := aw.avroType(items, "", "")
+		if err != nil {
+			return nil, err
+		}
+		return &avroArray{Type: "array", Items: itemType}, nil
+	case rdl.BaseTypeMap:
+		if t.MapTypeDef != nil {
+			keys, items = t.MapTypeDef.Keys, t.MapTypeDef.Items
+		}
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		switch aw.registry.FindBaseType(keys) {
+		case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeEnum:
+		default:
+			return nil, fmt.Errorf("map keys of type %s are not strings", keys)
+		}
+		itemType, err := aw.avroType(items, "", "")
+		if err != nil {
+			return nil, err
+		}
+		return &avroMap{Type: "map", Values: itemType}, nil
+	case rdl.BaseTypeUnion:
+		if t.UnionTypeDef == nil {
+			return nil, fmt.Errorf("type %s has no Avro equivalent", ref)
+		}
+		return aw.union(t.UnionTypeDef)
+	case rdl.BaseTypeStruct, rdl.BaseTypeEnum:
+		if t.Variant == rdl.TypeVariantBaseType {
+			return nil, fmt.Errorf("type %s has no Avro equivalent", ref)
+		}
+		name, _, _ := rdl.TypeInfo(t)
+		return aw.fullName(name), nil
+	case rdl.BaseTypeBool:
+		return "boolean", nil
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32:
+		return "int", nil
+	case rdl.BaseTypeInt64:
+		return "long", nil
+	case rdl.BaseTypeFloat32:
+		return "float", nil
+	case rdl.BaseTypeFloat64:
+		return "double", nil
+	case rdl.BaseTypeBytes:
+		return "bytes", nil
+	case rdl.BaseTypeUUID:
+		return &avroLogical{Type: "string", LogicalType: "uuid"}, nil
+	case rdl.BaseTypeTimestamp:
+		return &avroLogical{Type: "long", LogicalType: "timestamp-millis"}, nil
+	case rdl.BaseTypeAny:
+		return nil, fmt.Errorf("type %s has no Avro equivalent", ref)
+	default:
+		return "string", nil
+	}
+}
+
+// union returns the Avro union of the variants of an RDL union, which must all
+// have distinct base types, except for records and enums of distinct names.
+func (aw *avroWriter) union(ut *rdl.UnionTypeDef) (interface{}, error) {
+	union := make([]interface{}, 0)
+	seen := make(map[string]rdl.TypeRef)
+	for _, v := range ut.Variants {
+		variantType, err := aw.avroType(v, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ut.Name, err)
+		}
+		if _, ok := variantType.([]interface{}); ok {
+			return nil, fmt.Errorf("%s: variant %s is a union", ut.Name, v)
+		}
+		key := unionKey(variantType)
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%s: variants %s and %s have the same base type %s", ut.Name, other, v, key)
+		}
+		seen[key] = v
+		union = append(union, variantType)
+	}
+	return union, nil
+}
+
+// unionKey returns what identifies a type in an Avro union: its name for
+// named and primitive types, and its type otherwise.
+func unionKey(avroType interface{}) string {
+	switch t := avroType.(type) {
+	case string:
+		return t
+	case *avroArray:
+		return t.Type
+	case *avroMap:
+		return t.Type
+	case *avroLogical:
+		return t.Type
+	}
+	return fmt.Sprint(avroType)
+}
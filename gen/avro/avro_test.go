@@ -0,0 +1,67 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package avro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// sampleSchema returns the shared sample schema without its Any fields, which
+// have no Avro equivalent.
+func sampleSchema() *rdl.Schema {
+	schema := gentest.SampleSchema()
+	for _, t := range schema.Types {
+		if t.StructTypeDef == nil {
+			continue
+		}
+		var fields []*rdl.StructFieldDef
+		for _, f := range t.StructTypeDef.Fields {
+			if f.Type != "Any" {
+				fields = append(fields, f)
+			}
+		}
+		t.StructTypeDef.Fields = fields
+	}
+	return schema
+}
+
+func TestGenerateAvro(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateAvro(sampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate Avro: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/avro/sample.avsc")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("Avro not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateAvroDuplicateVariants(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStringTypeBuilder("UserId").Build())
+	sb.AddType(rdl.NewUnionTypeBuilder("Union", "Key").Variant("UserId").Variant("String").Build())
+	var buf bytes.Buffer
+	err := GenerateAvro(gentest.MustBuild(sb), &buf)
+	if err == nil || err.Error() != "Key: variants UserId and String have the same base type string" {
+		test.Errorf("Duplicate union variants not rejected as expected: %v", err)
+	}
+}
+
+func TestGenerateAvroAny(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Event").Field("payload", "Any", false, nil, "").Build())
+	var buf bytes.Buffer
+	err := GenerateAvro(gentest.MustBuild(sb), &buf)
+	if err == nil || err.Error() != "Event.payload: type Any has no Avro equivalent" {
+		test.Errorf("Any field not rejected as expected: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package dot exports the type graph of RDL schemas as Graphviz DOT.
+package dot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ardielle/ardielle-go/rdl"
+)
+
+const banner = "parsec-rdl-gen"
+
+type dotWriter struct {
+	registry rdl.TypeRegistry
+	buf      bytes.Buffer
+}
+
+// GenerateDOT writes the types of the schema as a Graphviz DOT directed
+// graph, with a node per type labeled with its name and super type. Types
+// extending another type of the schema have a solid edge to it, and types
+// referring to other types of the schema a dashed edge to each: structs
+// labeled with the field, arrays and maps with items or keys, and unions
+// unlabeled. References to base types have no edge.
+func GenerateDOT(s *rdl.Schema, w io.Writer) error {
+	dw := &dotWriter{registry: rdl.NewTypeRegistry(s)}
+	fmt.Fprintf(&dw.buf, "// This file generated by %s\n", banner)
+	fmt.Fprintf(&dw.buf, "digraph %q {\n", string(s.Name))
+	dw.buf.WriteString("\tnode [shape=box];\n")
+	for _, t := range s.Types {
+		tName, tType, _ := rdl.TypeInfo(t)
+		fmt.Fprintf(&dw.buf, "\t%q [label=%q];\n", string(tName), fmt.Sprintf("%s: %s", tName, tType))
+	}
+	for _, t := range s.Types {
+		dw.edges(t)
+	}
+	dw.buf.WriteString("}\n")
+	_, err := w.Write(dw.buf.Bytes())
+	return err
+}
+
+func (dw *dotWriter) edges(t *rdl.Type) {
+	tName, tType, _ := rdl.TypeInfo(t)
+	if dw.defined(tType) {
+		fmt.Fprintf(&dw.buf, "\t%q -> %q;\n", string(tName), string(tType))
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		for _, f := range t.StructTypeDef.Fields {
+			for _, ref := range []rdl.TypeRef{f.Type, f.Keys, f.Items} {
+				dw.reference(tName, ref, string(f.Name))
+			}
+		}
+	case rdl.TypeVariantArrayTypeDef:
+		dw.reference(tName, t.ArrayTypeDef.Items, "items")
+	case rdl.TypeVariantMapTypeDef:
+		dw.reference(tName, t.MapTypeDef.Keys, "keys")
+		dw.reference(tName, t.MapTypeDef.Items, "items")
+	case rdl.TypeVariantUnionTypeDef:
+		for _, v := range t.UnionTypeDef.Variants {
+			dw.reference(tName, v, "")
+		}
+	}
+}
+
+// reference writes the dashed edge of a reference to a type of the schema.
+func (dw *dotWriter) reference(from rdl.TypeName, to rdl.TypeRef, label string) {
+	if !dw.defined(to) {
+		return
+	}
+	if label == "" {
+		fmt.Fprintf(&dw.buf, "\t%q -> %q [style=dashed];\n", string(from), string(to))
+	} else {
+		fmt.Fprintf(&dw.buf, "\t%q -> %q [style=dashed, label=%q];\n", string(from), string(to), label)
+	}
+}
+
+// defined returns true if the reference is to a type of the schema rather
+// than a base type.
+func (dw *dotWriter) defined(ref rdl.TypeRef) bool {
+	return ref != "" && !dw.registry.IsBaseTypeName(ref) && dw.registry.FindType(ref) != nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package elasticsearch generates Elasticsearch index mappings from RDL
+// schemas.
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/iancoleman/orderedmap"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GenerateElasticsearchMapping writes an index mapping for every struct type
+// with a full-text indexed field, keyed by the lowercased type name. String
+// fields marked FullTextIndex are mapped as text, other strings as keyword.
+func GenerateElasticsearchMapping(s *rdl.Schema, w io.Writer) error {
+	registry := rdl.NewTypeRegistry(s)
+	indices := orderedmap.New()
+	for _, t := range s.Types {
+		if t.StructTypeDef == nil || !hasFullTextField(registry, t) {
+			continue
+		}
+		properties, err := mappingProperties(registry, t, map[rdl.TypeName]bool{})
+		if err != nil {
+			return err
+		}
+		mappings := orderedmap.New()
+		mappings.Set("properties", properties)
+		index := orderedmap.New()
+		index.Set("mappings", mappings)
+		indices.Set(strings.ToLower(string(t.StructTypeDef.Name)), index)
+	}
+	if len(indices.Keys()) == 0 {
+		return fmt.Errorf("schema %s has no full-text indexed fields", s.Name)
+	}
+	j, err := json.MarshalIndent(indices, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+func hasFullTextField(registry rdl.TypeRegistry, t *rdl.Type) bool {
+	for _, f := range utils.FlattenedFields(registry, t) {
+		if f.FullTextIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingProperties returns the properties of a struct's mapping. visiting
+// holds the structs being mapped, so recursive types are mapped only once.
+func mappingProperties(registry rdl.TypeRegistry, t *rdl.Type, visiting map[rdl.TypeName]bool) (*orderedmap.OrderedMap, error) {
+	visiting[t.StructTypeDef.Name] = true
+	defer delete(visiting, t.StructTypeDef.Name)
+	properties := orderedmap.New()
+	for _, f := range utils.FlattenedFields(registry, t) {
+		ftype := f.Type
+		if registry.FindBaseType(ftype) == rdl.BaseTypeArray {
+			//Elasticsearch has no array type: any field can hold several values
+			ftype = f.Items
+			if at := registry.FindType(f.Type); at != nil && at.ArrayTypeDef != nil {
+				ftype = at.ArrayTypeDef.Items
+			}
+		}
+		property, err := mappingProperty(registry, ftype, f.FullTextIndex, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", t.StructTypeDef.Name, f.Name, err)
+		}
+		if property != nil {
+			properties.Set(string(f.Name), property)
+		}
+	}
+	return properties, nil
+}
+
+func mappingProperty(registry rdl.TypeRegistry, ref rdl.TypeRef, fullText bool, visiting map[rdl.TypeName]bool) (*orderedmap.OrderedMap, error) {
+	property := orderedmap.New()
+	t := registry.FindType(ref)
+	if t == nil {
+		return nil, fmt.Errorf("unknown type: %s", ref)
+	}
+	switch registry.BaseType(t) {
+	case rdl.BaseTypeString:
+		if fullText {
+			property.Set("type", "text")
+		} else {
+			property.Set("type", "keyword")
+		}
+	case rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeEnum:
+		property.Set("type", "keyword")
+	case rdl.BaseTypeBool:
+		property.Set("type", "boolean")
+	case rdl.BaseTypeInt8:
+		property.Set("type", "byte")
+	case rdl.BaseTypeInt16:
+		property.Set("type", "short")
+	case rdl.BaseTypeInt32:
+		property.Set("type", "integer")
+	case rdl.BaseTypeInt64:
+		property.Set("type", "long")
+	case rdl.BaseTypeFloat32:
+		property.Set("type", "float")
+	case rdl.BaseTypeFloat64:
+		property.Set("type", "double")
+	case rdl.BaseTypeTimestamp:
+		property.Set("type", "date")
+	case rdl.BaseTypeBytes:
+		property.Set("type", "binary")
+	case rdl.BaseTypeStruct:
+		if t.StructTypeDef == nil || t.StructTypeDef.Name == "Struct" {
+			property.Set("type", "object")
+			property.Set("enabled", false)
+		} else if visiting[t.StructTypeDef.Name] {
+			//a recursive reference: stop at the mapping of the enclosing struct
+			return nil, nil
+		} else {
+			properties, err := mappingProperties(registry, t, visiting)
+			if err != nil {
+				return nil, err
+			}
+			property.Set("properties", properties)
+		}
+	default:
+		//maps, unions and Any have no fixed structure to index
+		property.Set("type", "object")
+		property.Set("enabled", false)
+	}
+	return property, nil
+}
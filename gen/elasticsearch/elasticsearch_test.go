@@ -0,0 +1,57 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateElasticsearchMapping(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("articles")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Author").
+		Field("name", "String", false, nil, "").
+		Field("bio", "String", true, nil, "").
+		FullTextIndex("bio").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Article").
+		Field("id", "UUID", false, nil, "").
+		Field("title", "String", false, nil, "").
+		Field("body", "String", false, nil, "").
+		Field("published", "Timestamp", false, nil, "").
+		Field("views", "Int64", false, nil, "").
+		ArrayField("tags", "String", true, "").
+		Field("author", "Author", false, nil, "").
+		MapField("metadata", "String", "String", true, "").
+		FullTextIndex("title").
+		FullTextIndex("body").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Stats").
+		Field("count", "Int32", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateElasticsearchMapping(gentest.MustBuild(sb), &buf); err != nil {
+		test.Fatalf("cannot generate mapping: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/elasticsearch/articles_mapping.json")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("mapping not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateElasticsearchMappingNoIndex(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("stats")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Stats").Field("count", "Int32", false, nil, "").Build())
+	var buf bytes.Buffer
+	if err := GenerateElasticsearchMapping(gentest.MustBuild(sb), &buf); err == nil {
+		test.Error("expected an error for a schema without full-text indexed fields")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoCELOptions controls the generated CEL validator.
+type GoCELOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type celType struct {
+	Name        string
+	Fields      []string
+	Constraints []string
+}
+
+// GenerateGoCELValidator generates a ValidateCEL function checking values of
+// the struct types with CEL constraints satisfy them. The constraints are
+// evaluated with github.com/google/cel-go against the fields of the value,
+// each declared as a dynamically typed variable named after its JSON name.
+func GenerateGoCELValidator(s *rdl.Schema, w io.Writer, opts GoCELOptions) error {
+	registry := rdl.NewTypeRegistry(s)
+	var types []*celType
+	for _, t := range s.Types {
+		if t.StructTypeDef == nil || len(t.StructTypeDef.CELConstraints) == 0 {
+			continue
+		}
+		ct := &celType{Name: string(t.StructTypeDef.Name), Constraints: t.StructTypeDef.CELConstraints}
+		for _, f := range utils.FlattenedFields(registry, t) {
+			ct.Fields = append(ct.Fields, string(f.Name))
+		}
+		types = append(types, ct)
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("schema %s has no struct types with CEL constraints", s.Name)
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName(s, opts.Package) },
+		"types":   func() []*celType { return types },
+		"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	}
+	return executeTemplate(w, "cel", goCELValidatorTemplate, funcMap, s)
+}
+
+const goCELValidatorTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+type celType struct {
+	fields      []string
+	constraints []string
+}
+
+// celTypes holds the fields and the CEL constraints of the struct types.
+var celTypes = map[string]*celType{
+{{- range types}}
+	{{quote .Name}}: {
+		fields: []string{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{{quote $f}}{{end}}},
+		constraints: []string{
+{{- range .Constraints}}
+			{{quote .}},
+{{- end}}
+		},
+	},
+{{- end}}
+}
+
+var (
+	celOnce     sync.Once
+	celPrograms map[string][]cel.Program
+	celErr      error
+)
+
+func compileCELConstraints() {
+	celPrograms = make(map[string][]cel.Program)
+	for name, t := range celTypes {
+		var opts []cel.EnvOption
+		for _, f := range t.fields {
+			opts = append(opts, cel.Variable(f, cel.DynType))
+		}
+		env, err := cel.NewEnv(opts...)
+		if err != nil {
+			celErr = fmt.Errorf("%s: %v", name, err)
+			return
+		}
+		for _, expr := range t.constraints {
+			ast, iss := env.Compile(expr)
+			if iss.Err() != nil {
+				celErr = fmt.Errorf("%s: invalid CEL constraint %q: %v", name, expr, iss.Err())
+				return
+			}
+			prg, err := env.Program(ast)
+			if err != nil {
+				celErr = fmt.Errorf("%s: invalid CEL constraint %q: %v", name, expr, err)
+				return
+			}
+			celPrograms[name] = append(celPrograms[name], prg)
+		}
+	}
+}
+
+// ValidateCEL checks a value of the named struct type, given as the map of
+// its fields by JSON name, satisfies the CEL constraints of the type. Absent
+// fields are null. Types without constraints are always valid.
+func ValidateCEL(typeName string, fields map[string]interface{}) error {
+	celOnce.Do(compileCELConstraints)
+	if celErr != nil {
+		return celErr
+	}
+	t, ok := celTypes[typeName]
+	if !ok {
+		return nil
+	}
+	vars := make(map[string]interface{}, len(t.fields))
+	for _, f := range t.fields {
+		vars[f] = fields[f]
+	}
+	for i, prg := range celPrograms[typeName] {
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			return fmt.Errorf("%s: cannot evaluate %q: %v", typeName, t.constraints[i], err)
+		}
+		if valid, ok := out.Value().(bool); !ok || !valid {
+			return fmt.Errorf("%s: constraint not satisfied: %s", typeName, t.constraints[i])
+		}
+	}
+	return nil
+}
+`
@@ -0,0 +1,81 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateGoCELValidator(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Range").
+		Field("min", "Int32", false, nil, "").
+		Field("max", "Int32", false, nil, "").
+		CELConstraint("min <= max").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").Field("id", "String", false, nil, "").Build())
+	var buf bytes.Buffer
+	if err := GenerateGoCELValidator(gentest.MustBuild(sb), &buf, GoCELOptions{}); err != nil {
+		test.Fatalf("cannot generate CEL validator: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`"github.com/google/cel-go/cel"`,
+		"\t\"Range\": {\n\t\tfields: []string{\"min\", \"max\"},\n\t\tconstraints: []string{\n\t\t\t\"min <= max\",\n",
+		"func ValidateCEL(typeName string, fields map[string]interface{}) error",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated CEL validator is missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, `"User"`) {
+		test.Errorf("type without constraints included in the validator")
+	}
+	skipWithoutModule(test, "github.com/google/cel-go@v0.22.1")
+	runGoTest(test, map[string]string{
+		"go.mod":      "module sample\n\ngo 1.22\n\nrequire github.com/google/cel-go v0.22.1\n",
+		"cel.go":      src,
+		"cel_test.go": celTest,
+	})
+}
+
+const celTest = `package sample
+
+import "testing"
+
+func TestValidateCEL(t *testing.T) {
+	for _, c := range []struct {
+		fields map[string]interface{}
+		valid  bool
+	}{
+		{map[string]interface{}{"min": 1, "max": 2}, true},
+		{map[string]interface{}{"min": 2, "max": 2}, true},
+		{map[string]interface{}{"min": 3, "max": 2}, false},
+	} {
+		if err := ValidateCEL("Range", c.fields); (err == nil) != c.valid {
+			t.Errorf("%v: %v", c.fields, err)
+		}
+	}
+	if err := ValidateCEL("User", map[string]interface{}{"id": "jane"}); err != nil {
+		t.Errorf("type without constraints: %v", err)
+	}
+}
+`
+
+func TestCELConstraintUndefinedField(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Range").
+		Field("min", "Int32", false, nil, "").
+		Field("max", "Int32", false, nil, "").
+		CELConstraint("min <= maximum").
+		Build())
+	if _, err := sb.Build(); err == nil || !strings.Contains(err.Error(), "maximum") {
+		test.Errorf("expected a build error for the undefined field maximum, got %v", err)
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoCLIOptions controls the generated command line client.
+type GoCLIOptions struct {
+	// Package is the package of the generated file, "main" if empty.
+	Package string
+	// BaseURLFlag is the name of the flag holding the service URL, "base-url" if empty.
+	BaseURLFlag string
+	// Output is the format responses are printed in: "json" (default), "table" or "yaml".
+	Output string
+}
+
+// GenerateGoCLI generates a Cobra command line client with one command per
+// resource. Each command takes a flag per resource input and a "json" flag
+// holding the request body, so resources with a body cannot have an input
+// named "json", and resources cannot share a command name. The responses of content-addressed resources are
+// rejected unless their Content-Digest header has the SHA-256 digest of their
+// body. The commands of resources propagating the trace context inject the
+// trace context of the command in their request headers with the global
+// OpenTelemetry propagator.
+//
+// The commands of resources with a retry policy retry the requests failing
+// with a network error, a 429 or a 5xx status, waiting the exponential
+// backoff delay plus a random jitter of up to the jitter factor of this
+// delay. A multiplier of 0 keeps the delay constant.
+//
+// When resources are multi-tenant, a persistent "tenant" flag gives the
+// tenant of their requests: it sets their tenant header, or their tenant
+// path parameter when its own flag is not set. Tenants taken from a JWT
+// claim come with the bearer token instead. Inputs cannot be named "tenant"
+// then.
+func GenerateGoCLI(s *rdl.Schema, w io.Writer, opts GoCLIOptions) error {
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if opts.BaseURLFlag == "" {
+		opts.BaseURLFlag = "base-url"
+	}
+	switch opts.Output {
+	case "":
+		opts.Output = "json"
+	case "json", "table", "yaml":
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.Output)
+	}
+	multiTenant := false
+	for _, r := range s.Resources {
+		if r.MultiTenant != nil {
+			if err := checkMultiTenant(r); err != nil {
+				return fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+			}
+			multiTenant = true
+		}
+	}
+	commands := make(map[string]*rdl.Resource)
+	for _, r := range s.Resources {
+		name := methodName(r)
+		if other, ok := commands[name]; ok {
+			return fmt.Errorf("%s %s and %s %s have the same command name %s", other.Method, other.Path, r.Method, r.Path, name)
+		}
+		commands[name] = r
+		if p := r.Retry; p != nil {
+			if p.MaxAttempts < 1 || p.JitterFactor < 0 || p.JitterFactor > 1 || p.Multiplier < 0 || p.InitialDelayMillis < 0 {
+				return fmt.Errorf("%s %s: invalid retry policy", r.Method, r.Path)
+			}
+		}
+		for _, in := range cliFlagInputs(r) {
+			if multiTenant && in.Name == "tenant" {
+				return fmt.Errorf("%s %s: input tenant conflicts with the tenant flag", r.Method, r.Path)
+			}
+		}
+		if bodyInput(r) == nil {
+			continue
+		}
+		for _, in := range cliFlagInputs(r) {
+			if in.Name == "json" {
+				return fmt.Errorf("%s %s: input json conflicts with the request body flag", r.Method, r.Path)
+			}
+		}
+	}
+	funcMap := template.FuncMap{
+		"header":      func() string { return utils.GoGenerationHeader(banner) },
+		"opts":        func() GoCLIOptions { return opts },
+		"rootPath":    func() string { return utils.JavaGenerationRootPath(s) },
+		"commandName": methodName,
+		"constructor": func(r *rdl.Resource) string { return "new" + goName(methodName(r)) + "Command" },
+		"flagInputs":  cliFlagInputs,
+		"hasBody":     func(r *rdl.Resource) bool { return bodyInput(r) != nil },
+		"quote":       func(s string) string { return fmt.Sprintf("%q", s) },
+		"multiTenant": func() bool { return multiTenant },
+		"tenantParam": func(r *rdl.Resource, in *rdl.ResourceInput) bool {
+			return r.MultiTenant != nil && r.MultiTenant.TenantFrom == "path" && string(in.Name) == r.MultiTenant.TenantKey
+		},
+		"retried": func() bool {
+			for _, r := range s.Resources {
+				if r.Retry != nil {
+					return true
+				}
+			}
+			return false
+		},
+		"retryPolicy": func(p *rdl.RetryPolicy) string {
+			multiplier := p.Multiplier
+			if multiplier == 0 {
+				multiplier = 1
+			}
+			return fmt.Sprintf("retryPolicy{maxAttempts: %d, initialDelay: %d * time.Millisecond, maxDelay: %d * time.Millisecond, multiplier: %v, jitterFactor: %v}",
+				p.MaxAttempts, p.InitialDelayMillis, p.MaxDelayMillis, multiplier, p.JitterFactor)
+		},
+		"traced": func() bool {
+			for _, r := range s.Resources {
+				if r.TraceContext {
+					return true
+				}
+			}
+			return false
+		},
+		"contentAddressed": func() bool {
+			for _, r := range s.Resources {
+				if r.ContentAddressed {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	return executeTemplate(w, "cli", goCLITemplate, funcMap, s)
+}
+
+// cliFlagInputs returns the inputs that are passed as command line flags,
+// i.e. every input except the request body.
+func cliFlagInputs(r *rdl.Resource) []*rdl.ResourceInput {
+	var inputs []*rdl.ResourceInput
+	for _, in := range r.Inputs {
+		if in.PathParam || in.QueryParam != "" || in.Header != "" {
+			inputs = append(inputs, in)
+		}
+	}
+	return inputs
+}
+
+const goCLITemplate = `{{header}}
+
+package {{opts.Package}}
+
+import (
+	"bytes"
+{{- if contentAddressed}}
+	"crypto/sha256"
+	"encoding/base64"
+{{- end}}
+	"encoding/json"
+{{- if retried}}
+	"errors"
+{{- end}}
+	"fmt"
+	"io/ioutil"
+{{- if retried}}
+	"math"
+	"math/rand"
+{{- end}}
+	"net/http"
+	"net/url"
+	"os"
+{{- if eq opts.Output "table"}}
+	"sort"
+{{- end}}
+	"strings"
+{{- if eq opts.Output "table"}}
+	"text/tabwriter"
+{{- end}}
+{{- if retried}}
+	"time"
+{{- end}}
+
+	"github.com/spf13/cobra"
+{{- if traced}}
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+{{- end}}
+{{- if eq opts.Output "yaml"}}
+	"gopkg.in/yaml.v3"
+{{- end}}
+)
+
+var baseURL string
+{{- if multiTenant}}
+
+var tenant string
+{{- end}}
+
+// NewRootCommand returns the root command of the {{.Name}} client.
+func NewRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   {{quote (print .Name)}},
+		Short: {{quote .Comment}},
+	}
+	root.PersistentFlags().StringVar(&baseURL, {{quote opts.BaseURLFlag}}, {{quote (print "http://localhost:8080" rootPath)}}, "the base URL of the service")
+{{- if multiTenant}}
+	root.PersistentFlags().StringVar(&tenant, "tenant", "", "the tenant of the requests to multi-tenant resources")
+{{- end}}
+{{- range .Resources}}
+	root.AddCommand({{constructor .}}())
+{{- end}}
+	return root
+}
+{{range .Resources}}
+func {{constructor .}}() *cobra.Command {
+{{- $r := .}}
+{{- range flagInputs .}}
+	var {{.Name}}Flag string
+{{- end}}
+{{- if hasBody .}}
+	var body string
+{{- end}}
+	cmd := &cobra.Command{
+		Use:   {{quote (commandName .)}},
+		Short: {{quote .Comment}},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := {{quote .Path}}
+			query := url.Values{}
+			header := http.Header{}
+{{- with .MultiTenant}}
+{{- if eq .TenantFrom "header"}}
+			if tenant != "" {
+				header.Set({{quote .TenantKey}}, tenant)
+			}
+{{- end}}
+{{- end}}
+{{- range flagInputs .}}
+{{- if tenantParam $r .}}
+			if !cmd.Flags().Changed({{quote (print .Name)}}) {
+				{{.Name}}Flag = tenant
+			}
+			if {{.Name}}Flag == "" {
+				return fmt.Errorf("missing tenant: set the tenant or {{.Name}} flag")
+			}
+{{- end}}
+{{- if .PathParam}}
+			path = strings.Replace(path, "{{"{"}}{{.Name}}{{"}"}}", url.PathEscape({{.Name}}Flag), -1)
+{{- else if .QueryParam}}
+			if cmd.Flags().Changed({{quote (print .Name)}}) {
+				query.Set({{quote .QueryParam}}, {{.Name}}Flag)
+			}
+{{- else}}
+			if cmd.Flags().Changed({{quote (print .Name)}}) {
+				header.Set({{quote .Header}}, {{.Name}}Flag)
+			}
+{{- end}}
+{{- end}}
+{{- if .TraceContext}}
+			otel.GetTextMapPropagator().Inject(cmd.Context(), propagation.HeaderCarrier(header))
+{{- end}}
+{{- if .Retry}}
+			return invokeWithRetry({{retryPolicy .Retry}}, {{quote $r.Method}}, path, query, header, {{if hasBody .}}body{{else}}""{{end}}, {{.ContentAddressed}})
+{{- else}}
+			return invoke({{quote $r.Method}}, path, query, header, {{if hasBody .}}body{{else}}""{{end}}, {{.ContentAddressed}})
+{{- end}}
+		},
+	}
+{{- range flagInputs .}}
+	cmd.Flags().StringVar(&{{.Name}}Flag, {{quote (print .Name)}}, "", {{quote .Comment}})
+{{- if and .PathParam (not (tenantParam $r .))}}
+	cmd.MarkFlagRequired({{quote (print .Name)}})
+{{- end}}
+{{- end}}
+{{- if hasBody .}}
+	cmd.Flags().StringVar(&body, "json", "", "the request body, as JSON")
+{{- end}}
+	return cmd
+}
+{{end}}
+func invoke(method string, path string, query url.Values, header http.Header, body string, contentAddressed bool) error {
+	u := strings.TrimSuffix(baseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+{{- if contentAddressed}}
+	if contentAddressed {
+		if err := verifyContentDigest(resp.Header.Get("Content-Digest"), data); err != nil {
+			return err
+		}
+	}
+{{- end}}
+	if resp.StatusCode >= 300 {
+{{- if retried}}
+		return &statusError{code: resp.StatusCode, message: fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(data)))}
+{{- else}}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+{{- end}}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	return printResult(result)
+}
+{{- if retried}}
+
+// statusError is the error of a response with an error status.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// retryPolicy tells how requests are retried.
+type retryPolicy struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	multiplier   float64
+	jitterFactor float64
+}
+
+// delay returns the wait after the given failed attempt, from 1: the
+// exponential backoff delay, bounded by the max delay, plus a random jitter
+// of up to the jitter factor of this delay.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.initialDelay) * math.Pow(p.multiplier, float64(attempt-1))
+	if p.maxDelay > 0 && d > float64(p.maxDelay) {
+		d = float64(p.maxDelay)
+	}
+	return time.Duration(d + rand.Float64()*p.jitterFactor*d)
+}
+
+// invokeWithRetry invokes the resource, retrying the requests failing with a
+// network error, a 429 or a 5xx status.
+func invokeWithRetry(policy retryPolicy, method string, path string, query url.Values, header http.Header, body string, contentAddressed bool) error {
+	for attempt := 1; ; attempt++ {
+		err := invoke(method, path, query, header, body, contentAddressed)
+		if err == nil || attempt >= policy.maxAttempts || !retryable(err) {
+			return err
+		}
+		time.Sleep(policy.delay(attempt))
+	}
+}
+
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+{{- end}}
+{{- if contentAddressed}}
+
+// verifyContentDigest checks the SHA-256 digest of a Content-Digest header
+// (RFC 9530) against the body of its response.
+func verifyContentDigest(header string, body []byte) error {
+	sum := sha256.Sum256(body)
+	expected := base64.StdEncoding.EncodeToString(sum[:])
+	for _, digest := range strings.Split(header, ",") {
+		digest = strings.TrimSpace(digest)
+		if strings.HasPrefix(digest, "sha-256=:") && strings.HasSuffix(digest, ":") {
+			if digest[len("sha-256=:"):len(digest)-1] != expected {
+				return fmt.Errorf("response body does not match its content digest")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("response without a sha-256 content digest")
+}
+{{- end}}
+{{if eq opts.Output "json"}}
+func printResult(result interface{}) error {
+	out, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(out))
+	return nil
+}
+{{- else if eq opts.Output "yaml"}}
+func printResult(result interface{}) error {
+	out, err := yaml.Marshal(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	return nil
+}
+{{- else}}
+func printResult(result interface{}) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	switch v := result.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%s\t%v\n", key, v[key])
+		}
+	case []interface{}:
+		for i, value := range v {
+			fmt.Fprintf(tw, "%d\t%v\n", i, value)
+		}
+	default:
+		fmt.Fprintf(tw, "%v\n", v)
+	}
+	return tw.Flush()
+}
+{{- end}}
+`
@@ -0,0 +1,417 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// sampleSchema is the schema the generated client code is compiled and run
+// against. It is smaller than gentest.SampleSchema, and testdata/grpc is
+// generated from it.
+func sampleSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.Version(1)
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Field("id", "String", false, nil, "the user id").
+		Field("age", "Int32", true, nil, "the age").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Input("id", "String", true, "", "", false, nil, "the user id").
+		Input("fields", "String", false, "fields", "", true, nil, "fields to return").
+		Input("auth", "String", false, "", "Authorization", true, nil, "credentials").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "POST", "/users").
+		Input("user", "User", false, "", "", false, nil, "the user").
+		Expected("CREATED").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoCLI(test *testing.T) {
+	for _, output := range []string{"json", "table", "yaml"} {
+		var buf bytes.Buffer
+		err := GenerateGoCLI(sampleSchema(), &buf, GoCLIOptions{Output: output})
+		if err != nil {
+			test.Fatalf("cannot generate cli: %v", err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "cli.go", buf.Bytes(), 0); err != nil {
+			test.Fatalf("generated %s cli does not parse: %v", output, err)
+		}
+		src := buf.String()
+		for _, expected := range []string{
+			`func newGetUserCommand() *cobra.Command`,
+			`func newPostUserCommand() *cobra.Command`,
+			`cmd.Flags().StringVar(&idFlag, "id", "", "the user id")`,
+			`cmd.Flags().StringVar(&fieldsFlag, "fields", "", "fields to return")`,
+			`cmd.Flags().StringVar(&authFlag, "auth", "", "credentials")`,
+			`cmd.Flags().StringVar(&body, "json", "", "the request body, as JSON")`,
+			`"base-url", "http://localhost:8080/sample/v1"`,
+		} {
+			if !strings.Contains(src, expected) {
+				test.Errorf("generated %s cli is missing %q:\n%s", output, expected, src)
+			}
+		}
+	}
+}
+
+func TestGenerateGoCLIRun(test *testing.T) {
+	skipWithoutModule(test, "github.com/spf13/cobra@v1.8.1")
+	skipWithoutModule(test, "gopkg.in/yaml.v3@v3.0.1")
+	for _, c := range []struct {
+		output   string
+		expected string
+	}{
+		{"json", "{\n    \"age\": 30,\n    \"id\": \"jane\",\n    \"zone\": \"eu\"\n}\n"},
+		{"table", "age   30\nid    jane\nzone  eu\n"},
+		{"yaml", "age: 30\nid: jane\nzone: eu\n"},
+	} {
+		var buf bytes.Buffer
+		if err := GenerateGoCLI(sampleSchema(), &buf, GoCLIOptions{Output: c.output}); err != nil {
+			test.Fatalf("cannot generate cli: %v", err)
+		}
+		runGoTest(test, map[string]string{
+			"go.mod":      "module sample\n\ngo 1.22\n\nrequire (\n\tgithub.com/spf13/cobra v1.8.1\n\tgopkg.in/yaml.v3 v3.0.1\n)\n",
+			"cli.gen.go":  buf.String(),
+			"output.go":   fmt.Sprintf("package main\n\nconst expectedOutput = %q\n", c.expected),
+			"cli_test.go": cliTest,
+		})
+	}
+}
+
+func TestGenerateGoCLIConflicts(test *testing.T) {
+	schema := sampleSchema()
+	schema.Resources = append(schema.Resources, rdl.NewResourceBuilder("User", "GET", "/people/{id}").
+		Input("id", "String", true, "", "", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err == nil {
+		test.Error("expected an error for resources with the same command name")
+	}
+	schema = sampleSchema()
+	schema.Resources[1].Inputs = append(schema.Resources[1].Inputs, &rdl.ResourceInput{Name: "json", Type: "String", QueryParam: "json", Optional: true})
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err == nil {
+		test.Error("expected an error for an input named json")
+	}
+}
+
+func TestGenerateGoCLIOptions(test *testing.T) {
+	var buf bytes.Buffer
+	err := GenerateGoCLI(sampleSchema(), &buf, GoCLIOptions{Package: "cli", BaseURLFlag: "endpoint"})
+	if err != nil {
+		test.Fatalf("cannot generate cli: %v", err)
+	}
+	if !strings.Contains(buf.String(), "package cli\n") || !strings.Contains(buf.String(), `StringVar(&baseURL, "endpoint"`) {
+		test.Errorf("options not applied:\n%s", buf.String())
+	}
+	if err := GenerateGoCLI(sampleSchema(), &buf, GoCLIOptions{Output: "xml"}); err == nil {
+		test.Error("expected an error for an unsupported output format")
+	}
+}
+
+const cliTest = `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// run executes the command line, returning what it printed.
+func run(t *testing.T, args ...string) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	root := NewRootCommand()
+	root.SetArgs(args)
+	err = root.Execute()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return string(out)
+}
+
+func TestCLI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/users/jane":
+			if r.URL.Query().Get("fields") != "age" || r.Header.Get("Authorization") != "token" {
+				t.Errorf("unexpected query %q, authorization %q", r.URL.RawQuery, r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(` + "`" + `{"zone":"eu","id":"jane","age":30}` + "`" + `))
+		case r.Method == "POST" && r.URL.Path == "/users":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != ` + "`" + `{"id":"john"}` + "`" + ` || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected body %s of type %q", body, r.Header.Get("Content-Type"))
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	out := run(t, "getUser", "--base-url", ts.URL, "--id", "jane", "--fields", "age", "--auth", "token")
+	if out != expectedOutput {
+		t.Errorf("output %q, expected %q", out, expectedOutput)
+	}
+	if out := run(t, "postUser", "--base-url", ts.URL, "--json", ` + "`" + `{"id":"john"}` + "`" + `); out != "" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+`
+
+const cliContentDigestTest = `package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestContentDigest(t *testing.T) {
+	body := ` + "`" + `{"id":"jane"}` + "`" + `
+	sum := sha256.Sum256([]byte(body))
+	digest := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	for _, c := range []struct {
+		digest string
+		served string
+		valid  bool
+	}{
+		{digest, body, true},
+		{"md5=:AAAA:, " + digest, body, true},
+		{digest, ` + "`" + `{"id":"john"}` + "`" + `, false},
+		{"", body, false},
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.digest != "" {
+				w.Header().Set("Content-Digest", c.digest)
+			}
+			w.Write([]byte(c.served))
+		}))
+		baseURL = ts.URL
+		err := invoke("GET", "/users/jane", url.Values{}, http.Header{}, "", true)
+		if (err == nil) != c.valid {
+			t.Errorf("digest %q of %s: %v", c.digest, c.served, err)
+		}
+		ts.Close()
+	}
+}
+`
+
+func TestGenerateGoCLIContentDigest(test *testing.T) {
+	schema := sampleSchema()
+	schema.Resources[0].ContentAddressed = true
+	var buf bytes.Buffer
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err != nil {
+		test.Fatalf("cannot generate cli: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`return invoke("GET", path, query, header, "", true)`,
+		`return invoke("POST", path, query, header, body, false)`,
+		"func verifyContentDigest(header string, body []byte) error {",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated cli is missing %q:\n%s", expected, src)
+		}
+	}
+	skipWithoutModule(test, "github.com/spf13/cobra@v1.8.1")
+	runGoTest(test, map[string]string{
+		"go.mod":                 "module sample\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.1\n",
+		"cli.gen.go":             src,
+		"content_digest_test.go": cliContentDigestTest,
+	})
+}
+
+const cliRetryTest = `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	p := retryPolicy{maxAttempts: 5, initialDelay: 100 * time.Millisecond, maxDelay: time.Second, multiplier: 2, jitterFactor: 0.5}
+	for _, c := range []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+	} {
+		var min, max, sum time.Duration
+		for i := 0; i < 1000; i++ {
+			d := p.delay(c.attempt)
+			if d < c.base || d > c.base+c.base/2 {
+				t.Fatalf("delay %v after attempt %d, expected between %v and %v", d, c.attempt, c.base, c.base+c.base/2)
+			}
+			if i == 0 || d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+			sum += d
+		}
+		if max-min < c.base/4 {
+			t.Errorf("delays after attempt %d between %v and %v, expected more jitter", c.attempt, min, max)
+		}
+		if mean := sum / 1000; mean < c.base+c.base/5 || mean > c.base+c.base*3/10 {
+			t.Errorf("mean delay %v after attempt %d, expected about %v", mean, c.attempt, c.base+c.base/4)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch {
+		case r.URL.Path == "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		case attempts < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	baseURL = ts.URL
+	p := retryPolicy{maxAttempts: 3, initialDelay: time.Millisecond, multiplier: 2, jitterFactor: 1}
+	if err := invokeWithRetry(p, "GET", "/users/jane", url.Values{}, http.Header{}, "", false); err != nil || attempts != 3 {
+		t.Errorf("%d attempts: %v", attempts, err)
+	}
+	attempts = 0
+	p.maxAttempts = 2
+	if err := invokeWithRetry(p, "GET", "/users/jane", url.Values{}, http.Header{}, "", false); err == nil || attempts != 2 {
+		t.Errorf("%d attempts: %v", attempts, err)
+	}
+	attempts = 0
+	if err := invokeWithRetry(p, "GET", "/bad", url.Values{}, http.Header{}, "", false); err == nil || attempts != 1 {
+		t.Errorf("client error retried: %d attempts: %v", attempts, err)
+	}
+}
+`
+
+func TestGenerateGoCLIRetry(test *testing.T) {
+	schema := sampleSchema()
+	schema.Resources[0].Retry = &rdl.RetryPolicy{MaxAttempts: 3, InitialDelayMillis: 100, MaxDelayMillis: 1000, Multiplier: 2, JitterFactor: 0.5}
+	var buf bytes.Buffer
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err != nil {
+		test.Fatalf("cannot generate cli: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`return invokeWithRetry(retryPolicy{maxAttempts: 3, initialDelay: 100 * time.Millisecond, maxDelay: 1000 * time.Millisecond, multiplier: 2, jitterFactor: 0.5}, "GET", path, query, header, "", false)`,
+		`return invoke("POST", path, query, header, body, false)`,
+		"return time.Duration(d + rand.Float64()*p.jitterFactor*d)",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated cli is missing %q:\n%s", expected, src)
+		}
+	}
+	skipWithoutModule(test, "github.com/spf13/cobra@v1.8.1")
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.1\n",
+		"cli.gen.go":    src,
+		"retry_test.go": cliRetryTest,
+	})
+
+	schema.Resources[0].Retry.JitterFactor = 1.5
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err == nil {
+		test.Error("expected an error for a jitter factor above 1")
+	}
+}
+
+const cliTenantTest = `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	var path, header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, header = r.URL.Path, r.Header.Get("X-Tenant-ID")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	for _, c := range []struct {
+		args   []string
+		path   string
+		header string
+		valid  bool
+	}{
+		{[]string{"getUser", "--tenant", "acme"}, "/users/acme", "", true},
+		{[]string{"getUser", "--tenant", "acme", "--id", "jane"}, "/users/jane", "", true},
+		{[]string{"getUser", "--id", "jane"}, "/users/jane", "", true},
+		{[]string{"getUser"}, "", "", false},
+		{[]string{"postUser", "--tenant", "acme", "--json", "{}"}, "/users", "acme", true},
+		{[]string{"postUser", "--json", "{}"}, "/users", "", true},
+	} {
+		tenant, path, header = "", "", ""
+		root := NewRootCommand()
+		root.SetArgs(append(c.args, "--base-url", ts.URL))
+		err := root.Execute()
+		if (err == nil) != c.valid || path != c.path || header != c.header {
+			t.Errorf("%v: path %q, tenant header %q, error %v", c.args, path, header, err)
+		}
+	}
+}
+`
+
+func TestGenerateGoCLIMultiTenant(test *testing.T) {
+	schema := sampleSchema()
+	schema.Resources[0].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "path", TenantKey: "id"}
+	schema.Resources[1].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "header", TenantKey: "X-Tenant-ID"}
+	var buf bytes.Buffer
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err != nil {
+		test.Fatalf("cannot generate cli: %v", err)
+	}
+	src := buf.String()
+	if strings.Contains(src, `cmd.MarkFlagRequired("id")`) {
+		test.Errorf("tenant path parameter marked as required:\n%s", src)
+	}
+	skipWithoutModule(test, "github.com/spf13/cobra@v1.8.1")
+	runGoTest(test, map[string]string{
+		"go.mod":         "module sample\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.1\n",
+		"cli.gen.go":     src,
+		"tenant_test.go": cliTenantTest,
+	})
+
+	schema = sampleSchema()
+	schema.Resources[0].Inputs[1].Name = "tenant"
+	schema.Resources[1].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "jwt", TenantKey: "tid"}
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err == nil {
+		test.Error("expected an error for an input named tenant")
+	}
+	schema = sampleSchema()
+	schema.Resources[0].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "path", TenantKey: "fields"}
+	if err := GenerateGoCLI(schema, &buf, GoCLIOptions{}); err == nil {
+		test.Error("expected an error for a tenant path parameter that is not in the path")
+	}
+}
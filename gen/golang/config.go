@@ -0,0 +1,310 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const (
+	configKeyAnnotation      = "x_config_key"
+	configDefaultAnnotation  = "x_config_default"
+	configRequiredAnnotation = "x_config_required"
+)
+
+// GoConfigOptions controls the generated configuration struct.
+type GoConfigOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// EnvPrefix is prepended to the environment variable of every setting.
+	EnvPrefix string
+}
+
+type configField struct {
+	Name     string
+	Key      string
+	Env      string
+	GoType   string
+	Default  string
+	Required bool
+	Comment  string
+}
+
+// GenerateGoConfig generates a ServiceConfig struct with a field for every
+// struct field annotated with x_config_key, the setting's key in the YAML
+// file. The key uppercased, with every other character replaced by an
+// underscore, is the environment variable overriding it. x_config_default
+// gives the default value and x_config_required makes a setting mandatory.
+func GenerateGoConfig(s *rdl.Schema, w io.Writer, opts GoConfigOptions) error {
+	registry := rdl.NewTypeRegistry(s)
+	var fields []*configField
+	keys := make(map[string]*configField)
+	for _, t := range s.Types {
+		if t.StructTypeDef == nil {
+			continue
+		}
+		for _, f := range t.StructTypeDef.Fields {
+			key, ok := f.Annotations[configKeyAnnotation]
+			if !ok {
+				continue
+			}
+			cf, err := newConfigField(registry, f, key, opts.EnvPrefix)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", t.StructTypeDef.Name, f.Name, err)
+			}
+			if prev, ok := keys[key]; ok {
+				if prev.GoType != cf.GoType {
+					return fmt.Errorf("config key %s declared as both %s and %s", key, prev.GoType, cf.GoType)
+				}
+				continue
+			}
+			keys[key] = cf
+			fields = append(fields, cf)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("schema %s has no fields annotated with %s", s.Name, configKeyAnnotation)
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName(s, opts.Package) },
+		"fields":  func() []*configField { return fields },
+		"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+		"uses": func(goType string) bool {
+			for _, f := range fields {
+				if f.GoType == goType {
+					return true
+				}
+			}
+			return false
+		},
+		"needsFmt": func() bool {
+			for _, f := range fields {
+				if f.Required || (f.GoType != "string" && f.GoType != "[]string") {
+					return true
+				}
+			}
+			return false
+		},
+		"parse": configParse,
+		"zero":  configZero,
+	}
+	return executeTemplate(w, "config", goConfigTemplate, funcMap, s)
+}
+
+func newConfigField(registry rdl.TypeRegistry, f *rdl.StructFieldDef, key string, envPrefix string) (*configField, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty %s", configKeyAnnotation)
+	}
+	cf := &configField{
+		Name:    configFieldName(key),
+		Key:     key,
+		Env:     envPrefix + configEnvName(key),
+		Comment: f.Comment,
+	}
+	switch registry.FindBaseType(f.Type) {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeTimestamp, rdl.BaseTypeEnum:
+		cf.GoType = "string"
+	case rdl.BaseTypeBool:
+		cf.GoType = "bool"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32:
+		cf.GoType = "int32"
+	case rdl.BaseTypeInt64:
+		cf.GoType = "int64"
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		cf.GoType = "float64"
+	case rdl.BaseTypeArray:
+		if f.Items != "" && registry.FindBaseType(f.Items) != rdl.BaseTypeString {
+			return nil, fmt.Errorf("only arrays of strings can be config settings")
+		}
+		cf.GoType = "[]string"
+	default:
+		return nil, fmt.Errorf("type %s cannot be a config setting", f.Type)
+	}
+	//a bool always has a value, so it cannot be missing
+	cf.Required = annotationFlag(f.Annotations, configRequiredAnnotation) && cf.GoType != "bool"
+	if def, ok := f.Annotations[configDefaultAnnotation]; ok {
+		lit, err := configLiteral(cf.GoType, def)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s %q: %v", configDefaultAnnotation, def, err)
+		}
+		cf.Default = lit
+	}
+	return cf, nil
+}
+
+// configLiteral returns the Go literal for a default value.
+func configLiteral(goType string, value string) (string, error) {
+	var err error
+	switch goType {
+	case "string":
+		return strconv.Quote(value), nil
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int32":
+		_, err = strconv.ParseInt(value, 10, 32)
+	case "int64":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "[]string":
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, strconv.Quote(strings.TrimSpace(item)))
+		}
+		return "[]string{" + strings.Join(items, ", ") + "}", nil
+	}
+	return value, err
+}
+
+// configParse returns the statements converting the environment variable
+// value v into the field.
+func configParse(f *configField) string {
+	switch f.GoType {
+	case "string":
+		return fmt.Sprintf("c.%s = v", f.Name)
+	case "[]string":
+		return fmt.Sprintf("c.%s = strings.Split(v, \",\")", f.Name)
+	case "bool":
+		return fmt.Sprintf("b, err := strconv.ParseBool(v)\nif err != nil {\nreturn fmt.Errorf(\"%s: %%v\", err)\n}\nc.%s = b", f.Env, f.Name)
+	case "float64":
+		return fmt.Sprintf("n, err := strconv.ParseFloat(v, 64)\nif err != nil {\nreturn fmt.Errorf(\"%s: %%v\", err)\n}\nc.%s = n", f.Env, f.Name)
+	default:
+		bits := strings.TrimPrefix(f.GoType, "int")
+		return fmt.Sprintf("n, err := strconv.ParseInt(v, 10, %s)\nif err != nil {\nreturn fmt.Errorf(\"%s: %%v\", err)\n}\nc.%s = %s(n)", bits, f.Env, f.Name, f.GoType)
+	}
+}
+
+// configZero returns the condition under which a required field is missing.
+func configZero(f *configField) string {
+	switch f.GoType {
+	case "string":
+		return fmt.Sprintf("c.%s == \"\"", f.Name)
+	case "[]string":
+		return fmt.Sprintf("len(c.%s) == 0", f.Name)
+	default:
+		return fmt.Sprintf("c.%s == 0", f.Name)
+	}
+}
+
+func configFieldName(key string) string {
+	var name string
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		name += utils.Capitalize(part)
+	}
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+func configEnvName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+}
+
+const goConfigTemplate = `{{header}}
+
+package {{package}}
+
+import (
+{{- if needsFmt}}
+	"fmt"
+{{- end}}
+	"io/ioutil"
+	"os"
+{{- if or (uses "bool") (uses "int32") (uses "int64") (uses "float64")}}
+	"strconv"
+{{- end}}
+{{- if uses "[]string"}}
+	"strings"
+{{- end}}
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServiceConfig holds the configuration settings of the {{.Name}} service.
+type ServiceConfig struct {
+{{- range fields}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	{{.Name}} {{.GoType}} ` + "`" + `yaml:{{quote .Key}}` + "`" + `
+{{- end}}
+}
+
+// NewServiceConfig returns a configuration holding the default settings.
+func NewServiceConfig() *ServiceConfig {
+	return &ServiceConfig{
+{{- range fields}}
+{{- if .Default}}
+		{{.Name}}: {{.Default}},
+{{- end}}
+{{- end}}
+	}
+}
+
+// LoadServiceConfig returns the default configuration, overridden by the
+// YAML file at path if path is not empty, then by environment variables.
+// The result is validated.
+func LoadServiceConfig(path string) (*ServiceConfig, error) {
+	c := NewServiceConfig()
+	if path != "" {
+		if err := c.LoadYAML(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.LoadEnv(); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadYAML overrides the settings present in the YAML file at path.
+func (c *ServiceConfig) LoadYAML(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, c)
+}
+
+// LoadEnv overrides the settings whose environment variable is set and not
+// empty.
+func (c *ServiceConfig) LoadEnv() error {
+{{- range fields}}
+	if v := os.Getenv({{quote .Env}}); v != "" {
+		{{parse .}}
+	}
+{{- end}}
+	return nil
+}
+
+// Validate - checks for missing required settings
+func (c *ServiceConfig) Validate() error {
+{{- range fields}}
+{{- if .Required}}
+	if {{zero .}} {
+		return fmt.Errorf("ServiceConfig.{{.Key}} is missing but is a required setting")
+	}
+{{- end}}
+{{- end}}
+	return nil
+}
+`
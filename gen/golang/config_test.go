@@ -0,0 +1,148 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func configSchema() *rdl.Schema {
+	settings := rdl.NewStructTypeBuilder("Struct", "Settings").
+		Field("db", "String", false, nil, "the database URL").
+		Field("port", "Int32", true, nil, "").
+		Field("debug", "Bool", true, nil, "").
+		ArrayField("hosts", "String", true, "").
+		Field("other", "String", true, nil, "").
+		Build()
+	annotations := []map[rdl.ExtendedAnnotation]string{
+		{"x_config_key": "database.url", "x_config_required": "true"},
+		{"x_config_key": "port", "x_config_default": "8080"},
+		{"x_config_key": "debug", "x_config_default": "false"},
+		{"x_config_key": "allowed-hosts", "x_config_default": "localhost, example.com"},
+		nil,
+	}
+	for i, f := range settings.StructTypeDef.Fields {
+		f.Annotations = annotations[i]
+	}
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(settings)
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoConfig(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoConfig(configSchema(), &buf, GoConfigOptions{EnvPrefix: "SAMPLE_"}); err != nil {
+		test.Fatalf("cannot generate config: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "config.go", buf.Bytes(), 0); err != nil {
+		test.Fatalf("generated config does not parse: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"DatabaseUrl  string   `yaml:\"database.url\"`",
+		"Port         int32    `yaml:\"port\"`",
+		"AllowedHosts []string `yaml:\"allowed-hosts\"`",
+		`Port:         8080,`,
+		`AllowedHosts: []string{"localhost", "example.com"},`,
+		`os.Getenv("SAMPLE_DATABASE_URL")`,
+		`os.Getenv("SAMPLE_ALLOWED_HOSTS")`,
+		`n, err := strconv.ParseInt(v, 10, 32)`,
+		`if c.DatabaseUrl == "" {`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated config is missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "Other") {
+		test.Errorf("field without %s became a setting", configKeyAnnotation)
+	}
+}
+
+// configTest runs against the generated configuration.
+const configTest = `package sample
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadServiceConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "database.url: postgres://db/sample\nport: 9090\nallowed-hosts: [a.example.com, b.example.com]\n"
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadServiceConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ServiceConfig{DatabaseUrl: "postgres://db/sample", Port: 9090, AllowedHosts: []string{"a.example.com", "b.example.com"}}
+	if !reflect.DeepEqual(*c, expected) {
+		t.Errorf("YAML file: expected %+v, got %+v", expected, *c)
+	}
+
+	t.Setenv("SAMPLE_PORT", "7070")
+	t.Setenv("SAMPLE_DEBUG", "true")
+	t.Setenv("SAMPLE_ALLOWED_HOSTS", "c.example.com")
+	c, err = LoadServiceConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = ServiceConfig{DatabaseUrl: "postgres://db/sample", Port: 7070, Debug: true, AllowedHosts: []string{"c.example.com"}}
+	if !reflect.DeepEqual(*c, expected) {
+		t.Errorf("environment override: expected %+v, got %+v", expected, *c)
+	}
+
+	t.Setenv("SAMPLE_PORT", "eighty")
+	if _, err := LoadServiceConfig(path); err == nil {
+		t.Errorf("expected an error for a port that is not a number")
+	}
+	t.Setenv("SAMPLE_PORT", "")
+	if _, err := LoadServiceConfig(""); err == nil {
+		t.Errorf("expected an error for a missing database URL")
+	}
+	t.Setenv("SAMPLE_DATABASE_URL", "postgres://env/sample")
+	c, err = LoadServiceConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DatabaseUrl != "postgres://env/sample" || c.Port != 8080 || len(c.AllowedHosts) != 1 {
+		t.Errorf("defaults with environment override: got %+v", *c)
+	}
+}
+`
+
+func TestGenerateGoConfigRun(test *testing.T) {
+	skipWithoutModule(test, "gopkg.in/yaml.v3@v3.0.1")
+	var buf bytes.Buffer
+	if err := GenerateGoConfig(configSchema(), &buf, GoConfigOptions{EnvPrefix: "SAMPLE_"}); err != nil {
+		test.Fatalf("cannot generate config: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":         "module sample\n\ngo 1.22\n\nrequire gopkg.in/yaml.v3 v3.0.1\n",
+		"config.go":      buf.String(),
+		"config_test.go": configTest,
+	})
+}
+
+func TestGenerateGoConfigErrors(test *testing.T) {
+	s := configSchema()
+	s.Types[0].StructTypeDef.Fields[1].Annotations["x_config_default"] = "eighty"
+	var buf bytes.Buffer
+	if err := GenerateGoConfig(s, &buf, GoConfigOptions{}); err == nil {
+		test.Error("expected an error for a default that is not a number")
+	}
+	if err := GenerateGoConfig(sampleSchema(), &buf, GoConfigOptions{}); err == nil {
+		test.Error("expected an error for a schema without settings")
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// ContractOptions controls the generated Pact contracts.
+type ContractOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// Provider is the name of the provider, the schema name if empty.
+	Provider string
+	// Consumer is the name of the consumer, the provider name followed by
+	// "-client" if empty.
+	Consumer string
+}
+
+// GenerateGoContract generates consumer-driven contract boilerplate using
+// pact-go: a Pact interaction per resource, built from the resource's
+// inputs, expected status code and output type, plus a function publishing
+// the resulting pact files to a Pact Broker with its HTTP API. Bodies are
+// matched by type against example values of the RDL types.
+func GenerateGoContract(s *rdl.Schema, w io.Writer, opts ContractOptions) error {
+	if opts.Provider == "" {
+		opts.Provider = string(s.Name)
+	}
+	if opts.Consumer == "" {
+		opts.Consumer = opts.Provider + "-client"
+	}
+	if len(s.Resources) == 0 {
+		return fmt.Errorf("schema %s has no resources", s.Name)
+	}
+	ex := &exampler{registry: rdl.NewTypeRegistry(s), visiting: make(map[rdl.TypeRef]bool)}
+	funcMap := template.FuncMap{
+		"header":      func() string { return utils.GoGenerationHeader(banner) },
+		"package":     func() string { return packageName(s, opts.Package) },
+		"opts":        func() ContractOptions { return opts },
+		"quote":       func(s string) string { return fmt.Sprintf("%q", s) },
+		"name":        methodName,
+		"examplePath": func(r *rdl.Resource) string { return ex.path(r) },
+		"pathRegexp":  pathRegexp,
+		"status":      func(r *rdl.Resource) string { return rdl.StatusCode(r.Expected) },
+		"hasBody": func(r *rdl.Resource) bool {
+			code := rdl.StatusCode(r.Expected)
+			return code != "204" && code != "304"
+		},
+		"bodyInput": bodyInput,
+		"example":   func(t rdl.TypeRef) string { return ex.literal(t, "") },
+		"exampleString": func(in *rdl.ResourceInput) string {
+			return fmt.Sprintf("%q", ex.stringValue(in))
+		},
+	}
+	if err := ex.check(s); err != nil {
+		return err
+	}
+	return executeTemplate(w, "contract", goContractTemplate, funcMap, s)
+}
+
+// exampler builds example values of RDL types, as Go literals.
+type exampler struct {
+	registry rdl.TypeRegistry
+	visiting map[rdl.TypeRef]bool
+}
+
+// check verifies that every type used by the resources is defined, so that
+// the examples can be built.
+func (ex *exampler) check(s *rdl.Schema) error {
+	for _, r := range s.Resources {
+		refs := []rdl.TypeRef{r.Type}
+		for _, in := range r.Inputs {
+			refs = append(refs, in.Type)
+		}
+		for _, ref := range refs {
+			if ex.registry.FindType(ref) == nil {
+				return fmt.Errorf("%s %s: unknown type: %s", r.Method, r.Path, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// literal returns an example value of the type. Only required struct fields
+// are included, as a provider may leave optional ones out.
+func (ex *exampler) literal(ref rdl.TypeRef, items rdl.TypeRef) string {
+	t := ex.registry.FindType(ref)
+	if t == nil {
+		return "nil"
+	}
+	switch ex.registry.BaseType(t) {
+	case rdl.BaseTypeBool:
+		return "true"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		return "1"
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		return "1.5"
+	case rdl.BaseTypeTimestamp:
+		return `"2016-01-01T00:00:00.000Z"`
+	case rdl.BaseTypeUUID:
+		return `"2fd2b3ae-1a4b-4b4b-9e0c-5a3b1c2d3e4f"`
+	case rdl.BaseTypeEnum:
+		if t.EnumTypeDef != nil && len(t.EnumTypeDef.Elements) > 0 {
+			return fmt.Sprintf("%q", t.EnumTypeDef.Elements[0].Symbol)
+		}
+		return `"string"`
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeBytes:
+		return `"string"`
+	case rdl.BaseTypeArray:
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items == "" || items == "Any" {
+			return "[]interface{}{}"
+		}
+		return "[]interface{}{" + ex.literal(items, "") + "}"
+	case rdl.BaseTypeStruct:
+		if t.StructTypeDef == nil || ex.visiting[ref] {
+			return "map[string]interface{}{}"
+		}
+		ex.visiting[ref] = true
+		defer delete(ex.visiting, ref)
+		var fields []string
+		for _, f := range utils.FlattenedFields(ex.registry, t) {
+			if !f.Optional {
+				fields = append(fields, fmt.Sprintf("%q: %s", f.Name, ex.literal(f.Type, f.Items)))
+			}
+		}
+		return "map[string]interface{}{" + strings.Join(fields, ", ") + "}"
+	default:
+		return "map[string]interface{}{}"
+	}
+}
+
+// stringValue returns the example of an input as it appears in a URL or
+// header.
+func (ex *exampler) stringValue(in *rdl.ResourceInput) string {
+	if in.Default != nil {
+		return fmt.Sprint(in.Default)
+	}
+	switch ex.registry.FindBaseType(in.Type) {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+		return string(in.Name)
+	default:
+		return strings.Trim(ex.literal(in.Type, ""), `"`)
+	}
+}
+
+// path returns the resource path with every parameter replaced by an example.
+func (ex *exampler) path(r *rdl.Resource) string {
+	path := resourcePath(r)
+	for _, in := range r.Inputs {
+		if in.PathParam {
+			path = strings.Replace(path, "{"+string(in.Name)+"}", ex.stringValue(in), -1)
+		}
+	}
+	return path
+}
+
+var pathParamPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// pathRegexp returns the regular expression matching the resource path with
+// any value for its parameters.
+func pathRegexp(r *rdl.Resource) string {
+	path := resourcePath(r)
+	var re string
+	last := 0
+	for _, loc := range pathParamPattern.FindAllStringIndex(path, -1) {
+		re += regexp.QuoteMeta(path[last:loc[0]]) + "[^/]+"
+		last = loc[1]
+	}
+	return "^" + re + regexp.QuoteMeta(path[last:]) + "$"
+}
+
+func resourcePath(r *rdl.Resource) string {
+	if i := strings.Index(r.Path, "?"); i >= 0 {
+		return r.Path[:i]
+	}
+	return r.Path
+}
+
+const goContractTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pact-foundation/pact-go/dsl"
+)
+
+// NewPact returns the pact between {{opts.Consumer}} and {{opts.Provider}}, with
+// an interaction for every resource.
+func NewPact() *dsl.Pact {
+	pact := &dsl.Pact{
+		Consumer: {{quote opts.Consumer}},
+		Provider: {{quote opts.Provider}},
+	}
+	AddInteractions(pact)
+	return pact
+}
+
+// AddInteractions adds an interaction for every resource of {{opts.Provider}} to
+// the pact. Unlike pact.AddInteraction, it does not start the mock service,
+// which pact.Verify starts. Call it again before every Verify, as Verify
+// clears the interactions.
+func AddInteractions(pact *dsl.Pact) {
+{{- range .Resources}}
+	pact.Interactions = append(pact.Interactions, (&dsl.Interaction{}).
+		UponReceiving({{quote (print "a " .Method " request to " (name .))}}).
+		WithRequest(dsl.Request{
+			Method: {{quote .Method}},
+			Path:   dsl.Term({{quote (examplePath .)}}, {{quote (pathRegexp .)}}),
+{{- $query := false}}{{range .Inputs}}{{if and .QueryParam (not .Optional)}}{{$query = true}}{{end}}{{end}}
+{{- if $query}}
+			Query: dsl.MapMatcher{
+{{- range .Inputs}}
+{{- if and .QueryParam (not .Optional)}}
+				{{quote .QueryParam}}: dsl.String({{exampleString .}}),
+{{- end}}
+{{- end}}
+			},
+{{- end}}
+{{- $headers := false}}{{range .Inputs}}{{if and .Header (not .Optional)}}{{$headers = true}}{{end}}{{end}}
+{{- if $headers}}
+			Headers: dsl.MapMatcher{
+{{- range .Inputs}}
+{{- if and .Header (not .Optional)}}
+				{{quote .Header}}: dsl.String({{exampleString .}}),
+{{- end}}
+{{- end}}
+			},
+{{- end}}
+{{- with bodyInput .}}
+			Body: dsl.Like({{example .Type}}),
+{{- end}}
+		}).
+		WillRespondWith(dsl.Response{
+			Status: {{status .}},
+{{- if hasBody .}}
+			Headers: dsl.MapMatcher{"Content-Type": dsl.String("application/json")},
+			Body:    dsl.Like({{example .Type}}),
+{{- end}}
+		}))
+{{- end}}
+}
+
+// PublishContracts publishes the pact files written by the consumer tests
+// to the Pact Broker, under the given consumer version, putting each of them
+// to the broker's /pacts/provider/{provider}/consumer/{consumer}/version/{version}
+// resource.
+func PublishContracts(brokerURL string, version string, pactFiles ...string) error {
+	for _, file := range pactFiles {
+		if err := publishContract(brokerURL, version, file); err != nil {
+			return fmt.Errorf("cannot publish %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+func publishContract(brokerURL string, version string, file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var pact struct {
+		Consumer struct{ Name string } ` + "`" + `json:"consumer"` + "`" + `
+		Provider struct{ Name string } ` + "`" + `json:"provider"` + "`" + `
+	}
+	if err := json.Unmarshal(data, &pact); err != nil {
+		return err
+	}
+	if pact.Consumer.Name == "" || pact.Provider.Name == "" {
+		return fmt.Errorf("no consumer or provider name")
+	}
+	u := strings.TrimSuffix(brokerURL, "/") + "/pacts/provider/" + url.PathEscape(pact.Provider.Name) +
+		"/consumer/" + url.PathEscape(pact.Consumer.Name) + "/version/" + url.PathEscape(version)
+	req, err := http.NewRequest("PUT", u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("broker responded %s: %s", resp.Status, body)
+	}
+	return nil
+}
+`
@@ -0,0 +1,117 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoContract(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoContract(sampleSchema(), &buf, ContractOptions{Consumer: "web"}); err != nil {
+		test.Fatalf("cannot generate contract: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "contract.go", buf.Bytes(), 0); err != nil {
+		test.Fatalf("generated contract does not parse: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`Consumer: "web",`,
+		`Provider: "sample",`,
+		`UponReceiving("a GET request to getUser")`,
+		`Path:   dsl.Term("/users/id", "^/users/[^/]+$"),`,
+		`UponReceiving("a POST request to postUser")`,
+		`Body:   dsl.Like(map[string]interface{}{"id": "string"}),`,
+		`Status:  201,`,
+		`Body:    dsl.Like(map[string]interface{}{"id": "string"}),`,
+		`func PublishContracts(brokerURL string, version string, pactFiles ...string) error`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated contract is missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, `"fields"`) || strings.Contains(src, `"Authorization"`) {
+		test.Errorf("optional inputs should not be part of the contract:\n%s", src)
+	}
+}
+
+// contractTest runs against the generated contract, publishing it to a
+// fake Pact Broker.
+const contractTest = `package sample
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublishContracts(t *testing.T) {
+	pact := NewPact()
+	if len(pact.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(pact.Interactions))
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"consumer":     map[string]string{"name": pact.Consumer},
+		"provider":     map[string]string{"name": pact.Provider},
+		"interactions": pact.Interactions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "web-sample.json")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var method, path, contentType string
+	var body []byte
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer broker.Close()
+	if err := PublishContracts(broker.URL+"/", "1.0.0+abc", file); err != nil {
+		t.Fatal(err)
+	}
+	if method != "PUT" || path != "/pacts/provider/sample/consumer/web/version/1.0.0+abc" || contentType != "application/json" {
+		t.Errorf("unexpected request %s %s (%s)", method, path, contentType)
+	}
+	if string(body) != string(data) {
+		t.Errorf("expected the pact file as body, got %s", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	}))
+	defer failing.Close()
+	if err := PublishContracts(failing.URL, "1.0.0", file); err == nil {
+		t.Errorf("expected an error when the broker rejects the pact")
+	}
+	if err := PublishContracts(broker.URL, "1.0.0", filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing pact file")
+	}
+}
+`
+
+func TestGenerateGoContractRun(test *testing.T) {
+	skipWithoutModule(test, "github.com/pact-foundation/pact-go@v1.10.0")
+	var buf bytes.Buffer
+	if err := GenerateGoContract(sampleSchema(), &buf, ContractOptions{Consumer: "web"}); err != nil {
+		test.Fatalf("cannot generate contract: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":           "module sample\n\ngo 1.22\n\nrequire github.com/pact-foundation/pact-go v1.10.0\n",
+		"contract.go":      buf.String(),
+		"contract_test.go": contractTest,
+	})
+}
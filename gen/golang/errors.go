@@ -0,0 +1,245 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoErrorOptions controls the generated error types.
+type GoErrorOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type errorType struct {
+	Name    string
+	RDLName string
+	Comment string
+	Fields  []*errorField
+	Code    *errorField
+	Message *errorField
+}
+
+type errorField struct {
+	Name   string
+	JSON   string
+	GoType string
+}
+
+type errorSentinel struct {
+	Name    string
+	Type    *errorType
+	Status  int
+	Message string
+	Comment string
+}
+
+// GenerateGoErrorTypes generates an error type for every type used as a
+// resource exception, named after it with an "Exception" suffix and holding
+// its fields, plus a sentinel value for every exception of every resource.
+// The status of the response is kept by the error and returned by its
+// StatusCode method. A "code" integer field and a "message" string field,
+// when the type has them, are set in the sentinels.
+func GenerateGoErrorTypes(s *rdl.Schema, w io.Writer, opts GoErrorOptions) error {
+	registry := rdl.NewTypeRegistry(s)
+	types := make(map[string]*errorType)
+	var typeNames []string
+	var sentinels []*errorSentinel
+	for _, r := range s.Resources {
+		var syms []string
+		for sym := range r.Exceptions {
+			syms = append(syms, sym)
+		}
+		sort.Strings(syms)
+		for _, sym := range syms {
+			ex := r.Exceptions[sym]
+			et, ok := types[ex.Type]
+			if !ok {
+				var err error
+				if et, err = newErrorType(registry, ex.Type); err != nil {
+					return fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+				}
+				types[ex.Type] = et
+				typeNames = append(typeNames, ex.Type)
+			}
+			status, err := strconv.Atoi(rdl.StatusCode(sym))
+			if err != nil {
+				return fmt.Errorf("%s %s: unknown status %s", r.Method, r.Path, sym)
+			}
+			message := ex.Comment
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			sentinels = append(sentinels, &errorSentinel{
+				Name:    "Err" + goName(methodName(r)) + statusName(sym),
+				Type:    et,
+				Status:  status,
+				Message: message,
+				Comment: ex.Comment,
+			})
+		}
+	}
+	if len(sentinels) == 0 {
+		return fmt.Errorf("schema %s has no resource exceptions", s.Name)
+	}
+	sort.Strings(typeNames)
+	var errorTypes []*errorType
+	for _, name := range typeNames {
+		errorTypes = append(errorTypes, types[name])
+	}
+	funcMap := template.FuncMap{
+		"header":    func() string { return utils.GoGenerationHeader(banner) },
+		"package":   func() string { return packageName(s, opts.Package) },
+		"types":     func() []*errorType { return errorTypes },
+		"sentinels": func() []*errorSentinel { return sentinels },
+		"quote":     func(s string) string { return fmt.Sprintf("%q", s) },
+		"usesHTTP": func() bool {
+			for _, et := range errorTypes {
+				if et.Message == nil {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	return executeTemplate(w, "errors", goErrorsTemplate, funcMap, s)
+}
+
+func newErrorType(registry rdl.TypeRegistry, name string) (*errorType, error) {
+	t := registry.FindType(rdl.TypeRef(name))
+	if t == nil {
+		return nil, fmt.Errorf("unknown exception type: %s", name)
+	}
+	if t.StructTypeDef == nil {
+		return nil, fmt.Errorf("exception type %s is not a struct", name)
+	}
+	et := &errorType{
+		Name:    typeVarName(rdl.TypeRef(name)) + "Exception",
+		RDLName: name,
+		Comment: t.StructTypeDef.Comment,
+	}
+	for _, f := range utils.FlattenedFields(registry, t) {
+		ef := &errorField{
+			Name:   goName(string(f.Name)),
+			JSON:   string(f.Name),
+			GoType: errorFieldType(registry, f.Type, f.Items),
+		}
+		if f.Optional {
+			ef.JSON += ",omitempty"
+		}
+		switch {
+		case f.Name == "code" && strings.HasPrefix(ef.GoType, "int"):
+			et.Code = ef
+		case f.Name == "message" && ef.GoType == "string":
+			et.Message = ef
+		}
+		et.Fields = append(et.Fields, ef)
+	}
+	return et, nil
+}
+
+// errorFieldType returns the Go type of an exception field. The error types
+// stand alone, so structs and other user types are reduced to their
+// underlying JSON representation.
+func errorFieldType(registry rdl.TypeRegistry, ref rdl.TypeRef, items rdl.TypeRef) string {
+	t := registry.FindType(ref)
+	if t == nil {
+		return "interface{}"
+	}
+	switch registry.BaseType(t) {
+	case rdl.BaseTypeBool:
+		return "bool"
+	case rdl.BaseTypeInt8:
+		return "int8"
+	case rdl.BaseTypeInt16:
+		return "int16"
+	case rdl.BaseTypeInt32:
+		return "int32"
+	case rdl.BaseTypeInt64:
+		return "int64"
+	case rdl.BaseTypeFloat32:
+		return "float32"
+	case rdl.BaseTypeFloat64:
+		return "float64"
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeTimestamp, rdl.BaseTypeEnum:
+		return "string"
+	case rdl.BaseTypeBytes:
+		return "[]byte"
+	case rdl.BaseTypeArray:
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items == "" || items == "Any" {
+			return "[]interface{}"
+		}
+		return "[]" + errorFieldType(registry, items, "")
+	case rdl.BaseTypeStruct, rdl.BaseTypeMap:
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+}
+
+// statusName returns the CamelCase form of a symbolic status, as NotFound
+// for NOT_FOUND.
+func statusName(sym string) string {
+	var name string
+	for _, part := range strings.Split(sym, "_") {
+		name += utils.Capitalize(strings.ToLower(part))
+	}
+	return name
+}
+
+const goErrorsTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"fmt"
+{{- if usesHTTP}}
+	"net/http"
+{{- end}}
+)
+{{range types}}
+// {{.Name}} is the error carrying a {{.RDLName}}.{{if .Comment}} {{.Comment}}{{end}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.GoType}} ` + "`" + `json:"{{.JSON}}"` + "`" + `
+{{- end}}
+
+	status int
+}
+
+// StatusCode returns the HTTP status of the response carrying the error.
+func (e *{{.Name}}) StatusCode() int {
+	return e.status
+}
+
+func (e *{{.Name}}) Error() string {
+{{- if .Message}}
+	return fmt.Sprintf("%d %s", e.status, e.{{.Message.Name}})
+{{- else}}
+	return fmt.Sprintf("%d %s", e.status, http.StatusText(e.status))
+{{- end}}
+}
+{{end}}
+var (
+{{- range $s := sentinels}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	{{.Name}} = &{{.Type.Name}}{ {{- with .Type.Code}}{{.Name}}: {{$s.Status}}, {{end}}{{with .Type.Message}}{{.Name}}: {{quote $s.Message}}, {{end}}status: {{.Status}}}
+{{- end}}
+)
+`
@@ -0,0 +1,105 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func errorSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").Field("id", "String", false, nil, "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "ResourceError").
+		Comment("The error body of failed requests").
+		Field("code", "Int32", false, nil, "").
+		Field("message", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "QuotaError").
+		Field("limit", "Int64", false, nil, "").
+		ArrayField("resets", "Timestamp", true, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Input("id", "String", true, "", "", false, nil, "").
+		Exception("NOT_FOUND", "ResourceError", "no such user").
+		Exception("FORBIDDEN", "QuotaError", "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "POST", "/users").
+		Input("user", "User", false, "", "", false, nil, "").
+		Exception("CONFLICT", "ResourceError", "").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoErrorTypes(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoErrorTypes(errorSchema(), &buf, GoErrorOptions{}); err != nil {
+		test.Fatalf("cannot generate error types: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "errors.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated error types do not parse: %v\n%s", err, buf.String())
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("sample", fset, []*ast.File{f}, nil)
+	if err != nil {
+		test.Fatalf("generated error types do not compile: %v\n%s", err, buf.String())
+	}
+	errorInterface := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	for _, name := range []string{"ResourceErrorException", "QuotaErrorException"} {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			test.Errorf("missing error type %s", name)
+			continue
+		}
+		if !types.Implements(types.NewPointer(obj.Type()), errorInterface) {
+			test.Errorf("*%s does not implement error", name)
+		}
+	}
+	//errors.As matches the sentinels by their pointer type
+	for name, typeName := range map[string]string{
+		"ErrGetUserNotFound":  "ResourceErrorException",
+		"ErrGetUserForbidden": "QuotaErrorException",
+		"ErrPostUserConflict": "ResourceErrorException",
+	} {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			test.Errorf("missing sentinel %s", name)
+			continue
+		}
+		if expected := "*sample." + typeName; obj.Type().String() != expected {
+			test.Errorf("sentinel %s is a %s, expected %s", name, obj.Type(), expected)
+		}
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"Resets []string `json:\"resets,omitempty\"`",
+		"ErrGetUserNotFound  = &ResourceErrorException{Code: 404, Message: \"no such user\", status: 404}",
+		"ErrGetUserForbidden = &QuotaErrorException{status: 403}",
+		"ErrPostUserConflict = &ResourceErrorException{Code: 409, Message: \"Conflict\", status: 409}",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated error types are missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+func TestGenerateGoErrorTypesNotStruct(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/ping").Exception("NOT_FOUND", "String", "").Build())
+	var buf bytes.Buffer
+	if err := GenerateGoErrorTypes(gentest.MustBuild(sb), &buf, GoErrorOptions{}); err == nil {
+		test.Errorf("expected an error for a string exception type")
+	}
+}
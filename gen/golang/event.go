@@ -0,0 +1,271 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoEventOptions controls the generated event sourcing code.
+type GoEventOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// Backend is the event store implementation: "inmem" (default), "postgres" or "kafka".
+	Backend string
+}
+
+// GenerateGoEvent generates event sourcing boilerplate for the struct types
+// annotated with x_event: an EventHandler with a method per event type, an
+// EventStore for the selected backend, and an EventLog whose Publish and
+// Replay methods record events in a store and replay them to a handler. The
+// event structs themselves are expected to be generated into the same
+// package.
+func GenerateGoEvent(s *rdl.Schema, w io.Writer, opts GoEventOptions) error {
+	switch opts.Backend {
+	case "":
+		opts.Backend = "inmem"
+	case "inmem", "postgres", "kafka":
+	default:
+		return fmt.Errorf("unsupported event store backend: %s", opts.Backend)
+	}
+	var events []*rdl.StructTypeDef
+	for _, t := range s.Types {
+		if t.StructTypeDef != nil && annotationFlag(t.StructTypeDef.Annotations, "x_event") {
+			events = append(events, t.StructTypeDef)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("schema %s has no struct types annotated with x_event", s.Name)
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName(s, opts.Package) },
+		"backend": func() string { return opts.Backend },
+		"events":  func() []*rdl.StructTypeDef { return events },
+		"ddl":     func() string { return fmt.Sprintf("%q", postgresEventTableDDL) },
+	}
+	return executeTemplate(w, "event", goEventTemplate, funcMap, s)
+}
+
+// annotationFlag reports whether the annotation is present and is either
+// valueless or "true".
+func annotationFlag(annotations map[rdl.ExtendedAnnotation]string, key rdl.ExtendedAnnotation) bool {
+	v, ok := annotations[key]
+	return ok && (v == "" || v == "true")
+}
+
+// postgresEventTableDDL creates the table the postgres event store reads and writes.
+const postgresEventTableDDL = `CREATE TABLE IF NOT EXISTS events (
+    sequence BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+const goEventTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"encoding/json"
+	"fmt"
+{{- if eq backend "inmem"}}
+	"sync"
+{{- else if eq backend "postgres"}}
+	"database/sql"
+{{- else if eq backend "kafka"}}
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+{{- end}}
+)
+
+// EventType is implemented by every event of the schema.
+type EventType interface {
+	EventName() string
+}
+{{range events}}
+// EventName returns the name {{.Name}} events are recorded under.
+func (e *{{.Name}}) EventName() string {
+	return "{{.Name}}"
+}
+{{end}}
+// Event is a recorded event, with its payload encoded as JSON.
+type Event struct {
+	Sequence int64
+	Name     string
+	Payload  []byte
+}
+
+// EventHandler is called for each event that is replayed.
+type EventHandler interface {
+{{- range events}}
+	Handle{{.Name}}(sequence int64, event *{{.Name}}) error
+{{- end}}
+}
+
+// EventStore records events and loads them back in order.
+type EventStore interface {
+	Append(name string, payload []byte) (int64, error)
+	Load(from int64) ([]*Event, error)
+}
+
+// EventLog publishes events to its store and replays them to its handler.
+type EventLog struct {
+	Store   EventStore
+	Handler EventHandler
+}
+
+// NewEventLog returns an event log over the store, replaying events to the handler.
+func NewEventLog(store EventStore, handler EventHandler) *EventLog {
+	return &EventLog{Store: store, Handler: handler}
+}
+
+// Publish records the event in the store.
+func (l *EventLog) Publish(event EventType) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = l.Store.Append(event.EventName(), payload)
+	return err
+}
+
+// Replay passes every event recorded at or after the given sequence to the handler.
+func (l *EventLog) Replay(from int64) error {
+	events, err := l.Store.Load(from)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		switch e.Name {
+{{- range events}}
+		case "{{.Name}}":
+			var event {{.Name}}
+			if err := json.Unmarshal(e.Payload, &event); err != nil {
+				return err
+			}
+			if err := l.Handler.Handle{{.Name}}(e.Sequence, &event); err != nil {
+				return err
+			}
+{{- end}}
+		default:
+			return fmt.Errorf("unknown event %s at sequence %d", e.Name, e.Sequence)
+		}
+	}
+	return nil
+}
+{{if eq backend "inmem"}}
+// InMemEventStore keeps events in memory; sequences start at 0.
+type InMemEventStore struct {
+	mutex  sync.Mutex
+	events []*Event
+}
+
+// NewInMemEventStore returns an empty in-memory event store.
+func NewInMemEventStore() *InMemEventStore {
+	return &InMemEventStore{}
+}
+
+func (s *InMemEventStore) Append(name string, payload []byte) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sequence := int64(len(s.events))
+	s.events = append(s.events, &Event{Sequence: sequence, Name: name, Payload: payload})
+	return sequence, nil
+}
+
+func (s *InMemEventStore) Load(from int64) ([]*Event, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if from < 0 || from >= int64(len(s.events)) {
+		return nil, nil
+	}
+	return append([]*Event(nil), s.events[from:]...), nil
+}
+{{- else if eq backend "postgres"}}
+// EventTableDDL creates the table used by PostgresEventStore.
+const EventTableDDL = {{ddl}}
+
+// PostgresEventStore keeps events in the events table.
+type PostgresEventStore struct {
+	DB *sql.DB
+}
+
+// NewPostgresEventStore returns an event store backed by the given database.
+func NewPostgresEventStore(db *sql.DB) *PostgresEventStore {
+	return &PostgresEventStore{DB: db}
+}
+
+func (s *PostgresEventStore) Append(name string, payload []byte) (int64, error) {
+	var sequence int64
+	err := s.DB.QueryRow("INSERT INTO events (type, payload) VALUES ($1, $2) RETURNING sequence", name, payload).Scan(&sequence)
+	return sequence, err
+}
+
+func (s *PostgresEventStore) Load(from int64) ([]*Event, error) {
+	rows, err := s.DB.Query("SELECT sequence, type, payload FROM events WHERE sequence >= $1 ORDER BY sequence", from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(&e.Sequence, &e.Name, &e.Payload); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+{{- else if eq backend "kafka"}}
+// KafkaEventStore keeps events in a single partition of a Kafka topic; the
+// message offset is the event sequence and the message key its name. The
+// sequence of an appended event is the offset the broker assigned to its
+// message; loads are serialized, as they move the offset of the connection.
+type KafkaEventStore struct {
+	Conn  *kafka.Conn
+	mutex sync.Mutex
+}
+
+// NewKafkaEventStore returns an event store backed by the given partition leader connection.
+func NewKafkaEventStore(conn *kafka.Conn) *KafkaEventStore {
+	return &KafkaEventStore{Conn: conn}
+}
+
+func (s *KafkaEventStore) Append(name string, payload []byte) (int64, error) {
+	_, _, sequence, _, err := s.Conn.WriteCompressedMessagesAt(nil, kafka.Message{Key: []byte(name), Value: payload})
+	return sequence, err
+}
+
+func (s *KafkaEventStore) Load(from int64) ([]*Event, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	last, err := s.Conn.ReadLastOffset()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Conn.Seek(from, kafka.SeekAbsolute); err != nil {
+		return nil, err
+	}
+	var events []*Event
+	for sequence := from; sequence < last; sequence++ {
+		s.Conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		msg, err := s.Conn.ReadMessage(10e6)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &Event{Sequence: msg.Offset, Name: string(msg.Key), Payload: msg.Value})
+	}
+	return events, nil
+}
+{{- end}}
+`
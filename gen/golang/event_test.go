@@ -0,0 +1,254 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func eventSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("orders")
+	created := rdl.NewStructTypeBuilder("Struct", "OrderCreated").Field("id", "String", false, nil, "").Build()
+	created.StructTypeDef.Annotations = map[rdl.ExtendedAnnotation]string{"x_event": "true"}
+	shipped := rdl.NewStructTypeBuilder("Struct", "OrderShipped").Field("id", "String", false, nil, "").Build()
+	shipped.StructTypeDef.Annotations = map[rdl.ExtendedAnnotation]string{"x_event": ""}
+	sb.AddType(created).AddType(shipped)
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Order").Field("id", "String", false, nil, "").Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoEvent(test *testing.T) {
+	for _, backend := range []string{"inmem", "postgres", "kafka"} {
+		var buf bytes.Buffer
+		if err := GenerateGoEvent(eventSchema(), &buf, GoEventOptions{Backend: backend}); err != nil {
+			test.Fatalf("cannot generate %s events: %v", backend, err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "events.go", buf.Bytes(), 0); err != nil {
+			test.Fatalf("generated %s events do not parse: %v", backend, err)
+		}
+		src := buf.String()
+		for _, expected := range []string{
+			"package orders\n",
+			"HandleOrderCreated(sequence int64, event *OrderCreated) error",
+			"HandleOrderShipped(sequence int64, event *OrderShipped) error",
+			"func (l *EventLog) Publish(event EventType) error",
+			"func (l *EventLog) Replay(from int64) error",
+		} {
+			if !strings.Contains(src, expected) {
+				test.Errorf("generated %s events are missing %q", backend, expected)
+			}
+		}
+		if strings.Contains(src, "HandleOrder(") {
+			test.Errorf("type without x_event annotation treated as an event")
+		}
+	}
+}
+
+// eventHandlerTest records the events replayed to it.
+const eventHandlerTest = `package orders
+
+import "fmt"
+
+type recorder []string
+
+func (r *recorder) HandleOrderCreated(sequence int64, event *OrderCreated) error {
+	*r = append(*r, fmt.Sprintf("%d created %s", sequence, event.Id))
+	return nil
+}
+
+func (r *recorder) HandleOrderShipped(sequence int64, event *OrderShipped) error {
+	*r = append(*r, fmt.Sprintf("%d shipped %s", sequence, event.Id))
+	return nil
+}
+`
+
+const inMemEventTest = `package orders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventLog(t *testing.T) {
+	var r recorder
+	log := NewEventLog(NewInMemEventStore(), &r)
+	for _, event := range []EventType{&OrderCreated{Id: "a"}, &OrderCreated{Id: "b"}, &OrderShipped{Id: "a"}} {
+		if err := log.Publish(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Replay(1); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (recorder{"1 created b", "2 shipped a"}); !reflect.DeepEqual(r, expected) {
+		t.Errorf("replayed %q, expected %q", r, expected)
+	}
+}
+`
+
+const postgresEventTest = `package orders
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// fakeDB records the statements it runs, keeping the events in memory.
+type fakeDB struct {
+	queries []string
+	events  [][]driver.Value
+}
+
+func (db *fakeDB) Open(name string) (driver.Conn, error) { return db, nil }
+func (db *fakeDB) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db, query}, nil
+}
+func (db *fakeDB) Close() error              { return nil }
+func (db *fakeDB) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.queries = append(s.db.queries, s.query)
+	switch s.query {
+	case "INSERT INTO events (type, payload) VALUES ($1, $2) RETURNING sequence":
+		sequence := int64(len(s.db.events) + 1)
+		s.db.events = append(s.db.events, []driver.Value{sequence, args[0], args[1]})
+		return &fakeRows{columns: []string{"sequence"}, rows: [][]driver.Value{{sequence}}}, nil
+	case "SELECT sequence, type, payload FROM events WHERE sequence >= $1 ORDER BY sequence":
+		rows := &fakeRows{columns: []string{"sequence", "type", "payload"}}
+		for _, e := range s.db.events {
+			if e[0].(int64) >= args[0].(int64) {
+				rows.rows = append(rows.rows, e)
+			}
+		}
+		return rows, nil
+	}
+	return nil, driver.ErrSkip
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestPostgresEventStore(t *testing.T) {
+	fake := &fakeDB{}
+	sql.Register("fake", fake)
+	db, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r recorder
+	log := NewEventLog(NewPostgresEventStore(db), &r)
+	if err := log.Publish(&OrderCreated{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Publish(&OrderShipped{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Replay(2); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (recorder{"2 shipped a"}); !reflect.DeepEqual(r, expected) {
+		t.Errorf("replayed %q, expected %q", r, expected)
+	}
+	if string(fake.events[0][2].([]byte)) != ` + "`" + `{"id":"a"}` + "`" + ` {
+		t.Errorf("unexpected payload %s", fake.events[0][2])
+	}
+	if len(fake.queries) != 3 {
+		t.Errorf("unexpected queries %q", fake.queries)
+	}
+}
+`
+
+const kafkaEventTest = `package orders
+
+import "testing"
+
+func TestKafkaEventStore(t *testing.T) {
+	var store EventStore = NewKafkaEventStore(nil)
+	_ = NewEventLog(store, &recorder{})
+}
+`
+
+func TestGenerateGoEventRun(test *testing.T) {
+	schema := eventSchema()
+	var model bytes.Buffer
+	if err := GenerateGo(schema, "orders", &model); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	for _, c := range []struct {
+		backend string
+		gomod   string
+		test    string
+	}{
+		{"inmem", "module orders\n\ngo 1.16\n", inMemEventTest},
+		{"postgres", "module orders\n\ngo 1.16\n", postgresEventTest},
+		{"kafka", "module orders\n\ngo 1.22\n\nrequire github.com/segmentio/kafka-go v0.4.50\n", kafkaEventTest},
+	} {
+		var buf bytes.Buffer
+		if err := GenerateGoEvent(schema, &buf, GoEventOptions{Backend: c.backend}); err != nil {
+			test.Fatalf("cannot generate %s events: %v", c.backend, err)
+		}
+		if c.backend == "kafka" {
+			skipWithoutModule(test, "github.com/segmentio/kafka-go@v0.4.50")
+		}
+		runGoTest(test, map[string]string{
+			"go.mod":          c.gomod,
+			"model.go":        model.String(),
+			"events.go":       buf.String(),
+			"handler_test.go": eventHandlerTest,
+			"events_test.go":  c.test,
+		})
+	}
+}
+
+func TestGenerateGoEventPostgres(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoEvent(eventSchema(), &buf, GoEventOptions{Backend: "postgres"}); err != nil {
+		test.Fatalf("cannot generate events: %v", err)
+	}
+	for _, expected := range []string{
+		`"INSERT INTO events (type, payload) VALUES ($1, $2) RETURNING sequence"`,
+		`"SELECT sequence, type, payload FROM events WHERE sequence >= $1 ORDER BY sequence"`,
+		`sequence BIGSERIAL PRIMARY KEY,\n    type TEXT NOT NULL,\n    payload JSONB NOT NULL,`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			test.Errorf("generated postgres store is missing %q", expected)
+		}
+	}
+	if err := GenerateGoEvent(eventSchema(), &buf, GoEventOptions{Backend: "mongo"}); err == nil {
+		test.Error("expected an error for an unsupported backend")
+	}
+}
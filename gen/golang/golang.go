@@ -0,0 +1,99 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package golang generates Go source code from RDL schemas.
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+// executeTemplate runs the template against data, gofmts the result and
+// writes it to w.
+func executeTemplate(w io.Writer, name string, source string, funcMap template.FuncMap, data interface{}) error {
+	t, err := template.New(name).Funcs(funcMap).Parse(source)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func packageName(s *rdl.Schema, pkg string) string {
+	if pkg != "" {
+		return pkg
+	}
+	return utils.GoGenerationPackage(s)
+}
+
+// methodName follows the naming used by the java generators: the resource
+// name if present, otherwise the lowercase method followed by the type.
+func methodName(r *rdl.Resource) string {
+	if r.Name != "" {
+		return utils.Uncapitalize(string(r.Name))
+	}
+	bodyType := typeVarName(r.Type)
+	for _, in := range r.Inputs {
+		if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+			bodyType = typeVarName(in.Type)
+		}
+	}
+	return strings.ToLower(r.Method) + bodyType
+}
+
+func typeVarName(t rdl.TypeRef) string {
+	return utils.Capitalize(strings.Replace(string(t), ".", "", -1))
+}
+
+func goName(name string) string {
+	return utils.Capitalize(name)
+}
+
+// bodyInput returns the input carried in the request body, if any.
+func bodyInput(r *rdl.Resource) *rdl.ResourceInput {
+	for _, in := range r.Inputs {
+		if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+			return in
+		}
+	}
+	return nil
+}
+
+// checkMultiTenant checks the tenant of a multi-tenant resource comes from
+// one of its path parameters, a header or a JWT claim.
+func checkMultiTenant(r *rdl.Resource) error {
+	mt := r.MultiTenant
+	if mt.TenantKey == "" {
+		return fmt.Errorf("multi-tenant resource without a tenant key")
+	}
+	switch mt.TenantFrom {
+	case "path":
+		for _, in := range r.Inputs {
+			if in.PathParam && string(in.Name) == mt.TenantKey {
+				return nil
+			}
+		}
+		return fmt.Errorf("tenant path parameter %s is not an input", mt.TenantKey)
+	case "header", "jwt":
+		return nil
+	}
+	return fmt.Errorf("tenant from %q, expected \"path\", \"header\" or \"jwt\"", mt.TenantFrom)
+}
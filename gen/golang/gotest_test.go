@@ -0,0 +1,59 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runGoTest runs go test on a standalone package made of the files, which
+// may be in subdirectories holding the other packages of its module, skipping
+// the test when the go command is not available.
+func runGoTest(test *testing.T, files map[string]string) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		test.Skip("go command not found, not running the generated code")
+	}
+	dir, err := ioutil.TempDir("", "gen")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module sample\n\ngo 1.16\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			test.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			test.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		test.Errorf("generated code does not behave as expected: %v\n%s", err, out)
+	}
+}
+
+// skipWithoutModule skips the test when a module the generated code
+// depends on cannot be downloaded, as when offline.
+func skipWithoutModule(test *testing.T, module string) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		test.Skip("go command not found, not running the generated code")
+	}
+	cmd := exec.Command(gobin, "mod", "download", module)
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		test.Skipf("cannot download %s, not running the generated code: %v\n%s", module, err, out)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoGRPCOptions controls the generated gRPC scaffold.
+type GoGRPCOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// ProtoImport is the import path of the Go stubs generated from the proto
+	// file, its go_package option if empty.
+	ProtoImport string
+}
+
+type grpcService struct {
+	Name    string
+	Methods []*grpcMethod
+}
+
+type grpcMethod struct {
+	Name     string
+	Request  string
+	Response string
+	Comment  string
+}
+
+var (
+	protoGoPackage = regexp.MustCompile(`option\s+go_package\s*=\s*"([^";]+)(;[^"]*)?"`)
+	protoService   = regexp.MustCompile(`service\s+(\w+)\s*\{`)
+	protoRPC       = regexp.MustCompile(`rpc\s+(\w+)\s*\(\s*(stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(stream\s+)?([\w.]+)\s*\)`)
+)
+
+// GenerateGoGRPC generates a gRPC scaffold for the service declared in the
+// proto file generated for the schema. Each rpc is delegated to the method of
+// the same name of a handler interface, and errors carrying an HTTP status
+// (a StatusCode() int method, like rdl.ResourceError) are translated to
+// gRPC status codes. A client constructor is generated as well.
+func GenerateGoGRPC(s *rdl.Schema, protoFile string, w io.Writer, opts GoGRPCOptions) error {
+	data, err := ioutil.ReadFile(protoFile)
+	if err != nil {
+		return err
+	}
+	proto := string(data)
+	if opts.ProtoImport == "" {
+		m := protoGoPackage.FindStringSubmatch(proto)
+		if m == nil {
+			return fmt.Errorf("%s has no go_package option, and no proto import path was given", protoFile)
+		}
+		opts.ProtoImport = m[1]
+	}
+	svc, err := parseProtoService(proto, protoFile)
+	if err != nil {
+		return err
+	}
+	comments := make(map[string]string)
+	for _, r := range s.Resources {
+		comments[goName(methodName(r))] = r.Comment
+	}
+	for _, m := range svc.Methods {
+		m.Comment = comments[m.Name]
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName(s, opts.Package) },
+		"opts":    func() GoGRPCOptions { return opts },
+		"service": func() *grpcService { return svc },
+		"message": grpcMessageType,
+		"usesEmpty": func() bool {
+			for _, m := range svc.Methods {
+				if m.Request == "google.protobuf.Empty" || m.Response == "google.protobuf.Empty" {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	return executeTemplate(w, "grpc", goGRPCTemplate, funcMap, s)
+}
+
+// parseProtoService returns the first service declared in the proto source.
+// Streaming rpcs have no resource counterpart and are rejected.
+func parseProtoService(proto string, protoFile string) (*grpcService, error) {
+	loc := protoService.FindStringSubmatchIndex(proto)
+	if loc == nil {
+		return nil, fmt.Errorf("%s declares no service", protoFile)
+	}
+	svc := &grpcService{Name: proto[loc[2]:loc[3]]}
+	body := proto[loc[1]:]
+	if end := strings.Index(body, "\n}"); end >= 0 {
+		body = body[:end]
+	}
+	for _, m := range protoRPC.FindAllStringSubmatch(body, -1) {
+		if m[2] != "" || m[4] != "" {
+			return nil, fmt.Errorf("%s: streaming rpc %s is not supported", protoFile, m[1])
+		}
+		svc.Methods = append(svc.Methods, &grpcMethod{Name: m[1], Request: m[3], Response: m[5]})
+	}
+	if len(svc.Methods) == 0 {
+		return nil, fmt.Errorf("%s: service %s has no rpcs", protoFile, svc.Name)
+	}
+	return svc, nil
+}
+
+// grpcMessageType returns the Go type of a proto message.
+func grpcMessageType(message string) string {
+	if message == "google.protobuf.Empty" {
+		return "emptypb.Empty"
+	}
+	return "pb." + message[strings.LastIndex(message, ".")+1:]
+}
+
+const goGRPCTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+{{- if usesEmpty}}
+	"google.golang.org/protobuf/types/known/emptypb"
+{{- end}}
+
+	pb {{printf "%q" opts.ProtoImport}}
+)
+{{$svc := service}}
+// {{$svc.Name}}Handler implements the {{$svc.Name}} service.
+type {{$svc.Name}}Handler interface {
+{{- range $svc.Methods}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	{{.Name}}(ctx context.Context, request *{{message .Request}}) (*{{message .Response}}, error)
+{{- end}}
+}
+
+type {{$svc.Name}}GRPCServer struct {
+	pb.Unimplemented{{$svc.Name}}Server
+	handler {{$svc.Name}}Handler
+}
+
+// Register{{$svc.Name}}Handler registers the handler with the gRPC server.
+func Register{{$svc.Name}}Handler(server *grpc.Server, handler {{$svc.Name}}Handler) {
+	pb.Register{{$svc.Name}}Server(server, &{{$svc.Name}}GRPCServer{handler: handler})
+}
+{{range $svc.Methods}}
+func (s *{{$svc.Name}}GRPCServer) {{.Name}}(ctx context.Context, request *{{message .Request}}) (*{{message .Response}}, error) {
+	response, err := s.handler.{{.Name}}(ctx, request)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return response, nil
+}
+{{end}}
+// New{{$svc.Name}}Client connects to the {{$svc.Name}} service at target.
+func New{{$svc.Name}}Client(target string, opts ...grpc.DialOption) (pb.{{$svc.Name}}Client, *grpc.ClientConn, error) {
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pb.New{{$svc.Name}}Client(conn), conn, nil
+}
+
+// grpcError translates errors carrying an HTTP status to the matching gRPC
+// status.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	e, ok := err.(interface{ StatusCode() int })
+	if !ok {
+		return status.Error(codes.Unknown, err.Error())
+	}
+	var code codes.Code
+	switch e.StatusCode() {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+`
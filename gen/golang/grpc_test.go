@@ -0,0 +1,149 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoGRPC(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoGRPC(sampleSchema(), "../../testdata/grpc/sample.proto", &buf, GoGRPCOptions{}); err != nil {
+		test.Fatalf("cannot generate gRPC scaffold: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "grpc.go", buf.Bytes(), 0); err != nil {
+		test.Fatalf("generated gRPC scaffold does not parse: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`pb "example.com/sample/pb"`,
+		`"google.golang.org/protobuf/types/known/emptypb"`,
+		`GetUser(ctx context.Context, request *pb.GetUserRequest) (*pb.User, error)`,
+		`PostUser(ctx context.Context, request *pb.PostUserRequest) (*emptypb.Empty, error)`,
+		`pb.UnimplementedSampleServer`,
+		`pb.RegisterSampleServer(server, &SampleGRPCServer{handler: handler})`,
+		`return pb.NewSampleClient(conn), conn, nil`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated gRPC scaffold is missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+// grpcTest serves a handler with the generated scaffold and calls it with the
+// generated client, both built on the stubs of testdata/grpc/pb, which
+// protoc-gen-go and protoc-gen-go-grpc generated from sample.proto.
+const grpcTest = `package sample
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"example.com/sample/pb"
+)
+
+type notFound string
+
+func (e notFound) Error() string   { return "no user " + string(e) }
+func (e notFound) StatusCode() int { return 404 }
+
+type handler struct{}
+
+func (handler) GetUser(ctx context.Context, request *pb.GetUserRequest) (*pb.User, error) {
+	if request.Id != "jane" {
+		return nil, notFound(request.Id)
+	}
+	return &pb.User{Id: "jane", Age: 30}, nil
+}
+
+func (handler) PostUser(ctx context.Context, request *pb.PostUserRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func TestGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	RegisterSampleHandler(server, handler{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	client, conn, err := NewSampleClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	user, err := client.GetUser(context.Background(), &pb.GetUserRequest{Id: "jane"})
+	if err != nil || user.Id != "jane" || user.Age != 30 {
+		t.Errorf("user %v: %v", user, err)
+	}
+	_, err = client.GetUser(context.Background(), &pb.GetUserRequest{Id: "john"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("error %v, expected NotFound", err)
+	}
+	if _, err := client.PostUser(context.Background(), &pb.PostUserRequest{User: &pb.User{Id: "john"}}); err != nil {
+		t.Error(err)
+	}
+}
+`
+
+func TestGenerateGoGRPCRun(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoGRPC(sampleSchema(), "../../testdata/grpc/sample.proto", &buf, GoGRPCOptions{}); err != nil {
+		test.Fatalf("cannot generate gRPC scaffold: %v", err)
+	}
+	files := map[string]string{
+		"go.mod":       "module example.com/sample\n\ngo 1.22\n\nrequire (\n\tgoogle.golang.org/grpc v1.65.0\n\tgoogle.golang.org/protobuf v1.34.2\n)\n",
+		"grpc.gen.go":  buf.String(),
+		"grpc_test.go": grpcTest,
+	}
+	for _, name := range []string{"sample.pb.go", "sample_grpc.pb.go"} {
+		stub, err := ioutil.ReadFile(filepath.Join("../../testdata/grpc/pb", name))
+		if err != nil {
+			test.Fatal(err)
+		}
+		files["pb/"+name] = string(stub)
+	}
+	skipWithoutModule(test, "google.golang.org/grpc@v1.65.0")
+	runGoTest(test, files)
+}
+
+func TestGenerateGoGRPCErrors(test *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	protos := map[string]string{
+		"noservice.proto": "syntax = \"proto3\";\noption go_package = \"x/pb\";\n",
+		"stream.proto":    "syntax = \"proto3\";\noption go_package = \"x/pb\";\nservice S {\n  rpc Watch(A) returns (stream B);\n}\n",
+		"nopackage.proto": "syntax = \"proto3\";\nservice S {\n  rpc Get(A) returns (B);\n}\n",
+	}
+	for name, proto := range protos {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(proto), 0644); err != nil {
+			test.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := GenerateGoGRPC(sampleSchema(), path, &buf, GoGRPCOptions{}); err == nil {
+			test.Errorf("%s: expected an error", name)
+		}
+	}
+}
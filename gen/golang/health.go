@@ -0,0 +1,136 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// HealthProbeOptions controls the generated health probes.
+type HealthProbeOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// Dependencies are the dependencies the readiness probe checks.
+	Dependencies []DependencyCheck
+}
+
+// DependencyCheck is a dependency of the service. The generated code
+// declares the same type: function values cannot be generated, so Check is
+// set at run time with the generated SetDependencyCheck.
+type DependencyCheck struct {
+	Name  string
+	Check func() error
+}
+
+// GenerateGoHealthProbes generates the Kubernetes liveness and readiness
+// probe handlers of the service, served on /livez and /readyz. The liveness
+// probe always succeeds; the readiness probe fails with 503 Service
+// Unavailable as long as the check of any dependency fails or is not set.
+func GenerateGoHealthProbes(s *rdl.Schema, w io.Writer, opts HealthProbeOptions) error {
+	seen := make(map[string]bool)
+	for _, dep := range opts.Dependencies {
+		if dep.Name == "" {
+			return fmt.Errorf("dependency without a name")
+		}
+		if seen[dep.Name] {
+			return fmt.Errorf("duplicate dependency: %s", dep.Name)
+		}
+		seen[dep.Name] = true
+	}
+	funcMap := template.FuncMap{
+		"header":       func() string { return utils.GoGenerationHeader(banner) },
+		"package":      func() string { return packageName(s, opts.Package) },
+		"dependencies": func() []DependencyCheck { return opts.Dependencies },
+		"quote":        func(s string) string { return fmt.Sprintf("%q", s) },
+	}
+	return executeTemplate(w, "health", goHealthProbesTemplate, funcMap, s)
+}
+
+const goHealthProbesTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DependencyCheck is a dependency checked by ReadinessProbe. Check returns
+// an error when the dependency cannot be used.
+type DependencyCheck struct {
+	Name  string
+	Check func() error
+}
+
+var (
+	dependenciesMu sync.RWMutex
+	// Dependencies are the dependencies checked by ReadinessProbe, in order.
+	Dependencies = []DependencyCheck{
+{{- range dependencies}}
+		{Name: {{quote .Name}}},
+{{- end}}
+	}
+)
+
+// SetDependencyCheck sets the check of a dependency. It returns false if
+// the dependency is not declared.
+func SetDependencyCheck(name string, check func() error) bool {
+	dependenciesMu.Lock()
+	defer dependenciesMu.Unlock()
+	for i := range Dependencies {
+		if Dependencies[i].Name == name {
+			Dependencies[i].Check = check
+			return true
+		}
+	}
+	return false
+}
+
+// LivenessProbe answers the liveness probe: the service is alive as long as
+// it serves requests.
+func LivenessProbe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// ReadinessProbe answers the readiness probe: the service is ready when the
+// checks of all its dependencies succeed. The failing dependencies are
+// listed in the body of the 503 response otherwise.
+func ReadinessProbe(w http.ResponseWriter, r *http.Request) {
+	dependenciesMu.RLock()
+	defer dependenciesMu.RUnlock()
+	var failures []string
+	for _, dep := range Dependencies {
+		if dep.Check == nil {
+			failures = append(failures, dep.Name+": no check set")
+		} else if err := dep.Check(); err != nil {
+			failures = append(failures, dep.Name+": "+err.Error())
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if len(failures) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, failure := range failures {
+			fmt.Fprintln(w, failure)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// RegisterHealthProbes serves LivenessProbe on /livez and ReadinessProbe on
+// /readyz.
+func RegisterHealthProbes(mux *http.ServeMux) {
+	mux.HandleFunc("/livez", LivenessProbe)
+	mux.HandleFunc("/readyz", ReadinessProbe)
+}
+`
@@ -0,0 +1,89 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// healthProbesTest runs against the generated probes with httptest.
+const healthProbesTest = `package sample
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probe(path string) int {
+	mux := http.NewServeMux()
+	RegisterHealthProbes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec.Code
+}
+
+func TestProbes(t *testing.T) {
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness without checks: %d", code)
+	}
+	SetDependencyCheck("database", func() error { return nil })
+	SetDependencyCheck("cache", func() error { return errors.New("connection refused") })
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness with a failing check: %d", code)
+	}
+	if code := probe("/livez"); code != http.StatusOK {
+		t.Errorf("liveness with a failing check: %d", code)
+	}
+	SetDependencyCheck("cache", func() error { return nil })
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("readiness with passing checks: %d", code)
+	}
+	if SetDependencyCheck("queue", func() error { return nil }) {
+		t.Errorf("check set for an undeclared dependency")
+	}
+}
+`
+
+func TestGenerateGoHealthProbes(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	opts := HealthProbeOptions{Dependencies: []DependencyCheck{{Name: "database"}, {Name: "cache"}}}
+	var buf bytes.Buffer
+	if err := GenerateGoHealthProbes(gentest.MustBuild(sb), &buf, opts); err != nil {
+		test.Fatalf("cannot generate health probes: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "health.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated health probes do not parse: %v", err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{f}, nil); err != nil {
+		test.Fatalf("generated health probes do not compile: %v\n%s", err, buf.String())
+	}
+
+	runGoTest(test, map[string]string{
+		"health.go":      buf.String(),
+		"health_test.go": healthProbesTest,
+	})
+}
+
+func TestGenerateGoHealthProbesDuplicateDependency(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	opts := HealthProbeOptions{Dependencies: []DependencyCheck{{Name: "database"}, {Name: "database"}}}
+	var buf bytes.Buffer
+	if err := GenerateGoHealthProbes(gentest.MustBuild(sb), &buf, opts); err == nil {
+		test.Errorf("expected an error for a duplicate dependency")
+	}
+}
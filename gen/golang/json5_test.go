@@ -0,0 +1,110 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func json5Schema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("config")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Server").
+		Field("host", "String", false, nil, "").
+		Field("port", "Int32", false, nil, "").
+		ArrayField("tags", "String", true, "").
+		MapField("limits", "String", "Int64", true, "").
+		JSON5(true).
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Client").Field("name", "String", false, nil, "").Build())
+	return gentest.MustBuild(sb)
+}
+
+const json5Types = `package config
+
+type Server struct {
+	Host   string           ` + "`" + `json:"host"` + "`" + `
+	Port   int32            ` + "`" + `json:"port"` + "`" + `
+	Tags   []string         ` + "`" + `json:"tags,omitempty"` + "`" + `
+	Limits map[string]int64 ` + "`" + `json:"limits,omitempty"` + "`" + `
+}
+`
+
+const json5Test = `package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSON5(t *testing.T) {
+	var server Server
+	err := server.UnmarshalJSON5([]byte(` + "`" + `{
+  // the public address
+  host: 'api.example.com',
+  port: 8443, /* TLS */
+  tags: ["public", "v2",],
+  limits: {"max-conns": 9007199254740993,},
+}` + "`" + `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Server{Host: "api.example.com", Port: 8443, Tags: []string{"public", "v2"}, Limits: map[string]int64{"max-conns": 9007199254740993}}
+	if !reflect.DeepEqual(server, expected) {
+		t.Fatalf("unmarshaled %+v, expected %+v", server, expected)
+	}
+	data, err := server.MarshalJSON5()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := "{\n  host: \"api.example.com\",\n  port: 8443,\n  tags: [\n    \"public\",\n    \"v2\",\n  ],\n  limits: {\n    \"max-conns\": 9007199254740993,\n  },\n}\n"; string(data) != text {
+		t.Errorf("marshaled %s, expected %s", data, text)
+	}
+	if json.Valid(data) {
+		t.Errorf("marshaled JSON instead of JSON5: %s", data)
+	}
+	var decoded Server
+	if err := decoded.UnmarshalJSON5(data); err != nil || !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("marshaled JSON5 decoded as %+v: %v", decoded, err)
+	}
+	if err := decoded.UnmarshalJSON5([]byte("{host: }")); err == nil {
+		t.Errorf("expected an error for invalid JSON5")
+	}
+}
+`
+
+func TestGenerateGoJSON5(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoJSON5(json5Schema(), &buf, GoJSON5Options{}); err != nil {
+		test.Fatalf("cannot generate JSON5 methods: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/json5/config.go")
+	if err != nil {
+		test.Fatalf("cannot read JSON5 methods: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("JSON5 methods not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+	skipWithoutModule(test, "github.com/titanous/json5@v1.0.0")
+	runGoTest(test, map[string]string{
+		"go.mod":        "module config\n\ngo 1.22\n\nrequire github.com/titanous/json5 v1.0.0\n",
+		"json5.gen.go":  buf.String(),
+		"types.gen.go":  json5Types,
+		"json5_test.go": json5Test,
+	})
+}
+
+func TestGenerateGoJSON5WithoutJSON5Types(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("config")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Client").Field("name", "String", false, nil, "").Build())
+	var buf bytes.Buffer
+	if err := GenerateGoJSON5(gentest.MustBuild(sb), &buf, GoJSON5Options{}); err == nil {
+		test.Errorf("expected an error for a schema without JSON5 types")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+)
+
+// MigrationOptions are the options of GenerateGoMigration.
+type MigrationOptions struct {
+	// Dialect is the SQL dialect of the statements: "postgres" (the
+	// default), "mysql" or "sqlite".
+	Dialect string
+	// Down adds the statements reverting the migration.
+	Down bool
+}
+
+// GenerateGoMigration generates the SQL migration of the tables of the
+// struct types of a schema from its old version to its new one, in the
+// sql-migrate format. Every struct type has a table of the same name, with a
+// column per field: new struct types are created, removed ones dropped, and
+// the columns of changed ones added, altered or dropped following their
+// fields. Fields of struct, array, map and union types are JSON columns.
+// Required fields added to tables with rows need a default value.
+//
+// SQLite cannot alter the type of a column: changed fields are an error with
+// this dialect.
+func GenerateGoMigration(older, newer *rdl.Schema, w io.Writer, opts MigrationOptions) error {
+	m, err := newMigration(older, newer, opts.Dialect)
+	if err != nil {
+		return err
+	}
+	diff := rdl.DiffSchema(older, newer)
+	for _, t := range diff.AddedTypes {
+		if t.StructTypeDef != nil {
+			m.createTable(m.newReg, t)
+		}
+	}
+	for _, td := range diff.ChangedTypes {
+		if td.New.StructTypeDef == nil {
+			continue
+		}
+		if td.Old.StructTypeDef == nil {
+			m.createTable(m.newReg, td.New)
+			continue
+		}
+		table := m.quote(string(td.Name))
+		for _, f := range td.AddedFields {
+			m.statement(
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, m.column(m.newReg, f)),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, m.quote(string(f.Name))))
+		}
+		for _, fd := range td.ChangedFields {
+			if err := m.alterColumn(table, fd); err != nil {
+				return fmt.Errorf("%s: %v", td.Name, err)
+			}
+		}
+		for _, f := range td.RemovedFields {
+			m.statement(
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, m.quote(string(f.Name))),
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, m.column(m.oldReg, f)))
+		}
+	}
+	for _, t := range diff.RemovedTypes {
+		if t.StructTypeDef != nil {
+			m.dropTable(m.oldReg, t)
+		}
+	}
+	for _, td := range diff.ChangedTypes {
+		if td.Old.StructTypeDef != nil && td.New.StructTypeDef == nil {
+			m.dropTable(m.oldReg, td.Old)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "-- Migration of the %s schema from %s to %s, generated by %s\n", newer.Name, schemaVersion(older), schemaVersion(newer), banner)
+	fmt.Fprintf(&buf, "\n-- +migrate Up\n")
+	for _, s := range m.up {
+		fmt.Fprintf(&buf, "%s\n", s)
+	}
+	if opts.Down {
+		fmt.Fprintf(&buf, "\n-- +migrate Down\n")
+		for i := len(m.down) - 1; i >= 0; i-- {
+			fmt.Fprintf(&buf, "%s\n", m.down[i])
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func schemaVersion(s *rdl.Schema) string {
+	if s.Version == nil {
+		return "an unversioned schema"
+	}
+	return fmt.Sprintf("version %d", *s.Version)
+}
+
+type migration struct {
+	dialect string
+	oldReg  rdl.TypeRegistry
+	newReg  rdl.TypeRegistry
+	up      []string
+	down    []string
+}
+
+func newMigration(older, newer *rdl.Schema, dialect string) (*migration, error) {
+	switch dialect {
+	case "":
+		dialect = "postgres"
+	case "postgres", "mysql", "sqlite":
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	return &migration{dialect: dialect, oldReg: rdl.NewTypeRegistry(older), newReg: rdl.NewTypeRegistry(newer)}, nil
+}
+
+func (m *migration) statement(up, down string) {
+	m.up = append(m.up, up)
+	m.down = append(m.down, down)
+}
+
+func (m *migration) quote(name string) string {
+	if m.dialect == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+func (m *migration) createTable(reg rdl.TypeRegistry, t *rdl.Type) {
+	m.statement(m.createStatement(reg, t), fmt.Sprintf("DROP TABLE %s;", m.quote(string(t.StructTypeDef.Name))))
+}
+
+func (m *migration) dropTable(reg rdl.TypeRegistry, t *rdl.Type) {
+	m.statement(fmt.Sprintf("DROP TABLE %s;", m.quote(string(t.StructTypeDef.Name))), m.createStatement(reg, t))
+}
+
+func (m *migration) createStatement(reg rdl.TypeRegistry, t *rdl.Type) string {
+	var columns []string
+	for _, f := range migrationFields(reg, t) {
+		columns = append(columns, "\t"+m.column(reg, f))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", m.quote(string(t.StructTypeDef.Name)), strings.Join(columns, ",\n"))
+}
+
+// migrationFields returns the fields of a struct, inherited ones first.
+func migrationFields(reg rdl.TypeRegistry, t *rdl.Type) []*rdl.StructFieldDef {
+	st := t.StructTypeDef
+	if st.Type == "Struct" {
+		return st.Fields
+	}
+	if super := reg.FindType(st.Type); super != nil && super.StructTypeDef != nil {
+		return append(migrationFields(reg, super), st.Fields...)
+	}
+	return st.Fields
+}
+
+func (m *migration) column(reg rdl.TypeRegistry, f *rdl.StructFieldDef) string {
+	col := m.quote(string(f.Name)) + " " + m.sqlType(reg, f.Type) + nullability(f)
+	switch def := f.Default.(type) {
+	case string:
+		col += " DEFAULT '" + strings.Replace(def, "'", "''", -1) + "'"
+	case bool, int, int8, int16, int32, int64, float32, float64, json.Number:
+		col += fmt.Sprintf(" DEFAULT %v", def)
+	}
+	return col
+}
+
+func nullability(f *rdl.StructFieldDef) string {
+	if f.Optional {
+		return " NULL"
+	}
+	return " NOT NULL"
+}
+
+func (m *migration) alterColumn(table string, fd *rdl.FieldDiff) error {
+	oldType, newType := m.sqlType(m.oldReg, fd.Old.Type), m.sqlType(m.newReg, fd.New.Type)
+	if oldType == newType && fd.Old.Optional == fd.New.Optional {
+		return nil
+	}
+	col := m.quote(string(fd.Name))
+	switch m.dialect {
+	case "sqlite":
+		return fmt.Errorf("SQLite cannot alter column %s from %s%s to %s%s", fd.Name, oldType, nullability(fd.Old), newType, nullability(fd.New))
+	case "mysql":
+		m.statement(
+			fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, m.column(m.newReg, fd.New)),
+			fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, m.column(m.oldReg, fd.Old)))
+		return nil
+	}
+	if oldType != newType {
+		m.statement(
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, col, newType),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, col, oldType))
+	}
+	if fd.Old.Optional != fd.New.Optional {
+		set, drop := "SET NOT NULL", "DROP NOT NULL"
+		if fd.New.Optional {
+			set, drop = drop, set
+		}
+		m.statement(
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, col, set),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, col, drop))
+	}
+	return nil
+}
+
+// sqlTypes are the column types of the RDL base types, by dialect.
+var sqlTypes = map[rdl.BaseType]map[string]string{
+	rdl.BaseTypeBool:      {"postgres": "BOOLEAN", "mysql": "BOOLEAN", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt8:      {"postgres": "SMALLINT", "mysql": "TINYINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt16:     {"postgres": "SMALLINT", "mysql": "SMALLINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt32:     {"postgres": "INTEGER", "mysql": "INT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt64:     {"postgres": "BIGINT", "mysql": "BIGINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeFloat32:   {"postgres": "REAL", "mysql": "FLOAT", "sqlite": "REAL"},
+	rdl.BaseTypeFloat64:   {"postgres": "DOUBLE PRECISION", "mysql": "DOUBLE", "sqlite": "REAL"},
+	rdl.BaseTypeBytes:     {"postgres": "BYTEA", "mysql": "BLOB", "sqlite": "BLOB"},
+	rdl.BaseTypeTimestamp: {"postgres": "TIMESTAMP WITH TIME ZONE", "mysql": "DATETIME(3)", "sqlite": "TEXT"},
+	rdl.BaseTypeUUID:      {"postgres": "UUID", "mysql": "CHAR(36)", "sqlite": "TEXT"},
+}
+
+func (m *migration) sqlType(reg rdl.TypeRegistry, ref rdl.TypeRef) string {
+	bt := reg.FindBaseType(ref)
+	if types, ok := sqlTypes[bt]; ok {
+		return types[m.dialect]
+	}
+	switch bt {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeEnum:
+		if t := reg.FindType(ref); t != nil && t.StringTypeDef != nil && t.StringTypeDef.MaxSize != nil {
+			return fmt.Sprintf("VARCHAR(%d)", *t.StringTypeDef.MaxSize)
+		}
+		return "TEXT"
+	}
+	switch m.dialect {
+	case "postgres":
+		return "JSONB"
+	case "mysql":
+		return "JSON"
+	}
+	return "TEXT"
+}
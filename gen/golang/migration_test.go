@@ -0,0 +1,79 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// accountSchema is the version of the accounts schema with the user type
+// the function builds.
+func accountSchema(version int32, user func(tb *rdl.StructTypeBuilder), types ...*rdl.Type) *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("accounts").Version(version)
+	sb.AddType(rdl.NewStringTypeBuilder("Email").MaxSize(254).Build())
+	tb := rdl.NewStructTypeBuilder("Struct", "User").
+		Field("id", "UUID", false, nil, "").
+		Field("name", "String", false, nil, "")
+	user(tb)
+	sb.AddType(tb.Build())
+	for _, t := range types {
+		sb.AddType(t)
+	}
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoMigration(test *testing.T) {
+	v1 := accountSchema(1, func(tb *rdl.StructTypeBuilder) {
+		tb.Field("age", "Int32", true, nil, "")
+		tb.Field("nickname", "String", true, nil, "")
+	})
+	v2 := accountSchema(2, func(tb *rdl.StructTypeBuilder) {
+		tb.Field("age", "Int64", false, 0, "")
+		tb.Field("email", "Email", false, "", "")
+		tb.Field("verified", "Bool", true, nil, "")
+	}, rdl.NewStructTypeBuilder("User", "Admin").
+		Field("roles", "Array", false, nil, "").
+		Field("since", "Timestamp", false, nil, "").
+		Build())
+	v3 := accountSchema(3, func(tb *rdl.StructTypeBuilder) {
+		tb.Field("age", "Int64", false, 0, "")
+		tb.Field("email", "Email", false, "", "")
+		tb.Field("verified", "Bool", true, nil, "")
+	})
+	for _, c := range []struct {
+		golden string
+		older  *rdl.Schema
+		newer  *rdl.Schema
+		opts   MigrationOptions
+	}{
+		{"v1_v2_postgres.sql", v1, v2, MigrationOptions{Down: true}},
+		{"v1_v2_mysql.sql", v1, v2, MigrationOptions{Dialect: "mysql", Down: true}},
+		{"v2_v3_sqlite.sql", v2, v3, MigrationOptions{Dialect: "sqlite"}},
+	} {
+		var buf bytes.Buffer
+		if err := GenerateGoMigration(c.older, c.newer, &buf, c.opts); err != nil {
+			test.Fatalf("cannot generate migration %s: %v", c.golden, err)
+		}
+		expected, err := ioutil.ReadFile("../../testdata/migration/" + c.golden)
+		if err != nil {
+			test.Fatalf("cannot read migration %s: %v", c.golden, err)
+		}
+		if buf.String() != string(expected) {
+			test.Errorf("migration %s not generated as expected, real: \n%s\n, expected: \n%s\n", c.golden, buf.String(), string(expected))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateGoMigration(v1, v2, &buf, MigrationOptions{Dialect: "sqlite"}); err == nil {
+		test.Errorf("expected an error for a column type change with SQLite")
+	}
+	if err := GenerateGoMigration(v1, v2, &buf, MigrationOptions{Dialect: "oracle"}); err == nil {
+		test.Errorf("expected an error for an unsupported dialect")
+	}
+}
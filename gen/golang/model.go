@@ -0,0 +1,1211 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+type modelType struct {
+	Name     string
+	Comment  string
+	GoType   string
+	Embedded string
+	Fields   []*modelField
+	Elements []string
+	Min      string
+	Max      string
+	Compare  []string
+	Checksum string
+	Money    bool
+	SSE      *modelSSE
+	Renames  []*rdl.FieldMigration
+	CSV      *modelCSV
+	Builder  *modelBuilder
+}
+
+// modelBuilder describes the fluent builder of a struct: a setter for each of
+// its fields, inherited ones included, and the RDL names of the required
+// fields its Build method checks are set.
+type modelBuilder struct {
+	Setters  []*builderSetter
+	Required []string
+}
+
+type builderSetter struct {
+	Field string
+	Key   string
+	Param string
+	Value string
+}
+
+// modelCSV describes the CSV record of a struct: its number of columns and
+// the statements formatting and parsing the fields of the mapped columns.
+type modelCSV struct {
+	Width   int
+	Columns []*csvColumn
+}
+
+type csvColumn struct {
+	Index  int
+	Format string
+	Parse  string
+}
+
+// modelSSE describes a struct sent as a server-sent event: its id, event and
+// retry metadata fields, if any, and the fields making up its data.
+type modelSSE struct {
+	Metadata []*sseMetadata
+	Data     []*modelField
+}
+
+type sseMetadata struct {
+	Key   string
+	Cond  string
+	Value string
+}
+
+type modelField struct {
+	Name      string
+	GoType    string
+	Tag       string
+	Comment   string
+	Normalize string
+	Encrypted bool
+}
+
+type handlerMethod struct {
+	Name    string
+	Request string
+	Comment string
+	Params  []string
+	Result  string
+}
+
+// GenerateGo generates plain Go declarations of the types of the schema and
+// the interface of the handlers of its resources, without dependencies on
+// the ardielle runtime. Structs become structs with json tags, embedding the
+// struct of their super type if any, enums become string types with a const
+// block of their elements, and the other types are defined from the Go type
+// of their base type. Optional fields are pointers, except for slices, maps
+// and interfaces whose nil value already means absent. Number types with
+// bounds get a Validate method checking them.
+//
+// Structs with normalized fields get a Normalize method replacing the value
+// of these fields by the result of their function in the generated
+// NormalizationRegistry. Fields whose function is not registered are left
+// unchanged.
+//
+// Structs with encrypted fields get Encrypt and Decrypt methods returning a
+// copy of the struct with these fields encrypted or decrypted by a
+// FieldCipher, using AES-GCM, to store their ciphertext in the database.
+//
+// Number types holding monetary amounts are aliases of the generated Money
+// type, a decimal amount and its currency, whose arithmetic is exact.
+//
+// Structs with a checksum get Compute and Verify methods setting and
+// checking their checksum field, the crc32, adler32 or sha256 checksum of the
+// JSON encoding of their other fields, in hexadecimal.
+//
+// Structs sent as server-sent events get a WriteSSE method writing them as
+// an event, their id, event and retry fields, inherited ones included, as
+// the metadata of the event and the JSON encoding of their other fields as
+// its data.
+//
+// Structs with renamed fields get an UnmarshalJSON method accepting the old
+// names of these fields as well as the new ones. The new name wins when both
+// are present. Structs with renamed fields cannot be extended, as their
+// UnmarshalJSON would be promoted to the structs embedding them.
+//
+// Structs with a CSV mapping get MarshalCSV and UnmarshalCSV methods
+// converting them to and from CSV records, with their fields in the mapped
+// columns and the other columns empty. Absent optional fields are empty.
+//
+// Structs generating a builder get a FooBuilder type, created by
+// NewFooBuilder, with a chainable setter for each of their fields, inherited
+// ones included, named after the field. Setters of optional fields take the
+// value rather than a pointer to it. Its Build method returns the Foo, or an
+// error when a required field was not set.
+//
+// Structs with sort fields get a Compare method ordering them by these
+// fields in turn, absent optional values first.
+//
+// Union types are not declared: GenerateGoUnions generates them, to be put in
+// the same package.
+func GenerateGo(s *rdl.Schema, packageName string, w io.Writer) error {
+	registry := rdl.NewTypeRegistry(s)
+	var types []*modelType
+	for _, t := range s.Types {
+		if t.UnionTypeDef != nil {
+			continue
+		}
+		mt, err := newModelType(registry, t)
+		if err != nil {
+			return err
+		}
+		types = append(types, mt)
+	}
+	var methods []*handlerMethod
+	for _, r := range s.Resources {
+		methods = append(methods, newHandlerMethod(registry, r))
+	}
+	uses := func(goType string) bool {
+		for _, mt := range types {
+			if strings.Contains(mt.GoType, goType) {
+				return true
+			}
+			for _, f := range mt.Fields {
+				if strings.Contains(f.GoType, goType) {
+					return true
+				}
+			}
+		}
+		for _, m := range methods {
+			if strings.Contains(strings.Join(m.Params, ","), goType) || strings.Contains(m.Result, goType) {
+				return true
+			}
+		}
+		return false
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName },
+		"types":   func() []*modelType { return types },
+		"methods": func() []*handlerMethod { return methods },
+		"handler": func() string { return utils.Capitalize(string(s.Name)) + "Handler" },
+		"usesFmt": func() bool {
+			for _, mt := range types {
+				if mt.Min != "" || mt.Max != "" || mt.Checksum != "" || mt.Money || mt.SSE != nil || mt.CSV != nil || (mt.Builder != nil && len(mt.Builder.Required) > 0) {
+					return true
+				}
+			}
+			return false
+		},
+		"usesTime": func() bool { return uses("time.Time") },
+		"normalized": func(mt *modelType) bool {
+			for _, f := range mt.Fields {
+				if f.Normalize != "" {
+					return true
+				}
+			}
+			return false
+		},
+		"usesNormalization": func() bool {
+			for _, mt := range types {
+				for _, f := range mt.Fields {
+					if f.Normalize != "" {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		"encrypted": func(mt *modelType) bool {
+			for _, f := range mt.Fields {
+				if f.Encrypted {
+					return true
+				}
+			}
+			return false
+		},
+		"usesEncryption": func() bool {
+			for _, mt := range types {
+				for _, f := range mt.Fields {
+					if f.Encrypted {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		"optional": func(f *modelField) bool { return strings.HasPrefix(f.GoType, "*") },
+		"usesChecksum": func(algorithm string) bool {
+			for _, mt := range types {
+				if mt.Checksum != "" && (algorithm == "" || mt.Checksum == algorithm) {
+					return true
+				}
+			}
+			return false
+		},
+		"usesStrconv": func() bool {
+			for _, mt := range types {
+				if mt.CSV == nil {
+					continue
+				}
+				for _, c := range mt.CSV.Columns {
+					if strings.Contains(c.Format, "strconv.") {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		"usesRenames": func() bool {
+			for _, mt := range types {
+				if len(mt.Renames) > 0 {
+					return true
+				}
+			}
+			return false
+		},
+		"usesSSE": func() bool {
+			for _, mt := range types {
+				if mt.SSE != nil {
+					return true
+				}
+			}
+			return false
+		},
+		"usesMoney": func() bool {
+			for _, mt := range types {
+				if mt.Money {
+					return true
+				}
+			}
+			return false
+		},
+		"usesCmp": func() bool {
+			for _, mt := range types {
+				if len(mt.Compare) > 0 {
+					return true
+				}
+			}
+			return false
+		},
+		"usesComparePointers": func() bool {
+			for _, mt := range types {
+				for _, c := range mt.Compare {
+					if strings.HasPrefix(c, "comparePointers(") {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		"join": strings.Join,
+		"list": func(items ...string) []string { return items },
+	}
+	return executeTemplate(w, "model", goModelTemplate, funcMap, s)
+}
+
+func newModelType(registry rdl.TypeRegistry, t *rdl.Type) (*modelType, error) {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	mt := &modelType{Name: typeVarName(rdl.TypeRef(tName)), Comment: tComment}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		if tType != "Struct" {
+			mt.Embedded = typeVarName(tType)
+		}
+		for _, f := range t.StructTypeDef.Fields {
+			field := newModelField(registry, f)
+			if f.NormalizeFn != "" {
+				if registry.FindBaseType(f.Type) != rdl.BaseTypeString {
+					return nil, fmt.Errorf("%s.%s: only String fields can be normalized", tName, f.Name)
+				}
+				field.Normalize = normalizeStatement(field, f.NormalizeFn)
+			}
+			if f.Encrypted {
+				if registry.FindBaseType(f.Type) != rdl.BaseTypeString {
+					return nil, fmt.Errorf("%s.%s: only String fields can be encrypted", tName, f.Name)
+				}
+				field.Encrypted = true
+			}
+			mt.Fields = append(mt.Fields, field)
+		}
+		if alg := t.StructTypeDef.ChecksumAlgorithm; alg != "" {
+			switch alg {
+			case "crc32", "adler32", "sha256":
+			default:
+				return nil, fmt.Errorf("%s: unsupported checksum algorithm %q", tName, alg)
+			}
+			var checksum *modelField
+			for _, f := range mt.Fields {
+				if f.Name == "Checksum" {
+					checksum = f
+				}
+			}
+			if checksum == nil || checksum.GoType != "*string" {
+				return nil, fmt.Errorf("%s: no optional String checksum field", tName)
+			}
+			mt.Checksum = alg
+		}
+		for st := registry.FindType(tType); st != nil && st.StructTypeDef != nil; st = registry.FindType(st.StructTypeDef.Type) {
+			if len(st.StructTypeDef.FieldMigrations) > 0 {
+				return nil, fmt.Errorf("%s: cannot extend %s, which has renamed fields", tName, st.StructTypeDef.Name)
+			}
+		}
+		for _, m := range t.StructTypeDef.FieldMigrations {
+			found := false
+			for _, f := range utils.FlattenedFields(registry, t) {
+				found = found || f.Name == m.NewName
+			}
+			if !found {
+				return nil, fmt.Errorf("%s: unknown renamed field %s", tName, m.NewName)
+			}
+			mt.Renames = append(mt.Renames, m)
+		}
+		if len(t.StructTypeDef.CSVMapping) > 0 {
+			csv, err := newModelCSV(registry, t)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", tName, err)
+			}
+			mt.CSV = csv
+		}
+		if t.StructTypeDef.IsSSEPayload {
+			sse, err := newModelSSE(registry, t)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", tName, err)
+			}
+			mt.SSE = sse
+		}
+		if t.StructTypeDef.GenerateBuilder {
+			b, err := newModelBuilder(registry, t)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", tName, err)
+			}
+			mt.Builder = b
+		}
+		for _, name := range t.StructTypeDef.SortFields {
+			c, err := compareExpression(registry, t, name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", tName, err)
+			}
+			mt.Compare = append(mt.Compare, c)
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		mt.GoType = "string"
+		for _, e := range t.EnumTypeDef.Elements {
+			mt.Elements = append(mt.Elements, string(e.Symbol))
+		}
+	case rdl.TypeVariantArrayTypeDef:
+		mt.GoType = modelGoType(registry, "Array", t.ArrayTypeDef.Items, "")
+	case rdl.TypeVariantMapTypeDef:
+		mt.GoType = modelGoType(registry, "Map", t.MapTypeDef.Items, t.MapTypeDef.Keys)
+	case rdl.TypeVariantNumberTypeDef:
+		mt.GoType = modelGoType(registry, tType, "", "")
+		if utils.IsCurrency(registry, rdl.TypeRef(tName)) {
+			mt.GoType = "Money"
+			mt.Money = true
+			break
+		}
+		if t.NumberTypeDef.Min != nil {
+			mt.Min = utils.NumberString(t.NumberTypeDef.Min)
+		}
+		if t.NumberTypeDef.Max != nil {
+			mt.Max = utils.NumberString(t.NumberTypeDef.Max)
+		}
+	default:
+		mt.GoType = modelGoType(registry, tType, "", "")
+	}
+	return mt, nil
+}
+
+// normalizeStatement returns the statement replacing the value of the field
+// by the result of the registered normalization function fn.
+func normalizeStatement(f *modelField, fn string) string {
+	value, elemType := "v."+f.Name, f.GoType
+	if strings.HasPrefix(f.GoType, "*") {
+		value, elemType = "*v."+f.Name, f.GoType[1:]
+	}
+	result := "fn(" + value + ")"
+	if elemType != "string" {
+		result = fmt.Sprintf("%s(fn(string(%s)))", elemType, value)
+	}
+	if elemType != f.GoType {
+		return fmt.Sprintf("if fn := NormalizationRegistry[%q]; fn != nil && v.%s != nil {\ns := %s\nv.%s = &s\n}", fn, f.Name, result, f.Name)
+	}
+	return fmt.Sprintf("if fn := NormalizationRegistry[%q]; fn != nil {\nv.%s = %s\n}", fn, f.Name, result)
+}
+
+// compareExpression returns the expression comparing the sort field name of
+// the struct values a and b, with cmp.Compare for ordered types and with the
+// Compare method of time.Time for timestamps. The field may be inherited.
+func newModelField(registry rdl.TypeRegistry, f *rdl.StructFieldDef) *modelField {
+	goType := modelGoType(registry, f.Type, f.Items, f.Keys)
+	tag := string(f.Name)
+	if f.Optional {
+		tag += ",omitempty"
+		goType = optionalGoType(registry, f.Type, goType)
+	}
+	return &modelField{
+		Name:    goName(string(f.Name)),
+		GoType:  goType,
+		Tag:     fmt.Sprintf("`json:%q`", tag),
+		Comment: f.Comment,
+	}
+}
+
+// newModelBuilder returns the builder of a struct, whose name must not be
+// taken by another type, nor its Build method by a field.
+func newModelBuilder(registry rdl.TypeRegistry, t *rdl.Type) (*modelBuilder, error) {
+	name := typeVarName(rdl.TypeRef(t.StructTypeDef.Name)) + "Builder"
+	if registry.FindType(rdl.TypeRef(name)) != nil {
+		return nil, fmt.Errorf("its builder conflicts with the type %s", name)
+	}
+	b := &modelBuilder{}
+	for _, f := range utils.FlattenedFields(registry, t) {
+		field := newModelField(registry, f)
+		if field.Name == "Build" {
+			return nil, fmt.Errorf("field %s conflicts with the Build method of the builder", f.Name)
+		}
+		setter := &builderSetter{Field: field.Name, Key: string(f.Name), Param: field.GoType, Value: "value"}
+		if strings.HasPrefix(field.GoType, "*") {
+			setter.Param, setter.Value = field.GoType[1:], "&value"
+		}
+		b.Setters = append(b.Setters, setter)
+		if !f.Optional {
+			b.Required = append(b.Required, string(f.Name))
+		}
+	}
+	return b, nil
+}
+
+// newModelSSE splits the fields of a server-sent event struct, inherited
+// ones included, into its metadata, the id and event strings and the retry
+// integer, and its data.
+func newModelSSE(registry rdl.TypeRegistry, t *rdl.Type) (*modelSSE, error) {
+	sse := &modelSSE{}
+	for _, f := range utils.FlattenedFields(registry, t) {
+		field := newModelField(registry, f)
+		bt := registry.FindBaseType(f.Type)
+		switch f.Name {
+		case "id", "event":
+			if bt != rdl.BaseTypeString {
+				return nil, fmt.Errorf("the %s field of a server-sent event must be a String", f.Name)
+			}
+		case "retry":
+			if bt != rdl.BaseTypeInt32 && bt != rdl.BaseTypeInt64 {
+				return nil, fmt.Errorf("the retry field of a server-sent event must be an Int32 or Int64")
+			}
+		default:
+			sse.Data = append(sse.Data, field)
+			continue
+		}
+		m := &sseMetadata{Key: string(f.Name), Value: "v." + field.Name}
+		switch {
+		case f.Optional:
+			m.Cond = m.Value + " != nil"
+			m.Value = "*" + m.Value
+		case bt == rdl.BaseTypeString:
+			m.Cond = m.Value + ` != ""`
+		default:
+			m.Cond = m.Value + " != 0"
+		}
+		sse.Metadata = append(sse.Metadata, m)
+	}
+	return sse, nil
+}
+
+// newModelCSV returns the CSV record of a struct with a CSV mapping, checking
+// the mapped fields exist, inherited ones included, and are scalars.
+func newModelCSV(registry rdl.TypeRegistry, t *rdl.Type) (*modelCSV, error) {
+	fields := make(map[rdl.Identifier]*rdl.StructFieldDef)
+	for _, f := range utils.FlattenedFields(registry, t) {
+		fields[f.Name] = f
+	}
+	csv := &modelCSV{}
+	used := make(map[int]bool)
+	for _, c := range t.StructTypeDef.CSVMapping {
+		f, ok := fields[c.FieldName]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV field %s", c.FieldName)
+		}
+		index := int(c.Column)
+		if index < 0 || used[index] {
+			return nil, fmt.Errorf("CSV column %d of %s is negative or already mapped", c.Column, c.FieldName)
+		}
+		used[index] = true
+		if utils.IsCurrency(registry, f.Type) {
+			return nil, fmt.Errorf("CSV field %s of type %s is not a scalar", f.Name, f.Type)
+		}
+		column, err := newCSVColumn(registry, t, newModelField(registry, f), f, index)
+		if err != nil {
+			return nil, err
+		}
+		csv.Columns = append(csv.Columns, column)
+		if index >= csv.Width {
+			csv.Width = index + 1
+		}
+	}
+	sort.Slice(csv.Columns, func(i, j int) bool { return csv.Columns[i].Index < csv.Columns[j].Index })
+	return csv, nil
+}
+
+// newCSVColumn returns the statements formatting the field into its column
+// of record and parsing it from that column.
+func newCSVColumn(registry rdl.TypeRegistry, t *rdl.Type, field *modelField, f *rdl.StructFieldDef, index int) (*csvColumn, error) {
+	goType, value := field.GoType, "v."+field.Name
+	optional := strings.HasPrefix(goType, "*")
+	if optional {
+		goType, value = goType[1:], "*"+value
+	}
+	var format, parse string
+	switch bt := registry.FindBaseType(f.Type); bt {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeEnum:
+		format = fmt.Sprintf("string(%s)", value)
+		conv := goType + "(%s)"
+		if goType == "string" {
+			format, conv = value, "%s"
+		}
+		if !optional {
+			return &csvColumn{
+				Index:  index,
+				Format: fmt.Sprintf("record[%d] = %s", index, format),
+				Parse:  fmt.Sprintf("v.%s = "+conv, field.Name, fmt.Sprintf("record[%d]", index)),
+			}, nil
+		}
+		parse = "x := " + fmt.Sprintf(conv, "s")
+	case rdl.BaseTypeBool:
+		format = fmt.Sprintf("strconv.FormatBool(bool(%s))", value)
+		parse = "b, err := strconv.ParseBool(s)"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		format = fmt.Sprintf("strconv.FormatInt(int64(%s), 10)", value)
+		parse = fmt.Sprintf("b, err := strconv.ParseInt(s, 10, %s)", strings.TrimPrefix(strings.ToLower(bt.String()), "int"))
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		bits := strings.TrimPrefix(strings.ToLower(bt.String()), "float")
+		format = fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, %s)", value, bits)
+		parse = fmt.Sprintf("b, err := strconv.ParseFloat(s, %s)", bits)
+	case rdl.BaseTypeTimestamp:
+		format = fmt.Sprintf("time.Time(%s).Format(time.RFC3339Nano)", value)
+		parse = "b, err := time.Parse(time.RFC3339Nano, s)"
+	default:
+		return nil, fmt.Errorf("CSV field %s of type %s is not a scalar", f.Name, f.Type)
+	}
+	record := fmt.Sprintf("record[%d]", index)
+	if strings.HasPrefix(parse, "b, err") {
+		parse += fmt.Sprintf("\nif err != nil {\nreturn fmt.Errorf(\"%s.%s: %%v\", err)\n}\nx := %s(b)", t.StructTypeDef.Name, f.Name, goType)
+	}
+	column := &csvColumn{Index: index, Format: fmt.Sprintf("%s = %s", record, format)}
+	if optional {
+		column.Format = fmt.Sprintf("if v.%s != nil {\n%s\n}", field.Name, column.Format)
+		column.Parse = fmt.Sprintf("v.%s = nil\nif s := %s; s != \"\" {\n%s\nv.%s = &x\n}", field.Name, record, parse, field.Name)
+	} else {
+		column.Parse = fmt.Sprintf("{\ns := %s\n%s\nv.%s = x\n}", record, parse, field.Name)
+	}
+	return column, nil
+}
+
+func compareExpression(registry rdl.TypeRegistry, t *rdl.Type, name rdl.Identifier) (string, error) {
+	var field *rdl.StructFieldDef
+	for st := t; st != nil && st.StructTypeDef != nil && field == nil; st = registry.FindType(st.StructTypeDef.Type) {
+		for _, f := range st.StructTypeDef.Fields {
+			if f.Name == name {
+				field = f
+			}
+		}
+	}
+	if field == nil {
+		return "", fmt.Errorf("unknown sort field %s", name)
+	}
+	goType := modelGoType(registry, field.Type, field.Items, field.Keys)
+	compare := "cmp.Compare"
+	switch registry.FindBaseType(field.Type) {
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64, rdl.BaseTypeFloat32, rdl.BaseTypeFloat64,
+		rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeEnum:
+	case rdl.BaseTypeTimestamp:
+		compare = "time.Time.Compare"
+	default:
+		return "", fmt.Errorf("sort field %s of type %s is not ordered", name, field.Type)
+	}
+	fname := goName(string(name))
+	if field.Optional {
+		if compare == "cmp.Compare" {
+			compare += "[" + goType + "]"
+		}
+		return fmt.Sprintf("comparePointers(a.%s, b.%s, %s)", fname, fname, compare), nil
+	}
+	if compare == "cmp.Compare" {
+		return fmt.Sprintf("cmp.Compare(a.%s, b.%s)", fname, fname), nil
+	}
+	return fmt.Sprintf("a.%s.Compare(b.%s)", fname, fname), nil
+}
+
+// optionalGoType returns the Go type of an optional value: a pointer, unless
+// the type is a slice, a map or an interface.
+func optionalGoType(registry rdl.TypeRegistry, ref rdl.TypeRef, goType string) string {
+	switch registry.FindBaseType(ref) {
+	case rdl.BaseTypeArray, rdl.BaseTypeMap, rdl.BaseTypeBytes, rdl.BaseTypeAny:
+		return goType
+	}
+	if goType == "map[string]interface{}" {
+		return goType
+	}
+	return "*" + goType
+}
+
+func newHandlerMethod(registry rdl.TypeRegistry, r *rdl.Resource) *handlerMethod {
+	m := &handlerMethod{
+		Name:    goName(methodName(r)),
+		Request: fmt.Sprintf("%s %s", strings.ToUpper(r.Method), r.Path),
+		Comment: r.Comment,
+		Params:  []string{"ctx context.Context"},
+	}
+	for _, in := range r.Inputs {
+		if in.Context != "" {
+			continue
+		}
+		goType := modelGoType(registry, in.Type, "", "")
+		if in.Optional || in.Default != nil {
+			goType = optionalGoType(registry, in.Type, goType)
+		}
+		m.Params = append(m.Params, fmt.Sprintf("%s %s", utils.Uncapitalize(string(in.Name)), goType))
+	}
+	m.Result = "error"
+	if code := rdl.StatusCode(r.Expected); code != "204" && code != "304" {
+		result := modelGoType(registry, r.Type, "", "")
+		if registry.FindBaseType(r.Type) == rdl.BaseTypeStruct {
+			result = "*" + result
+		}
+		m.Result = "(" + result + ", error)"
+	}
+	return m
+}
+
+// modelGoType returns the Go type of a reference to an RDL type: the name of
+// the types of the schema, and the Go equivalent of the base types.
+func modelGoType(registry rdl.TypeRegistry, ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch ref {
+	case "Array":
+		if items == "" {
+			items = "Any"
+		}
+		return "[]" + modelGoType(registry, items, "", "")
+	case "Map":
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		return "map[" + modelGoType(registry, keys, "", "") + "]" + modelGoType(registry, items, "", "")
+	case "Struct":
+		return "map[string]interface{}"
+	case "Any":
+		return "interface{}"
+	}
+	if !registry.IsBaseTypeName(ref) {
+		return typeVarName(ref)
+	}
+	switch registry.FindBaseType(ref) {
+	case rdl.BaseTypeBool:
+		return "bool"
+	case rdl.BaseTypeInt8:
+		return "int8"
+	case rdl.BaseTypeInt16:
+		return "int16"
+	case rdl.BaseTypeInt32:
+		return "int32"
+	case rdl.BaseTypeInt64:
+		return "int64"
+	case rdl.BaseTypeFloat32:
+		return "float32"
+	case rdl.BaseTypeFloat64:
+		return "float64"
+	case rdl.BaseTypeTimestamp:
+		return "time.Time"
+	case rdl.BaseTypeBytes:
+		return "[]byte"
+	default:
+		return "string"
+	}
+}
+
+const goModelTemplate = `{{header}}
+
+package {{package}}
+{{- if or usesFmt usesTime methods usesCmp usesEncryption usesMoney usesRenames usesStrconv}}
+
+import (
+{{- if usesSSE}}
+	"bytes"
+{{- end}}
+{{- if usesCmp}}
+	"cmp"
+{{- end}}
+{{- if methods}}
+	"context"
+{{- end}}
+{{- if usesEncryption}}
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+{{- end}}
+{{- if usesChecksum "sha256"}}
+	"crypto/sha256"
+{{- end}}
+{{- if usesEncryption}}
+	"encoding/base64"
+{{- end}}
+{{- if usesChecksum "sha256"}}
+	"encoding/hex"
+{{- end}}
+{{- if or (usesChecksum "") usesSSE usesRenames}}
+	"encoding/json"
+{{- end}}
+{{- if usesEncryption}}
+	"errors"
+{{- end}}
+{{- if usesFmt}}
+	"fmt"
+{{- end}}
+{{- if usesChecksum "adler32"}}
+	"hash/adler32"
+{{- end}}
+{{- if usesChecksum "crc32"}}
+	"hash/crc32"
+{{- end}}
+{{- if usesMoney}}
+	"math/big"
+{{- end}}
+{{- if usesSSE}}
+	"net/http"
+{{- end}}
+{{- if usesStrconv}}
+	"strconv"
+{{- end}}
+{{- if usesMoney}}
+	"strings"
+{{- end}}
+{{- if usesTime}}
+	"time"
+{{- end}}
+)
+{{- end}}
+{{- if usesNormalization}}
+
+// NormalizationRegistry maps the names of normalization functions to their
+// implementation, called by the Normalize methods.
+var NormalizationRegistry = map[string]func(string) string{}
+{{- end}}
+{{- if usesMoney}}
+
+// Money is a monetary amount: a decimal number, kept as a string so that no
+// precision is lost, in a currency, as an ISO 4217 code.
+type Money struct {
+	Amount   string ` + "`" + `json:"amount"` + "`" + `
+	Currency string ` + "`" + `json:"currency"` + "`" + `
+}
+
+// Add returns the sum of the amounts, which must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	return m.combine(o, (*big.Rat).Add)
+}
+
+// Sub returns the difference of the amounts, which must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.combine(o, (*big.Rat).Sub)
+}
+
+// Mul returns the amount multiplied by the decimal factor.
+func (m Money) Mul(factor string) (Money, error) {
+	a, err := parseAmount(m.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	f, err := parseAmount(factor)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: a.Mul(a, f).FloatString(decimals(m.Amount) + decimals(factor)), Currency: m.Currency}, nil
+}
+
+func (m Money) combine(o Money, op func(z, x, y *big.Rat) *big.Rat) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("amounts in %s and %s cannot be combined", m.Currency, o.Currency)
+	}
+	a, err := parseAmount(m.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	b, err := parseAmount(o.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	scale := decimals(m.Amount)
+	if d := decimals(o.Amount); d > scale {
+		scale = d
+	}
+	return Money{Amount: op(a, a, b).FloatString(scale), Currency: m.Currency}, nil
+}
+
+// parseAmount parses a decimal amount, without exponent.
+func parseAmount(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return r, nil
+}
+
+// decimals returns the number of digits after the decimal point of an amount.
+func decimals(s string) int {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+{{- end}}
+{{- if usesEncryption}}
+
+// FieldCipher encrypts the values of encrypted fields with AES-GCM, as the
+// base64 encoding of a random nonce followed by the sealed value.
+type FieldCipher struct {
+	aead cipher.AEAD
+}
+
+// NewFieldCipher returns a cipher using the AES key, of 16, 24 or 32 bytes.
+func NewFieldCipher(key []byte) (*FieldCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FieldCipher{aead: aead}, nil
+}
+
+// Encrypt returns the ciphertext of the plaintext.
+func (c *FieldCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// Decrypt returns the plaintext of the ciphertext.
+func (c *FieldCipher) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], nil)
+	return string(plaintext), err
+}
+
+// crypt replaces the value of the field by its encryption or decryption.
+func crypt[T ~string](field *T, f func(string) (string, error)) error {
+	s, err := f(string(*field))
+	if err != nil {
+		return err
+	}
+	*field = T(s)
+	return nil
+}
+{{- end}}
+{{- if usesComparePointers}}
+
+// comparePointers compares optional values, absent values first.
+func comparePointers[T any](a, b *T, compare func(T, T) int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return compare(*a, *b)
+}
+{{- end}}
+{{range types}}
+{{- $t := .}}
+// {{.Name}}{{if .Comment}} - {{.Comment}}{{else}} is generated from its RDL type.{{end}}
+{{- if .Fields}}
+type {{.Name}} struct {
+{{- if .Embedded}}
+	{{.Embedded}}
+{{- end}}
+{{- range $i, $f := .Fields}}
+{{- if .Comment}}
+{{- if $i}}
+{{end}}
+	// {{.Comment}}
+{{- end}}
+	{{.Name}} {{.GoType}} {{.Tag}}
+{{- end}}
+}
+{{- else if not .GoType}}
+type {{.Name}} struct {
+{{- if .Embedded}}
+	{{.Embedded}}
+{{- end}}
+}
+{{- else if .Money}}
+type {{.Name}} = Money
+{{- else}}
+type {{.Name}} {{.GoType}}
+{{- end}}
+{{- if .Elements}}
+{{$t := .}}
+// {{.Name}} values
+const (
+{{- range .Elements}}
+	{{$t.Name}}{{.}} {{$t.Name}} = "{{.}}"
+{{- end}}
+)
+{{- end}}
+{{- if or .Min .Max}}
+
+// Validate checks the {{.Name}} is within its bounds.
+func (v {{.Name}}) Validate() error {
+{{- if .Min}}
+	if v < {{.Min}} {
+		return fmt.Errorf("{{.Name}}: %v is less than {{.Min}}", v)
+	}
+{{- end}}
+{{- if .Max}}
+	if v > {{.Max}} {
+		return fmt.Errorf("{{.Name}}: %v is greater than {{.Max}}", v)
+	}
+{{- end}}
+	return nil
+}
+{{- end}}
+{{- if normalized .}}
+
+// Normalize applies the registered normalization functions to the fields of
+// the {{.Name}}.
+func (v *{{.Name}}) Normalize() {
+{{- range .Fields}}
+{{- if .Normalize}}
+	{{.Normalize}}
+{{- end}}
+{{- end}}
+}
+{{- end}}
+{{- if encrypted .}}
+{{$t := .}}
+{{- range $method := list "Encrypt" "Decrypt"}}
+// {{$method}} returns a copy of the {{$t.Name}} whose encrypted fields are {{if eq $method "Encrypt"}}encrypted{{else}}decrypted{{end}}.
+func (v {{$t.Name}}) {{$method}}(c *FieldCipher) ({{$t.Name}}, error) {
+{{- range $t.Fields}}
+{{- if .Encrypted}}
+{{- if optional .}}
+	if v.{{.Name}} != nil {
+		value := *v.{{.Name}}
+		if err := crypt(&value, c.{{$method}}); err != nil {
+			return {{$t.Name}}{}, err
+		}
+		v.{{.Name}} = &value
+	}
+{{- else}}
+	if err := crypt(&v.{{.Name}}, c.{{$method}}); err != nil {
+		return {{$t.Name}}{}, err
+	}
+{{- end}}
+{{- end}}
+{{- end}}
+	return v, nil
+}
+{{end}}
+{{- end}}
+{{- if .Checksum}}
+
+// checksum returns the {{.Checksum}} checksum of the other fields of the {{.Name}}.
+func (v *{{.Name}}) checksum() (string, error) {
+	c := *v
+	c.Checksum = nil
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+{{- if eq .Checksum "sha256"}}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+{{- else if eq .Checksum "adler32"}}
+	return fmt.Sprintf("%08x", adler32.Checksum(data)), nil
+{{- else}}
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)), nil
+{{- end}}
+}
+
+// Compute sets the checksum of the {{.Name}}.
+func (v *{{.Name}}) Compute() error {
+	sum, err := v.checksum()
+	if err != nil {
+		return err
+	}
+	v.Checksum = &sum
+	return nil
+}
+
+// Verify checks the checksum of the {{.Name}} matches its other fields.
+func (v *{{.Name}}) Verify() error {
+	if v.Checksum == nil {
+		return fmt.Errorf("{{.Name}} has no checksum")
+	}
+	sum, err := v.checksum()
+	if err != nil {
+		return err
+	}
+	if *v.Checksum != sum {
+		return fmt.Errorf("{{.Name}}: checksum %s does not match %s", *v.Checksum, sum)
+	}
+	return nil
+}
+{{- end}}
+{{- if .Renames}}
+
+// UnmarshalJSON decodes a {{.Name}}, accepting the old name of a renamed
+// field when its new name is absent.
+{{- range .Renames}}
+// {{.OldName}} became {{.NewName}} in version {{.SinceVersion}}.
+{{- end}}
+func (v *{{.Name}}) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return nil
+	}
+	for _, r := range [][2]string{
+{{- range .Renames}}
+		{ {{- printf "%q" .OldName}}, {{printf "%q" .NewName -}} },
+{{- end}}
+	} {
+		if value, ok := fields[r[0]]; ok {
+			if _, ok := fields[r[1]]; !ok {
+				fields[r[1]] = value
+			}
+			delete(fields, r[0])
+		}
+	}
+	migrated, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	type plain {{.Name}}
+	return json.Unmarshal(migrated, (*plain)(v))
+}
+{{- end}}
+{{- if .CSV}}
+
+// MarshalCSV returns the CSV record of the {{.Name}}, with its fields in their
+// columns.
+func (v *{{.Name}}) MarshalCSV() []string {
+	record := make([]string, {{.CSV.Width}})
+{{- range .CSV.Columns}}
+	{{.Format}}
+{{- end}}
+	return record
+}
+
+// UnmarshalCSV sets the fields of the {{.Name}} from their columns of the CSV
+// record.
+func (v *{{.Name}}) UnmarshalCSV(record []string) error {
+	if len(record) < {{.CSV.Width}} {
+		return fmt.Errorf("{{.Name}}: CSV record of %d columns, expected {{.CSV.Width}}", len(record))
+	}
+{{- range .CSV.Columns}}
+	{{.Parse}}
+{{- end}}
+	return nil
+}
+{{- end}}
+{{- if .SSE}}
+
+// WriteSSE writes the {{.Name}} to w as a server-sent event, whose data is
+// the JSON encoding of its fields other than id, event and retry.
+func (v *{{.Name}}) WriteSSE(w http.ResponseWriter) error {
+	data, err := json.Marshal(struct {
+{{- range .SSE.Data}}
+		{{.Name}} {{.GoType}} {{.Tag}}
+{{- end}}
+	}{ {{- range $i, $f := .SSE.Data}}{{if $i}}, {{end}}v.{{$f.Name}}{{end -}} })
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+{{- range .SSE.Metadata}}
+	if {{.Cond}} {
+		fmt.Fprintf(&buf, "{{.Key}}: %v\n", {{.Value}})
+	}
+{{- end}}
+	fmt.Fprintf(&buf, "data: %s\n\n", data)
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+{{- end}}
+{{- with .Builder}}
+{{- $name := print $t.Name "Builder"}}
+
+// {{$name}} builds a {{$t.Name}} with chained setters.
+type {{$name}} struct {
+	v   {{$t.Name}}
+	set map[string]bool
+}
+
+// New{{$name}} returns a builder of an empty {{$t.Name}}.
+func New{{$name}}() *{{$name}} {
+	return &{{$name}}{set: make(map[string]bool)}
+}
+{{- range .Setters}}
+
+// {{.Field}} sets the {{.Key}} field.
+func (b *{{$name}}) {{.Field}}(value {{.Param}}) *{{$name}} {
+	b.v.{{.Field}} = {{.Value}}
+	b.set[{{printf "%q" .Key}}] = true
+	return b
+}
+{{- end}}
+
+// Build returns the {{$t.Name}}, or an error if a required field was not set.
+func (b *{{$name}}) Build() (*{{$t.Name}}, error) {
+{{- if .Required}}
+	for _, name := range []string{ {{- range $i, $r := .Required}}{{if $i}}, {{end}}{{printf "%q" $r}}{{end -}} } {
+		if !b.set[name] {
+			return nil, fmt.Errorf("{{$t.Name}}: missing required field %s", name)
+		}
+	}
+{{- end}}
+	v := b.v
+	return &v, nil
+}
+{{- end}}
+{{- if .Compare}}
+
+// Compare returns -1 when a sorts before b, 1 when it sorts after b and 0
+// otherwise.
+func (a {{.Name}}) Compare(b {{.Name}}) int {
+{{- range .Compare}}
+	if c := {{.}}; c != 0 {
+		return c
+	}
+{{- end}}
+	return 0
+}
+{{- end}}
+{{end}}
+{{- if methods}}
+// {{handler}} is implemented by the handlers of the resources.
+type {{handler}} interface {
+{{- range methods}}
+	// {{.Name}} handles {{.Request}}{{if .Comment}}: {{.Comment}}{{end}}
+	{{.Name}}({{join .Params ", "}}) {{.Result}}
+{{- end}}
+}
+{{- end}}
+`
@@ -0,0 +1,844 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// modelRuntimeTest runs against the generated types.
+const modelRuntimeTest = `package sample
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestModel(t *testing.T) {
+	age := Age(30)
+	data, err := json.Marshal(&Admin{User: User{Id: "bob", Role: RoleADMIN, Age: &age}, Region: "us-east"})
+	if err != nil || string(data) != ` + "`" + `{"id":"bob","role":"ADMIN","age":30,"created":"0001-01-01T00:00:00Z","region":"us-east"}` + "`" + ` {
+		t.Fatalf("unexpected encoding %s: %v", data, err)
+	}
+	if err := Age(200).Validate(); err == nil || err.Error() != "Age: 200 is greater than 150" {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if err := age.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+`
+
+func modelSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStringTypeBuilder("UserId").Pattern("[a-z][a-z0-9]*").Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Int32", "Age").Min(rdl.NewNumber(int32(0))).Max(rdl.NewNumber(int32(150))).Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Role").Element("ADMIN", "").Element("MEMBER", "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Comment("A user of the service").
+		Field("id", "UserId", false, nil, "the user id").
+		Field("role", "Role", false, nil, "").
+		Field("age", "Age", true, nil, "").
+		ArrayField("tags", "String", true, "").
+		MapField("labels", "String", "Int64", true, "").
+		Field("created", "Timestamp", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("User", "Admin").Field("region", "String", false, nil, "").Build())
+	sb.AddType(rdl.NewArrayTypeBuilder("Array", "Users").Items("User").Build())
+	sb.AddType(rdl.NewMapTypeBuilder("Map", "UserIndex").Keys("UserId").Items("User").Build())
+	sb.AddType(rdl.NewUnionTypeBuilder("Union", "Member").Variant("User").Variant("Admin").Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Comment("Get a user").
+		Input("id", "UserId", true, "", "", false, nil, "").
+		Input("fields", "String", false, "fields", "", true, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Member", "PUT", "/members/{id}").
+		Input("id", "UserId", true, "", "", false, nil, "").
+		Input("member", "Member", false, "", "", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "DELETE", "/users/{id}").
+		Name("RemoveUser").
+		Input("id", "UserId", true, "", "", false, nil, "").
+		Expected("NO_CONTENT").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGo(test *testing.T) {
+	schema := modelSchema()
+	var buf, unions bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	if err := GenerateGoUnions(schema, &unions, GoUnionOptions{}); err != nil {
+		test.Fatalf("cannot generate unions: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "model.go", buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		test.Fatalf("generated Go types do not parse: %v\n%s", err, buf.String())
+	}
+	u, err := parser.ParseFile(fset, "union.go", unions.Bytes(), 0)
+	if err != nil {
+		test.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{f, u}, nil); err != nil {
+		test.Fatalf("generated Go types do not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"type UserId string\n",
+		"\tRoleADMIN  Role = \"ADMIN\"\n",
+		"type User struct {\n\t// the user id\n\tId      UserId           `json:\"id\"`\n",
+		"\tAge     *Age             `json:\"age,omitempty\"`\n",
+		"\tTags    []string         `json:\"tags,omitempty\"`\n",
+		"type Admin struct {\n\tUser\n",
+		"type Users []User\n",
+		"type UserIndex map[UserId]User\n",
+		"func (v Age) Validate() error {\n",
+		"\t// GetUser handles GET /users/{id}: Get a user\n\tGetUser(ctx context.Context, id UserId, fields *string) (*User, error)\n",
+		"\tPutMember(ctx context.Context, id UserId, member Member) (Member, error)\n",
+		"\tRemoveUser(ctx context.Context, id UserId) error\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "type Member") {
+		test.Errorf("union type declared with the other types:\n%s", src)
+	}
+	runGoTest(test, map[string]string{
+		"model.go":      src,
+		"union.go":      unions.String(),
+		"model_test.go": modelRuntimeTest,
+	})
+}
+
+const normalizeTest = `package sample
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	NormalizationRegistry["lower"] = strings.ToLower
+	NormalizationRegistry["trim"] = strings.TrimSpace
+	nickname := Name("  Bob ")
+	c := Contact{Email: "Bob@Example.COM", Name: " Bob ", Nickname: &nickname, Phone: " 555 "}
+	c.Normalize()
+	if c.Email != "bob@example.com" || c.Name != "Bob" || *c.Nickname != "Bob" || c.Phone != " 555 " {
+		t.Errorf("unexpected normalized contact %+v, nickname %q", c, *c.Nickname)
+	}
+	c = Contact{}
+	c.Normalize()
+	if c.Nickname != nil {
+		t.Errorf("absent nickname set to %q", *c.Nickname)
+	}
+}
+`
+
+func TestGenerateGoNormalize(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStringTypeBuilder("Name").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Contact").
+		Field("email", "String", false, nil, "").
+		Field("name", "Name", false, nil, "").
+		Field("nickname", "Name", true, nil, "").
+		Field("phone", "String", false, nil, "").
+		Field("age", "Int32", true, nil, "").
+		NormalizeField("email", "lower").
+		NormalizeField("name", "trim").
+		NormalizeField("nickname", "trim").
+		NormalizeField("phone", "phone").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"var NormalizationRegistry = map[string]func(string) string{}\n",
+		"func (v *Contact) Normalize() {\n\tif fn := NormalizationRegistry[\"lower\"]; fn != nil {\n\t\tv.Email = fn(v.Email)\n\t}\n",
+		"\t\tv.Name = Name(fn(string(v.Name)))\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"model.go":          src,
+		"normalize_test.go": normalizeTest,
+	})
+
+	schema.Types[1].StructTypeDef.Fields[4].NormalizeFn = "round"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for a normalized Int32 field")
+	}
+}
+
+const compareTest = `package sample
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	low, high := Priority(1), Priority(2)
+	now := time.Now()
+	later := now.Add(time.Hour)
+	for _, c := range []struct {
+		a, b     Task
+		expected int
+	}{
+		{Task{Title: "a"}, Task{Title: "a"}, 0},
+		{Task{Title: "a"}, Task{Title: "b"}, -1},
+		{Task{Title: "b"}, Task{Title: "a"}, 1},
+		{Task{Priority: &low, Title: "b"}, Task{Priority: &high, Title: "a"}, -1},
+		{Task{Priority: &high}, Task{Priority: &low}, 1},
+		{Task{Title: "b"}, Task{Priority: &low, Title: "a"}, -1},
+		{Task{Priority: &low}, Task{}, 1},
+		{Task{Priority: &low, Due: &now}, Task{Priority: &low, Due: &later}, -1},
+		{Task{Priority: &low, Due: &later, Title: "a"}, Task{Priority: &low, Due: &now, Title: "b"}, 1},
+		{Task{Priority: &low, Due: &now, Title: "a"}, Task{Priority: &low, Due: &now, Title: "a"}, 0},
+	} {
+		if r := c.a.Compare(c.b); r != c.expected {
+			t.Errorf("%+v compared to %+v: %d, expected %d", c.a, c.b, r, c.expected)
+		}
+	}
+	subtasks := []SubTask{{Task: Task{Title: "c"}}, {Task: Task{Title: "a"}}, {Task: Task{Title: "b"}}}
+	slices.SortFunc(subtasks, SubTask.Compare)
+	if subtasks[0].Title != "a" || subtasks[2].Title != "c" {
+		t.Errorf("unexpected order %+v", subtasks)
+	}
+}
+`
+
+func TestGenerateGoCompare(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewNumberTypeBuilder("Int32", "Priority").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Task").
+		Field("title", "String", false, nil, "").
+		Field("priority", "Priority", true, nil, "").
+		Field("due", "Timestamp", true, nil, "").
+		Field("done", "Bool", false, nil, "").
+		SortBy("priority", "due", "title").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Task", "SubTask").
+		Field("parent", "String", false, nil, "").
+		SortBy("title").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"func (a Task) Compare(b Task) int {\n\tif c := comparePointers(a.Priority, b.Priority, cmp.Compare[Priority]); c != 0 {\n",
+		"\tif c := comparePointers(a.Due, b.Due, time.Time.Compare); c != 0 {\n",
+		"\tif c := cmp.Compare(a.Title, b.Title); c != 0 {\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":          "module sample\n\ngo 1.22\n",
+		"model.go":        src,
+		"compare_test.go": compareTest,
+	})
+
+	for _, field := range []rdl.Identifier{"done", "unknown"} {
+		schema.Types[1].StructTypeDef.SortFields = []rdl.Identifier{field}
+		if err := GenerateGo(schema, "sample", &buf); err == nil {
+			test.Errorf("expected an error for sort field %s", field)
+		}
+	}
+}
+
+const encryptTest = `package sample
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	c, err := NewFieldCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := "allergic to penicillin"
+	patient := Patient{Name: "jane", Ssn: "123-45-6789", Notes: &notes}
+	stored, err := patient.Encrypt(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "jane" || stored.Ssn == patient.Ssn || strings.Contains(string(stored.Ssn), "6789") || *stored.Notes == notes {
+		t.Errorf("plaintext stored: %+v", stored)
+	}
+	if *patient.Notes != notes {
+		t.Errorf("encryption changed the original value to %q", *patient.Notes)
+	}
+	loaded, err := stored.Decrypt(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Ssn != patient.Ssn || *loaded.Notes != notes {
+		t.Errorf("round trip: %+v, expected %+v", loaded, patient)
+	}
+	if again, _ := patient.Encrypt(c); again.Ssn == stored.Ssn {
+		t.Errorf("same ciphertext for two encryptions")
+	}
+	other, _ := NewFieldCipher([]byte("fedcba9876543210fedcba9876543210"))
+	if _, err := stored.Decrypt(other); err == nil {
+		t.Errorf("decrypted with another key")
+	}
+	if empty, err := (Patient{}).Encrypt(c); err != nil || empty.Notes != nil {
+		t.Errorf("absent value encrypted: %+v, %v", empty, err)
+	}
+}
+`
+
+func TestGenerateGoEncrypted(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStringTypeBuilder("Ssn").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Patient").
+		Field("name", "String", false, nil, "").
+		Field("ssn", "Ssn", false, nil, "").
+		Field("notes", "String", true, nil, "").
+		Field("age", "Int32", true, nil, "").
+		EncryptedField("ssn").
+		EncryptedField("notes").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"func NewFieldCipher(key []byte) (*FieldCipher, error) {\n",
+		"\n\n// Encrypt returns a copy of the Patient whose encrypted fields are encrypted.\nfunc (v Patient) Encrypt(c *FieldCipher) (Patient, error) {\n\tif err := crypt(&v.Ssn, c.Encrypt); err != nil {\n",
+		"\n\n// Decrypt returns a copy of the Patient whose encrypted fields are decrypted.\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":          "module sample\n\ngo 1.22\n",
+		"model.go":        src,
+		"encrypt_test.go": encryptTest,
+	})
+
+	schema.Types[1].StructTypeDef.Fields[3].Encrypted = true
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for an encrypted Int32 field")
+	}
+}
+
+const checksumTest = `package sample
+
+import (
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+func fmt8x(sum uint32) string {
+	return fmt.Sprintf("%08x", sum)
+}
+
+type checksummed interface {
+	Compute() error
+	Verify() error
+}
+
+func TestChecksum(t *testing.T) {
+	crc := &CrcPacket{Seq: 1, Payload: "hello"}
+	adler := &AdlerPacket{Seq: 1, Payload: "hello"}
+	sha := &ShaPacket{Seq: 1, Payload: "hello"}
+	for _, v := range []checksummed{crc, adler, sha} {
+		if err := v.Verify(); err == nil {
+			t.Errorf("%T without checksum verified", v)
+		}
+		if err := v.Compute(); err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Verify(); err != nil {
+			t.Errorf("%T: %v", v, err)
+		}
+	}
+	if len(*crc.Checksum) != 8 || len(*adler.Checksum) != 8 || len(*sha.Checksum) != 64 || *crc.Checksum == *adler.Checksum {
+		t.Errorf("unexpected checksums %s, %s, %s", *crc.Checksum, *adler.Checksum, *sha.Checksum)
+	}
+	if sum := crc32.ChecksumIEEE([]byte(` + "`" + `{"seq":1,"payload":"hello"}` + "`" + `)); *crc.Checksum != fmt8x(sum) {
+		t.Errorf("crc32 checksum %s, expected %s", *crc.Checksum, fmt8x(sum))
+	}
+	crc.Payload = "hellO"
+	adler.Seq = 2
+	wrong := "00000000"
+	sha.Checksum = &wrong
+	for _, v := range []checksummed{crc, adler, sha} {
+		if err := v.Verify(); err == nil {
+			t.Errorf("%T with a wrong checksum verified", v)
+		}
+	}
+}
+`
+
+func TestGenerateGoChecksum(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	for _, c := range []struct{ name, algorithm string }{
+		{"CrcPacket", "crc32"},
+		{"AdlerPacket", "adler32"},
+		{"ShaPacket", "sha256"},
+	} {
+		sb.AddType(rdl.NewStructTypeBuilder("Struct", c.name).
+			Field("seq", "Int64", false, nil, "").
+			Field("payload", "String", false, nil, "").
+			WithChecksum(c.algorithm).
+			Build())
+	}
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"\tChecksum *string `json:\"checksum,omitempty\"`\n",
+		"func (v *CrcPacket) Compute() error {\n",
+		"func (v *ShaPacket) Verify() error {\n",
+		"\treturn fmt.Sprintf(\"%08x\", adler32.Checksum(data)), nil\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"model.go":         src,
+		"checksum_test.go": checksumTest,
+	})
+
+	schema.Types[0].StructTypeDef.ChecksumAlgorithm = "md5"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for an unsupported checksum algorithm")
+	}
+}
+
+const moneyTest = `package sample
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	usd := func(amount string) Price { return Price{Amount: amount, Currency: "USD"} }
+	for _, c := range []struct {
+		op       func() (Money, error)
+		expected string
+	}{
+		{func() (Money, error) { return usd("0.1").Add(usd("0.2")) }, "0.3"},
+		{func() (Money, error) { return usd("12345678901234.5678").Add(usd("0.0001")) }, "12345678901234.5679"},
+		{func() (Money, error) { return usd("99999999999999999999.99").Add(usd("0.01")) }, "100000000000000000000.00"},
+		{func() (Money, error) { return usd("10.00").Sub(usd("0.015")) }, "9.985"},
+		{func() (Money, error) { return usd("19.99").Mul("0.15") }, "2.9985"},
+	} {
+		m, err := c.op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Amount != c.expected || m.Currency != "USD" {
+			t.Errorf("expected %s USD, got %s %s", c.expected, m.Amount, m.Currency)
+		}
+	}
+	if _, err := usd("1").Add(Money{Amount: "1", Currency: "EUR"}); err == nil {
+		t.Errorf("expected an error adding amounts in different currencies")
+	}
+	if _, err := usd("1e3").Add(usd("1")); err == nil {
+		t.Errorf("expected an error for an amount with an exponent")
+	}
+	var item Item
+	if err := json.Unmarshal([]byte(` + "`" + `{"price":{"amount":"0.30","currency":"USD"}}` + "`" + `), &item); err != nil {
+		t.Fatal(err)
+	}
+	var sale SalePrice = item.Price
+	if sale.Amount != "0.30" {
+		t.Errorf("expected the amount 0.30 to be kept as is, got %s", sale.Amount)
+	}
+}
+`
+
+func TestGenerateGoMoney(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewNumberTypeBuilder("Int64", "Price").Currency(true).Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Price", "SalePrice").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Item").
+		Field("price", "Price", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateGo(gentest.MustBuild(sb), "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"type Price = Money\n",
+		"type SalePrice = Money\n",
+		"func (m Money) Add(o Money) (Money, error) {\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"model.go":      src,
+		"money_test.go": moneyTest,
+	})
+}
+
+const ssePayloadTest = `package sample
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteSSE(t *testing.T) {
+	retry := int32(3000)
+	for _, c := range []struct {
+		tick     Tick
+		expected string
+	}{
+		{Tick{Message: Message{Id: "7", Event: "tick"}, Symbol: "ACME", Price: 12.5, Retry: &retry}, "id: 7\nevent: tick\nretry: 3000\ndata: {\"symbol\":\"ACME\",\"price\":12.5}\n\n"},
+		{Tick{Symbol: "ACME", Price: 1}, "data: {\"symbol\":\"ACME\",\"price\":1}\n\n"},
+	} {
+		rec := httptest.NewRecorder()
+		if err := c.tick.WriteSSE(rec); err != nil {
+			t.Fatal(err)
+		}
+		if body := rec.Body.String(); body != c.expected {
+			t.Errorf("expected event %q, got %q", c.expected, body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("expected the text/event-stream content type, got %q", ct)
+		}
+		if !rec.Flushed {
+			t.Errorf("expected the event to be flushed")
+		}
+	}
+}
+`
+
+func TestGenerateGoSSE(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Message").
+		Field("id", "String", false, nil, "").
+		Field("event", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Message", "Tick").
+		Field("symbol", "String", false, nil, "").
+		Field("price", "Float64", false, nil, "").
+		Field("retry", "Int32", true, nil, "").
+		IsSSEPayload(true).
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	expected := "func (v *Tick) WriteSSE(w http.ResponseWriter) error {\n"
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"model.go":    src,
+		"sse_test.go": ssePayloadTest,
+	})
+
+	schema.Types[1].StructTypeDef.Fields[2].Type = "String"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for a String retry field")
+	}
+}
+
+const renameTest = `package sample
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnmarshalRenamed(t *testing.T) {
+	for _, c := range []struct {
+		payload  string
+		expected Account
+	}{
+		{` + "`" + `{"login":"jane","mail":"jane@example.com"}` + "`" + `, Account{Name: "jane", Email: "jane@example.com"}},
+		{` + "`" + `{"name":"jane","email":"jane@example.com"}` + "`" + `, Account{Name: "jane", Email: "jane@example.com"}},
+		{` + "`" + `{"login":"old","name":"new"}` + "`" + `, Account{Name: "new"}},
+	} {
+		var a Account
+		if err := json.Unmarshal([]byte(c.payload), &a); err != nil {
+			t.Fatal(err)
+		}
+		if a != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.payload, c.expected, a)
+		}
+	}
+	var a Account
+	if err := json.Unmarshal([]byte(` + "`" + `{"login":1}` + "`" + `), &a); err == nil {
+		t.Errorf("expected an error for a number as name")
+	}
+}
+`
+
+func TestGenerateGoRenames(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Account").
+		Field("name", "String", false, nil, "").
+		Field("email", "String", false, nil, "").
+		MigrateField("login", "name", 2).
+		MigrateField("mail", "email", 3).
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	expected := "// login became name in version 2.\n"
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"model.go":       src,
+		"rename_test.go": renameTest,
+	})
+
+	sb.AddType(rdl.NewStructTypeBuilder("Account", "Admin").Build())
+	if err := GenerateGo(gentest.MustBuild(sb), "sample", &buf); err == nil {
+		test.Errorf("expected an error for a struct extending a struct with renamed fields")
+	}
+	schema.Types[0].StructTypeDef.FieldMigrations[0].NewName = "nickname"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for the rename of an unknown field")
+	}
+}
+
+const csvTest = `package sample
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCSV(t *testing.T) {
+	score := 9.5
+	joined := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	players := []Player{
+		{Person: Person{Name: "jane, \"the ace\""}, Age: 30, Score: &score, Active: true, Joined: joined, Level: LevelPRO},
+		{Person: Person{Name: "joe"}, Age: 7, Level: LevelAMATEUR, Joined: joined},
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, p := range players {
+		if err := w.Write(p.MarshalCSV()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	expected := "PRO,\"jane, \"\"the ace\"\"\",,30,9.5,true,2016-01-02T03:04:05Z\n" +
+		"AMATEUR,joe,,7,,false,2016-01-02T03:04:05Z\n"
+	if buf.String() != expected {
+		t.Errorf("expected CSV\n%s\ngot\n%s", expected, buf.String())
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, record := range records {
+		var p Player
+		if err := p.UnmarshalCSV(record); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p, players[i]) {
+			t.Errorf("expected %+v, got %+v", players[i], p)
+		}
+	}
+	var p Player
+	if err := p.UnmarshalCSV([]string{"PRO", "jane"}); err == nil {
+		t.Errorf("expected an error for a short record")
+	}
+	if err := p.UnmarshalCSV([]string{"PRO", "jane", "", "old", "", "true", "2016-01-02T03:04:05Z"}); err == nil {
+		t.Errorf("expected an error for an age that is not a number")
+	}
+}
+`
+
+func TestGenerateGoCSV(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewNumberTypeBuilder("Int32", "Age").Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Level").Element("AMATEUR", "").Element("PRO", "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Person").
+		Field("name", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Person", "Player").
+		Field("age", "Age", false, nil, "").
+		Field("score", "Float64", true, nil, "").
+		Field("active", "Bool", false, nil, "").
+		Field("joined", "Timestamp", false, nil, "").
+		Field("level", "Level", false, nil, "").
+		CSVColumn("joined", 6).
+		CSVColumn("name", 1).
+		CSVColumn("age", 3).
+		CSVColumn("score", 4).
+		CSVColumn("active", 5).
+		CSVColumn("level", 0).
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"func (v *Player) MarshalCSV() []string {\n",
+		"func (v *Player) UnmarshalCSV(record []string) error {\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"model.go":    src,
+		"csv_test.go": csvTest,
+	})
+
+	mapping := schema.Types[3].StructTypeDef.CSVMapping
+	mapping[0].Column = 1
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for a column mapped twice")
+	}
+	mapping[0].Column, mapping[0].FieldName = 6, "nickname"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for an unknown field")
+	}
+}
+
+const builderTest = `package sample
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	joined := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	player, err := NewPlayerBuilder().
+		Name("jane").
+		Age(30).
+		Nickname("jj").
+		Score(9.5).
+		Joined(joined).
+		Tags([]string{"a", "b"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nickname, score := "jj", 9.5
+	expected := &Player{Person: Person{Name: "jane"}, Age: 30, Nickname: &nickname, Score: &score, Joined: &joined, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(player, expected) {
+		t.Errorf("built %+v, expected %+v", player, expected)
+	}
+	if player, err := NewPlayerBuilder().Name("jane").Age(30).Build(); err != nil || player.Nickname != nil || player.Tags != nil {
+		t.Errorf("built %+v: %v", player, err)
+	}
+	for _, b := range []*PlayerBuilder{
+		NewPlayerBuilder().Age(30).Score(1),
+		NewPlayerBuilder().Name("jane").Nickname("jj"),
+		NewPlayerBuilder(),
+	} {
+		if player, err := b.Build(); err == nil {
+			t.Errorf("built %+v without a required field", player)
+		}
+	}
+}
+`
+
+func TestGenerateGoBuilder(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Person").
+		Field("name", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Person", "Player").
+		Field("age", "Int32", false, nil, "").
+		Field("nickname", "String", true, nil, "").
+		Field("score", "Float64", true, nil, "").
+		Field("joined", "Timestamp", true, nil, "").
+		ArrayField("tags", "String", true, "").
+		GenerateBuilder(true).
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateGo(schema, "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"func (b *PlayerBuilder) Nickname(value string) *PlayerBuilder {\n",
+		"func (b *PlayerBuilder) Build() (*Player, error) {\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated Go types are missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "PersonBuilder") {
+		test.Errorf("builder generated for a struct without one:\n%s", src)
+	}
+	runGoTest(test, map[string]string{
+		"model.go":        src,
+		"builder_test.go": builderTest,
+	})
+
+	sb = rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Point").
+		Field("x", "Int32", true, nil, "").
+		GenerateBuilder(true).
+		Build())
+	buf.Reset()
+	if err := GenerateGo(gentest.MustBuild(sb), "sample", &buf); err != nil {
+		test.Fatalf("cannot generate Go types: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"model.go": buf.String(),
+	})
+
+	schema.Types[1].StructTypeDef.Fields[0].Name = "build"
+	if err := GenerateGo(schema, "sample", &buf); err == nil {
+		test.Errorf("expected an error for a field conflicting with the Build method")
+	}
+}
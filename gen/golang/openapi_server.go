@@ -0,0 +1,1745 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// OAPICodegenOptions controls the generated OpenAPI server.
+type OAPICodegenOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type oapiOperation struct {
+	ID         string
+	Method     string
+	Path       string
+	Comment    string
+	PathParams []*oapiParam
+	Params     []*oapiParam
+	Body       string
+	BodyType   rdl.TypeRef
+	Responses  []*oapiResponse
+	Simulation *oapiSimulation
+	Envs       []string
+	CSP        string
+	APIKey     *rdl.APIKeyDef
+	Events     []*oapiEvent
+	Digest     bool
+	Batch      *oapiBatch
+	Trace      bool
+	LoadShed   *rdl.LoadShedDef
+	Roles      []string
+	Lease      *oapiLease
+	Cache      *oapiCache
+	Tenant     string
+}
+
+type oapiParam struct {
+	Name     string
+	Var      string
+	Key      string
+	Query    bool
+	Type     rdl.TypeRef
+	GoType   string
+	BaseType rdl.BaseType
+	Optional bool
+}
+
+type oapiResponse struct {
+	Code   string
+	Type   rdl.TypeRef
+	GoType string
+	Stream bool
+}
+
+type oapiEvent struct {
+	Name   string
+	Method string
+	GoType string
+}
+
+type oapiBatch struct {
+	ItemType  string
+	ErrorType rdl.TypeRef
+	Code      string
+}
+
+type oapiCache struct {
+	TTL     string
+	Backend string
+	Key     string
+}
+
+type oapiLease struct {
+	MaxDuration string
+	Path        string
+	RenewPath   string
+}
+
+type oapiSimulation struct {
+	Latency   string
+	ErrorRate float64
+	Status    string
+	Body      string
+}
+
+// GenerateGoOpenAPIServer generates a net/http server following the
+// conventions of oapi-codegen's std-http-server and strict-server output: a
+// ServerInterface taking decoded parameters, a StrictServerInterface taking
+// request objects and returning response objects, NewStrictHandler adapting
+// the latter to the former, and HandlerWithOptions registering the
+// operations on a ServeMux (Go 1.22 or later). Named RDL types are expected
+// to be declared in the same package, as oapi-codegen's models are.
+//
+// Resources restricted to some deployment environments are only registered
+// when the Env option names one of them.
+//
+// The responses of resources with a Content Security Policy carry it in
+// their Content-Security-Policy header.
+//
+// Resources with server-sent events stream their successful response: an
+// <Op>EventEmitter interface has a method sending each of their events, and
+// ReadServerSentEvents reads the event stream of a response in tests.
+//
+// Content-addressed resources buffer their responses to send the SHA-256
+// digest of their body in their Content-Digest header (RFC 9530).
+//
+// Resources propagating the trace context extract it from the W3C Trace
+// Context headers of their requests with the global OpenTelemetry
+// propagator.
+//
+// Resources shedding load serve a bounded number of requests concurrently:
+// the requests waiting for more than a millisecond, or finding the queue of
+// waiting requests full, are rejected with 503 Service Unavailable.
+//
+// Batch resources, which have a bulk error type, respond with a
+// BatchResult of the items of their type: 207 Multi-Status when some items
+// failed. Their bulk error types must be the same struct, with an Int32 index
+// and a String error field, declared as BulkError.
+//
+// Resources with lease semantics time out with 503 Service Unavailable
+// after the maximum duration of their lease, with http.TimeoutHandler, and
+// LeaseHandlers acquire, release and renew their leases in a LeaseStore. Given
+// the LeaseStore of the options, HandlerWithOptions registers them: POST and
+// DELETE on the path of the resource followed by /lease acquire and release a
+// lease, and PUT on the renew path, that same path by default, renews it.
+//
+// Multi-tenant resources take the tenant of their requests from a path
+// parameter, a header or a claim of the JWT bearer token, rejecting the
+// requests without one with 400 Bad Request, and put it in the request
+// context, where TenantFromContext finds it. The signature of the token is
+// not verified, which is left to the authentication middleware.
+//
+// Cached resources serve the successful responses kept in the CacheStore of
+// the options for their time to live, calling the handler and keeping its
+// response on a miss. The cache key is made of the key inputs, all inputs by
+// default, and of the tenant of multi-tenant resources. Without a CacheStore,
+// responses are kept in memory, except for resources cached in Redis, which
+// need one.
+//
+// Resources restricted to roles reject with 403 Forbidden the requests whose
+// user has none of them, as told by the RoleChecker of the options.
+//
+// When resources have API key authentication, an APIKeyMiddleware strict
+// middleware is generated, rejecting requests without a valid key.
+//
+// When resources have a simulation, a SimulationMiddleware strict middleware
+// is generated as well: with the -simulate flag set, it serves the simulated
+// responses instead of calling the handler.
+func GenerateGoOpenAPIServer(s *rdl.Schema, w io.Writer, opts OAPICodegenOptions) error {
+	if len(s.Resources) == 0 {
+		return fmt.Errorf("schema %s has no resources", s.Name)
+	}
+	registry := rdl.NewTypeRegistry(s)
+	var ops []*oapiOperation
+	for _, r := range s.Resources {
+		op, err := newOAPIOperation(registry, r)
+		if err != nil {
+			return fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+		}
+		ops = append(ops, op)
+	}
+	var bulkError rdl.TypeRef
+	for _, op := range ops {
+		if op.Batch == nil {
+			continue
+		}
+		if bulkError != "" && op.Batch.ErrorType != bulkError {
+			return fmt.Errorf("bulk error types %s and %s differ", bulkError, op.Batch.ErrorType)
+		}
+		bulkError = op.Batch.ErrorType
+	}
+	params := func(op *oapiOperation) []*oapiParam {
+		return append(append([]*oapiParam(nil), op.PathParams...), op.Params...)
+	}
+	uses := func(pred func(p *oapiParam) bool) bool {
+		for _, op := range ops {
+			for _, p := range params(op) {
+				if pred(p) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	funcMap := template.FuncMap{
+		"header":     func() string { return utils.GoGenerationHeader(banner) },
+		"package":    func() string { return packageName(s, opts.Package) },
+		"operations": func() []*oapiOperation { return ops },
+		"bulkError":  func() rdl.TypeRef { return bulkError },
+		"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+		"bind":       oapiBind,
+		"usesStrconv": func() bool {
+			return uses(func(p *oapiParam) bool {
+				return p.BaseType != rdl.BaseTypeString && p.BaseType != rdl.BaseTypeEnum && p.BaseType != rdl.BaseTypeTimestamp
+			})
+		},
+		"apiKeyAuthenticated": func() []*oapiOperation {
+			var authenticated []*oapiOperation
+			for _, op := range ops {
+				if op.APIKey != nil {
+					authenticated = append(authenticated, op)
+				}
+			}
+			return authenticated
+		},
+		"roleChecked": func() []*oapiOperation {
+			var checked []*oapiOperation
+			for _, op := range ops {
+				if len(op.Roles) > 0 {
+					checked = append(checked, op)
+				}
+			}
+			return checked
+		},
+		"multiTenant": func() bool {
+			for _, op := range ops {
+				if op.Tenant != "" {
+					return true
+				}
+			}
+			return false
+		},
+		"jwtTenant": func() bool {
+			for _, op := range ops {
+				if strings.HasPrefix(op.Tenant, "tenantFromJWT(") {
+					return true
+				}
+			}
+			return false
+		},
+		"cached": func() bool {
+			for _, op := range ops {
+				if op.Cache != nil {
+					return true
+				}
+			}
+			return false
+		},
+		"redisCached": func() bool {
+			for _, op := range ops {
+				if op.Cache != nil && op.Cache.Backend == "redis" {
+					return true
+				}
+			}
+			return false
+		},
+		"leased": func() []*oapiOperation {
+			var leased []*oapiOperation
+			for _, op := range ops {
+				if op.Lease != nil {
+					leased = append(leased, op)
+				}
+			}
+			return leased
+		},
+		"handlerFunc": func(op *oapiOperation) string {
+			if op.Lease == nil {
+				return "wrapper." + op.ID
+			}
+			return fmt.Sprintf("http.TimeoutHandler(http.HandlerFunc(wrapper.%s), %s, \"lease expired\").ServeHTTP", op.ID, op.Lease.MaxDuration)
+		},
+		"loadShedding": func() []*oapiOperation {
+			var shedding []*oapiOperation
+			for _, op := range ops {
+				if op.LoadShed != nil {
+					shedding = append(shedding, op)
+				}
+			}
+			return shedding
+		},
+		"traced": func() bool {
+			for _, op := range ops {
+				if op.Trace {
+					return true
+				}
+			}
+			return false
+		},
+		"contentAddressed": func() bool {
+			for _, op := range ops {
+				if op.Digest {
+					return true
+				}
+			}
+			return false
+		},
+		"streaming": func() []*oapiOperation {
+			var streaming []*oapiOperation
+			for _, op := range ops {
+				if len(op.Events) > 0 {
+					streaming = append(streaming, op)
+				}
+			}
+			return streaming
+		},
+		"simulated": func() []*oapiOperation {
+			var simulated []*oapiOperation
+			for _, op := range ops {
+				if op.Simulation != nil {
+					simulated = append(simulated, op)
+				}
+			}
+			return simulated
+		},
+		"usesTime": func() bool {
+			for _, op := range ops {
+				if op.Simulation != nil || op.LoadShed != nil || op.Lease != nil || op.Cache != nil {
+					return true
+				}
+				for _, p := range params(op) {
+					if p.BaseType == rdl.BaseTypeTimestamp {
+						return true
+					}
+				}
+				for _, resp := range op.Responses {
+					if strings.Contains(resp.GoType, "time.") {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		"usesQuery": func(op *oapiOperation) bool {
+			for _, p := range op.Params {
+				if p.Query {
+					return true
+				}
+			}
+			return false
+		},
+		"hasBody": func(resp *oapiResponse) bool { return resp.GoType != "" },
+		"envCondition": func(envs []string) string {
+			var conds []string
+			for _, env := range envs {
+				conds = append(conds, fmt.Sprintf("options.Env == %q", env))
+			}
+			return strings.Join(conds, " || ")
+		},
+		"usesJSON": func() bool {
+			for _, op := range ops {
+				if op.Body != "" || len(op.Events) > 0 || op.Cache != nil {
+					return true
+				}
+				for _, resp := range op.Responses {
+					if resp.GoType != "" {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+	return executeTemplate(w, "openapi_server", goOpenAPIServerTemplate, funcMap, s)
+}
+
+func newOAPIOperation(registry rdl.TypeRegistry, r *rdl.Resource) (*oapiOperation, error) {
+	op := &oapiOperation{
+		ID:      goName(methodName(r)),
+		Method:  strings.ToUpper(r.Method),
+		Path:    resourcePath(r),
+		Comment: r.Comment,
+		Envs:    r.Environments,
+		Digest:  r.ContentAddressed,
+		Trace:   r.TraceContext,
+	}
+	if r.ContentAddressed && len(r.SSEEvents) > 0 {
+		return nil, fmt.Errorf("content-addressed resources cannot stream server-sent events")
+	}
+	if r.CSP != nil {
+		op.CSP = cspHeader(r.CSP)
+	}
+	if ls := r.LoadShed; ls != nil {
+		if ls.MaxConcurrent < 1 || ls.QueueSize < 0 {
+			return nil, fmt.Errorf("load shedding needs a positive concurrency and a queue size of at least 0")
+		}
+		op.LoadShed = ls
+	}
+	if mt := r.MultiTenant; mt != nil {
+		if err := checkMultiTenant(r); err != nil {
+			return nil, err
+		}
+		switch mt.TenantFrom {
+		case "path":
+			op.Tenant = fmt.Sprintf("r.PathValue(%q)", mt.TenantKey)
+		case "header":
+			op.Tenant = fmt.Sprintf("r.Header.Get(%q)", mt.TenantKey)
+		default:
+			op.Tenant = fmt.Sprintf("tenantFromJWT(r, %q)", mt.TenantKey)
+		}
+	}
+	if l := r.Lease; l != nil {
+		if l.MaxDuration <= 0 {
+			return nil, fmt.Errorf("lease without a positive maximum duration")
+		}
+		op.Lease = &oapiLease{MaxDuration: goDuration(l.MaxDuration), Path: op.Path + "/lease", RenewPath: l.RenewPath}
+		switch {
+		case op.Lease.RenewPath == "":
+			op.Lease.RenewPath = op.Lease.Path
+		case !strings.HasPrefix(op.Lease.RenewPath, "/"):
+			return nil, fmt.Errorf("lease renew path %q is not absolute", l.RenewPath)
+		}
+	}
+	for _, role := range r.Roles {
+		if role == "" {
+			return nil, fmt.Errorf("empty role")
+		}
+	}
+	op.Roles = r.Roles
+	if key := r.APIKeyAuth; key != nil {
+		if key.In != "header" && key.In != "query" {
+			return nil, fmt.Errorf("API key in %q, expected \"header\" or \"query\"", key.In)
+		}
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key without a name")
+		}
+		op.APIKey = &rdl.APIKeyDef{In: key.In, Name: key.Name, Scheme: key.Scheme}
+		if op.APIKey.Scheme == "" {
+			op.APIKey.Scheme = key.Name
+		}
+	}
+	keys := make(map[rdl.Identifier]string)
+	var allKeys []string
+	for _, in := range r.Inputs {
+		if in.Context != "" {
+			continue
+		}
+		goType, err := oapiGoType(registry, in.Type)
+		if err != nil {
+			return nil, err
+		}
+		if in == bodyInput(r) {
+			op.Body = goType
+			op.BodyType = in.Type
+			continue
+		}
+		p := &oapiParam{
+			Name:     goName(string(in.Name)),
+			Var:      string(in.Name),
+			Type:     in.Type,
+			GoType:   goType,
+			BaseType: registry.FindBaseType(in.Type),
+			Optional: in.Optional && !in.PathParam,
+		}
+		if token.IsKeyword(p.Var) {
+			p.Var += "Param"
+		}
+		switch p.BaseType {
+		case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID, rdl.BaseTypeEnum, rdl.BaseTypeBool,
+			rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64,
+			rdl.BaseTypeFloat32, rdl.BaseTypeFloat64, rdl.BaseTypeTimestamp:
+		default:
+			return nil, fmt.Errorf("input %s of type %s cannot be a parameter", in.Name, in.Type)
+		}
+		if p.BaseType == rdl.BaseTypeSymbol || p.BaseType == rdl.BaseTypeUUID {
+			p.BaseType = rdl.BaseTypeString
+		}
+		switch {
+		case in.PathParam:
+			p.Key = string(in.Name)
+			op.PathParams = append(op.PathParams, p)
+			keys[in.Name] = p.Var
+		case in.QueryParam != "":
+			p.Key = in.QueryParam
+			p.Query = true
+			op.Params = append(op.Params, p)
+		default:
+			p.Key = in.Header
+			op.Params = append(op.Params, p)
+		}
+		if !in.PathParam {
+			keys[in.Name] = "params." + p.Name
+			if p.Optional {
+				keys[in.Name] = "optionalCacheKey(params." + p.Name + ")"
+			}
+		}
+		allKeys = append(allKeys, keys[in.Name])
+	}
+	if c := r.Cache; c != nil {
+		cache, err := newOAPICache(r, keys, allKeys)
+		if err != nil {
+			return nil, err
+		}
+		op.Cache = cache
+	}
+	methods := make(map[string]bool)
+	for _, ev := range r.SSEEvents {
+		method := sseMethodName(ev.EventName)
+		if !token.IsIdentifier(method) || token.IsKeyword(method) {
+			return nil, fmt.Errorf("server-sent event %q has no valid method name", ev.EventName)
+		}
+		if methods[method] {
+			return nil, fmt.Errorf("duplicate server-sent event %q", ev.EventName)
+		}
+		methods[method] = true
+		goType, err := oapiGoType(registry, ev.PayloadType)
+		if err != nil {
+			return nil, err
+		}
+		op.Events = append(op.Events, &oapiEvent{Name: ev.EventName, Method: method, GoType: goType})
+	}
+	goType, err := oapiGoType(registry, r.Type)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	addResponse := func(code string, ref rdl.TypeRef, goType string) {
+		if seen[code] {
+			return
+		}
+		seen[code] = true
+		if code == "204" || code == "304" {
+			goType = ""
+		}
+		op.Responses = append(op.Responses, &oapiResponse{Code: code, Type: ref, GoType: goType})
+	}
+	addResponse(rdl.StatusCode(r.Expected), r.Type, goType)
+	if len(op.Events) > 0 {
+		op.Responses[0].GoType = ""
+		op.Responses[0].Stream = true
+	}
+	for _, alt := range r.Alternatives {
+		addResponse(rdl.StatusCode(alt), r.Type, goType)
+	}
+	var codes []string
+	for sym := range r.Exceptions {
+		codes = append(codes, sym)
+	}
+	sort.Slice(codes, func(i, j int) bool { return rdl.StatusCode(codes[i]) < rdl.StatusCode(codes[j]) })
+	for _, sym := range codes {
+		exType, err := oapiGoType(registry, rdl.TypeRef(r.Exceptions[sym].Type))
+		if err != nil {
+			return nil, err
+		}
+		addResponse(rdl.StatusCode(sym), rdl.TypeRef(r.Exceptions[sym].Type), exType)
+	}
+	if r.BulkErrorType != "" {
+		if op.Responses[0].GoType == "" {
+			return nil, fmt.Errorf("batch resources must respond with a body")
+		}
+		batch, err := newOAPIBatch(registry, r)
+		if err != nil {
+			return nil, err
+		}
+		batch.Code = op.Responses[0].Code
+		op.Batch = batch
+	}
+	if sim := r.Simulate; sim != nil {
+		if sim.ErrorRate < 0 || sim.ErrorRate > 1 {
+			return nil, fmt.Errorf("simulated error rate %v is not between 0 and 1", sim.ErrorRate)
+		}
+		op.Simulation = &oapiSimulation{
+			Latency:   goDuration(sim.Latency),
+			ErrorRate: sim.ErrorRate,
+			Status:    op.Responses[0].Code,
+		}
+		if sim.Response != nil && op.Responses[0].GoType != "" {
+			body, err := json.Marshal(sim.Response)
+			if err != nil {
+				return nil, fmt.Errorf("bad simulated response: %v", err)
+			}
+			op.Simulation.Body = string(body)
+		}
+	}
+	return op, nil
+}
+
+// newOAPICache checks the caching of a resource and returns the expression
+// of its cache key, made of the expressions of its key inputs, after the
+// tenant of multi-tenant resources so that tenants never share responses.
+func newOAPICache(r *rdl.Resource, keys map[rdl.Identifier]string, allKeys []string) (*oapiCache, error) {
+	c := r.Cache
+	if strings.ToUpper(r.Method) != "GET" {
+		return nil, fmt.Errorf("only GET resources can be cached")
+	}
+	if len(r.SSEEvents) > 0 {
+		return nil, fmt.Errorf("resources streaming server-sent events cannot be cached")
+	}
+	if c.TTL <= 0 {
+		return nil, fmt.Errorf("cache without a positive time to live")
+	}
+	cache := &oapiCache{TTL: goDuration(c.TTL), Backend: c.Backend}
+	switch cache.Backend {
+	case "":
+		cache.Backend = "inmem"
+	case "inmem", "redis":
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q, expected \"inmem\" or \"redis\"", c.Backend)
+	}
+	args := allKeys
+	if len(c.KeyFrom) > 0 {
+		args = nil
+		for _, name := range c.KeyFrom {
+			key, ok := keys[name]
+			if !ok {
+				return nil, fmt.Errorf("cache key input %s is not a parameter", name)
+			}
+			args = append(args, key)
+		}
+	}
+	if r.MultiTenant != nil {
+		args = append([]string{"tenant"}, args...)
+	}
+	cache.Key = fmt.Sprintf("cacheKey(%q", goName(methodName(r)))
+	for _, arg := range args {
+		cache.Key += ", " + arg
+	}
+	cache.Key += ")"
+	return cache, nil
+}
+
+// goDuration returns the Go expression of a duration, in milliseconds when it
+// is a whole number of them.
+func goDuration(d time.Duration) string {
+	if d%time.Millisecond == 0 {
+		return fmt.Sprintf("%d*time.Millisecond", d/time.Millisecond)
+	}
+	return fmt.Sprintf("%d*time.Nanosecond", d)
+}
+
+// newOAPIBatch returns the batch results of a resource with a bulk error
+// type. The items of a batch are the items of the resource type when it is an
+// array, the resource type itself otherwise.
+func newOAPIBatch(registry rdl.TypeRegistry, r *rdl.Resource) (*oapiBatch, error) {
+	t := registry.FindType(r.BulkErrorType)
+	if t == nil || t.StructTypeDef == nil {
+		return nil, fmt.Errorf("bulk error type %s is not a struct", r.BulkErrorType)
+	}
+	fields := make(map[string]rdl.TypeRef)
+	for _, f := range t.StructTypeDef.Fields {
+		fields[string(f.Name)] = f.Type
+	}
+	if fields["index"] != "Int32" || fields["error"] != "String" {
+		return nil, fmt.Errorf("bulk error type %s has no Int32 index and String error fields", r.BulkErrorType)
+	}
+	items := r.Type
+	if t := registry.FindType(r.Type); t != nil && t.ArrayTypeDef != nil && t.ArrayTypeDef.Items != "" {
+		items = t.ArrayTypeDef.Items
+	}
+	itemType, err := oapiGoType(registry, items)
+	if err != nil {
+		return nil, err
+	}
+	return &oapiBatch{ItemType: itemType, ErrorType: r.BulkErrorType}, nil
+}
+
+// sseMethodName returns the name of the emitter method sending an event:
+// "user-created" is sent by UserCreated.
+func sseMethodName(eventName string) string {
+	parts := strings.FieldsFunc(eventName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, part := range parts {
+		parts[i] = utils.Capitalize(part)
+	}
+	return strings.Join(parts, "")
+}
+
+// oapiGoType returns the Go type for a reference to an RDL type. Structs,
+// enums, unions, arrays and maps declared in the schema keep their name, as
+// oapi-codegen declares a model for every component schema; other types are
+// mapped to the Go type oapi-codegen uses for their OpenAPI type.
+func oapiGoType(registry rdl.TypeRegistry, ref rdl.TypeRef) (string, error) {
+	t := registry.FindType(ref)
+	if t == nil {
+		return "", fmt.Errorf("unknown type: %s", ref)
+	}
+	tName, _, _ := rdl.TypeInfo(t)
+	bt := registry.BaseType(t)
+	switch bt {
+	case rdl.BaseTypeStruct, rdl.BaseTypeEnum, rdl.BaseTypeUnion, rdl.BaseTypeArray, rdl.BaseTypeMap:
+		if !registry.IsBaseTypeName(rdl.TypeRef(tName)) && string(tName) != "Struct" {
+			return string(tName), nil
+		}
+	}
+	switch bt {
+	case rdl.BaseTypeBool:
+		return "bool", nil
+	case rdl.BaseTypeInt8:
+		return "int8", nil
+	case rdl.BaseTypeInt16:
+		return "int16", nil
+	case rdl.BaseTypeInt32:
+		return "int32", nil
+	case rdl.BaseTypeInt64:
+		return "int64", nil
+	case rdl.BaseTypeFloat32:
+		return "float32", nil
+	case rdl.BaseTypeFloat64:
+		return "float64", nil
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeUUID:
+		return "string", nil
+	case rdl.BaseTypeTimestamp:
+		return "time.Time", nil
+	case rdl.BaseTypeBytes:
+		return "[]byte", nil
+	case rdl.BaseTypeArray:
+		return "[]interface{}", nil
+	case rdl.BaseTypeMap, rdl.BaseTypeStruct:
+		return "map[string]interface{}", nil
+	default:
+		return "interface{}", nil
+	}
+}
+
+// oapiBind returns the statements converting the parameter's string value s
+// and assigning it to dst, reporting malformed values to the wrapper's error
+// handler.
+func oapiBind(p *oapiParam, dst string) string {
+	var parse, value string
+	switch p.BaseType {
+	case rdl.BaseTypeString:
+		value = "s"
+	case rdl.BaseTypeEnum:
+		value = p.GoType + "(s)"
+	case rdl.BaseTypeBool:
+		parse = "strconv.ParseBool(s)"
+		value = "v"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		parse = fmt.Sprintf("strconv.ParseInt(s, 10, %s)", strings.TrimPrefix(p.GoType, "int"))
+		value = p.GoType + "(v)"
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		parse = fmt.Sprintf("strconv.ParseFloat(s, %s)", strings.TrimPrefix(p.GoType, "float"))
+		value = p.GoType + "(v)"
+	case rdl.BaseTypeTimestamp:
+		parse = "time.Parse(time.RFC3339, s)"
+		value = "v"
+	}
+	var code string
+	if parse != "" {
+		code = fmt.Sprintf("v, err := %s\nif err != nil {\nsiw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: %q, Err: err})\nreturn\n}\n", parse, p.Key)
+	}
+	if p.Optional {
+		return code + fmt.Sprintf("value := %s\n%s = &value", value, dst)
+	}
+	return code + fmt.Sprintf("%s = %s", dst, value)
+}
+
+// cspKeywords are the CSP source expressions written in single quotes.
+var cspKeywords = map[string]bool{
+	"self": true, "none": true, "unsafe-inline": true, "unsafe-eval": true, "unsafe-hashes": true,
+	"strict-dynamic": true, "report-sample": true, "wasm-unsafe-eval": true,
+}
+
+// cspHeader returns the serialized policy, as the value of a
+// Content-Security-Policy header: its directives separated by semicolons,
+// each followed by its sources. Keywords, nonces and hashes are quoted when
+// they are not already.
+func cspHeader(policy *rdl.CSPDef) string {
+	var directives []string
+	add := func(name string, sources []string) {
+		if len(sources) == 0 {
+			return
+		}
+		directive := name
+		for _, src := range sources {
+			if cspKeywords[src] || strings.HasPrefix(src, "nonce-") || strings.HasPrefix(src, "sha256-") ||
+				strings.HasPrefix(src, "sha384-") || strings.HasPrefix(src, "sha512-") {
+				src = "'" + src + "'"
+			}
+			directive += " " + src
+		}
+		directives = append(directives, directive)
+	}
+	add("default-src", policy.DefaultSrc)
+	add("script-src", policy.ScriptSrc)
+	add("style-src", policy.StyleSrc)
+	add("img-src", policy.ImgSrc)
+	add("connect-src", policy.ConnectSrc)
+	add("font-src", policy.FontSrc)
+	add("object-src", policy.ObjectSrc)
+	add("media-src", policy.MediaSrc)
+	add("frame-src", policy.FrameSrc)
+	add("frame-ancestors", policy.FrameAncestors)
+	add("form-action", policy.FormAction)
+	add("base-uri", policy.BaseURI)
+	if policy.UpgradeInsecureRequests {
+		directives = append(directives, "upgrade-insecure-requests")
+	}
+	return strings.Join(directives, "; ")
+}
+
+const goOpenAPIServerTemplate = `{{header}}
+
+package {{package}}
+
+import (
+{{- if streaming}}
+	"bufio"
+{{- end}}
+{{- if or contentAddressed cached}}
+	"bytes"
+{{- end}}
+	"context"
+{{- if contentAddressed}}
+	"crypto/sha256"
+{{- end}}
+{{- if or contentAddressed jwtTenant}}
+	"encoding/base64"
+{{- end}}
+{{- if or usesJSON jwtTenant}}
+	"encoding/json"
+{{- end}}
+{{- if leased}}
+	"errors"
+{{- end}}
+{{- if simulated}}
+	"flag"
+{{- end}}
+	"fmt"
+{{- if or simulated streaming}}
+	"io"
+{{- end}}
+{{- if simulated}}
+	"math/rand"
+{{- end}}
+	"net/http"
+{{- if usesStrconv}}
+	"strconv"
+{{- end}}
+{{- if or streaming jwtTenant}}
+	"strings"
+{{- end}}
+{{- if cached}}
+	"sync"
+{{- end}}
+{{- if usesTime}}
+	"time"
+{{- end}}
+{{- if traced}}
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+{{- end}}
+)
+{{range operations}}
+{{- if .Params}}
+// {{.ID}}Params defines parameters for {{.ID}}.
+type {{.ID}}Params struct {
+{{- range .Params}}
+	{{.Name}} {{if .Optional}}*{{end}}{{.GoType}} ` + "`" + `{{if .Query}}form:"{{.Key}}{{if .Optional}},omitempty{{end}}" {{end}}json:"{{.Key}}{{if .Optional}},omitempty{{end}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- if .Body}}
+// {{.ID}}JSONRequestBody defines body for {{.ID}} for application/json ContentType.
+type {{.ID}}JSONRequestBody = {{.Body}}
+{{end}}
+{{- end}}
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+{{- range operations}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	// ({{.Method}} {{.Path}})
+	{{.ID}}(w http.ResponseWriter, r *http.Request{{range .PathParams}}, {{.Var}} {{.GoType}}{{end}}{{if .Params}}, params {{.ID}}Params{{end}})
+{{- end}}
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+{{- if roleChecked}}
+	RoleChecker        RoleChecker
+{{- end}}
+{{- if cached}}
+	CacheStore         CacheStore
+{{- end}}
+}
+
+type MiddlewareFunc func(http.Handler) http.Handler
+{{range operations}}
+// {{.ID}} operation middleware
+func (siw *ServerInterfaceWrapper) {{.ID}}(w http.ResponseWriter, r *http.Request) {
+{{- if .Roles}}
+	if siw.RoleChecker == nil || !siw.RoleChecker.HasRole(r, operationRoles[{{quote .ID}}]) {
+		http.Error(w, "insufficient role", http.StatusForbidden)
+		return
+	}
+{{- end}}
+{{- if .LoadShed}}
+	shedder := loadShedders[{{quote .ID}}]
+	if !shedder.acquire() {
+		http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+		return
+	}
+	defer shedder.release()
+{{- end}}
+{{- if .CSP}}
+	w.Header().Set("Content-Security-Policy", {{quote .CSP}})
+{{- end}}
+{{- if .Digest}}
+	dw := &contentDigestWriter{ResponseWriter: w}
+	defer dw.flush()
+	w = dw
+{{- end}}
+{{- if .Trace}}
+	r = r.WithContext(otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header)))
+{{- end}}
+{{- if .Tenant}}
+	tenant := {{.Tenant}}
+	if tenant == "" {
+		http.Error(w, "missing tenant", http.StatusBadRequest)
+		return
+	}
+	r = r.WithContext(WithTenant(r.Context(), tenant))
+{{- end}}
+{{- range .PathParams}}
+
+	// ------------- Path parameter {{quote .Key}} -------------
+	var {{.Var}} {{.GoType}}
+	{
+		s := r.PathValue({{quote .Key}})
+		{{bind . .Var}}
+	}
+{{- end}}
+{{- if .Params}}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params {{.ID}}Params
+{{- if usesQuery .}}
+	query := r.URL.Query()
+{{- end}}
+{{- range .Params}}
+
+	// ------------- {{if .Optional}}Optional{{else}}Required{{end}} {{if .Query}}query{{else}}header{{end}} parameter {{quote .Key}} -------------
+	if s := {{if .Query}}query.Get{{else}}r.Header.Get{{end}}({{quote .Key}}); s != "" {
+		{{bind . (print "params." .Name)}}
+	}{{if not .Optional}} else {
+		siw.ErrorHandlerFunc(w, r, &{{if .Query}}RequiredParamError{{else}}RequiredHeaderError{{end}}{ParamName: {{quote .Key}}})
+		return
+	}{{end}}
+{{- end}}
+{{- end}}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+{{- if .Cache}}
+		serveCached(w, r, siw.CacheStore, {{.Cache.Key}}, {{.Cache.TTL}}, func(w http.ResponseWriter) {
+			siw.Handler.{{.ID}}(w, r{{range .PathParams}}, {{.Var}}{{end}}{{if .Params}}, params{{end}})
+		})
+{{- else}}
+		siw.Handler.{{.ID}}(w, r{{range .PathParams}}, {{.Var}}{{end}}{{if .Params}}, params{{end}})
+{{- end}}
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+{{end}}
+{{- if multiTenant}}
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of a request to a multi-tenant
+// operation.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok
+}
+{{- if jwtTenant}}
+
+// tenantFromJWT returns the claim of the JWT bearer token of the request,
+// or an empty string if there is none.
+func tenantFromJWT(r *http.Request, claim string) string {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	tenant, _ := claims[claim].(string)
+	return tenant
+}
+{{- end}}
+{{end}}
+{{- if cached}}
+// CacheStore keeps the responses of cached operations, which expire after
+// their time to live.
+type CacheStore interface {
+	// Get returns the value of the key, if any and not expired.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set keeps the value of the key for the time to live.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// NewMemoryCacheStore returns a CacheStore keeping the values in memory.
+func NewMemoryCacheStore() CacheStore {
+	return &memoryCacheStore{entries: make(map[string]memoryCacheEntry)}
+}
+
+type memoryCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (s *memoryCacheStore) Get(ctx context.Context, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (s *memoryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+// cacheKey returns the cache key of a request to the operation with the
+// values of its key parameters.
+func cacheKey(operationID string, values ...interface{}) string {
+	key := operationID
+	for _, v := range values {
+		key += "\x00" + fmt.Sprint(v)
+	}
+	return key
+}
+
+// optionalCacheKey returns the value of an optional parameter, or an empty
+// string if it is absent.
+func optionalCacheKey[T any](v *T) interface{} {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+// cachedResponse is a response kept in a CacheStore.
+type cachedResponse struct {
+	Status int         ` + "`" + `json:"status"` + "`" + `
+	Header http.Header ` + "`" + `json:"header"` + "`" + `
+	Body   []byte      ` + "`" + `json:"body"` + "`" + `
+}
+
+// serveCached writes the response cached under the key, or the response of
+// serve, which is cached when successful.
+func serveCached(w http.ResponseWriter, r *http.Request, store CacheStore, key string, ttl time.Duration, serve func(w http.ResponseWriter)) {
+	if data, ok := store.Get(r.Context(), key); ok {
+		var cached cachedResponse
+		if err := json.Unmarshal(data, &cached); err == nil {
+			for k, v := range cached.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(cached.Status)
+			w.Write(cached.Body)
+			return
+		}
+	}
+	cw := &cacheWriter{ResponseWriter: w, status: http.StatusOK}
+	serve(cw)
+	if cw.status < 200 || cw.status > 299 {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{Status: cw.status, Header: w.Header().Clone(), Body: cw.body.Bytes()})
+	if err == nil {
+		store.Set(r.Context(), key, data, ttl)
+	}
+}
+
+// cacheWriter records the response it writes, to cache it.
+type cacheWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	cw.status = status
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+{{end}}
+{{- with leased}}
+// ErrLeaseHeld is returned by LeaseStore.Acquire when another holder has the
+// lease.
+var ErrLeaseHeld = errors.New("lease held by another holder")
+
+// ErrLeaseNotHeld is returned by LeaseStore.Release and LeaseStore.Renew when
+// the token is not the one of a current lease.
+var ErrLeaseNotHeld = errors.New("lease not held")
+
+// LeaseStore keeps the leases of the resources with lease semantics, which
+// expire after their duration unless renewed.
+type LeaseStore interface {
+	// Acquire grants the lease of the resource for the duration, returning
+	// the token of its holder.
+	Acquire(ctx context.Context, resource string, duration time.Duration) (string, error)
+	// Release ends the lease of the token.
+	Release(ctx context.Context, token string) error
+	// Renew extends the lease of the token by the duration.
+	Renew(ctx context.Context, token string, duration time.Duration) error
+}
+
+// LeaseHandlers serve the leases of the resources with lease semantics: the
+// lease of a resource is named by the path of its request, and the token of
+// the holder is sent in the Lease-Token header.
+type LeaseHandlers struct {
+	Store LeaseStore
+}
+{{range .}}
+// Acquire{{.ID}} acquires the lease of a {{.ID}} resource, responding with
+// 201 Created and its token, or 409 Conflict when another holder has it.
+func (lh LeaseHandlers) Acquire{{.ID}}(w http.ResponseWriter, r *http.Request) {
+	token, err := lh.Store.Acquire(r.Context(), r.URL.Path, {{.Lease.MaxDuration}})
+	if err != nil {
+		leaseError(w, err)
+		return
+	}
+	w.Header().Set("Lease-Token", token)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Release{{.ID}} releases the lease of a {{.ID}} resource.
+func (lh LeaseHandlers) Release{{.ID}}(w http.ResponseWriter, r *http.Request) {
+	if err := lh.Store.Release(r.Context(), r.Header.Get("Lease-Token")); err != nil {
+		leaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Renew{{.ID}} renews the lease of a {{.ID}} resource, served at {{.Lease.RenewPath}}.
+func (lh LeaseHandlers) Renew{{.ID}}(w http.ResponseWriter, r *http.Request) {
+	if err := lh.Store.Renew(r.Context(), r.Header.Get("Lease-Token"), {{.Lease.MaxDuration}}); err != nil {
+		leaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+{{end}}
+func leaseError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrLeaseHeld) || errors.Is(err, ErrLeaseNotHeld) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+{{end}}
+{{- with roleChecked}}
+// RoleChecker tells if the authenticated user of a request has one of the
+// roles granting access to an operation.
+type RoleChecker interface {
+	HasRole(r *http.Request, roles []string) bool
+}
+
+var operationRoles = map[string][]string{
+{{- range .}}
+	{{quote .ID}}: { {{- range $i, $role := .Roles}}{{if $i}}, {{end}}{{quote $role}}{{end -}} },
+{{- end}}
+}
+{{end}}
+{{- with loadShedding}}
+// loadShedder bounds the number of concurrent requests of an operation.
+type loadShedder struct {
+	running chan struct{}
+	waiting chan struct{}
+}
+
+var loadShedders = map[string]*loadShedder{
+{{- range .}}
+	{{quote .ID}}: {running: make(chan struct{}, {{.LoadShed.MaxConcurrent}}), waiting: make(chan struct{}, {{.LoadShed.QueueSize}})},
+{{- end}}
+}
+
+// acquire reports whether a request may be served, waiting for a
+// millisecond at most in the queue when all the slots are taken. Served
+// requests must then release their slot.
+func (s *loadShedder) acquire() bool {
+	select {
+	case s.running <- struct{}{}:
+		return true
+	default:
+	}
+	select {
+	case s.waiting <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-s.waiting }()
+	timer := time.NewTimer(time.Millisecond)
+	defer timer.Stop()
+	select {
+	case s.running <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (s *loadShedder) release() {
+	<-s.running
+}
+{{end}}
+{{- if contentAddressed}}
+// contentDigestWriter buffers a response to send it with the SHA-256 digest
+// of its body in its Content-Digest header.
+type contentDigestWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (dw *contentDigestWriter) WriteHeader(status int) {
+	if dw.status == 0 {
+		dw.status = status
+	}
+}
+
+func (dw *contentDigestWriter) Write(b []byte) (int, error) {
+	return dw.body.Write(b)
+}
+
+func (dw *contentDigestWriter) flush() {
+	sum := sha256.Sum256(dw.body.Bytes())
+	dw.ResponseWriter.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	if dw.status == 0 {
+		dw.status = http.StatusOK
+	}
+	dw.ResponseWriter.WriteHeader(dw.status)
+	dw.ResponseWriter.Write(dw.body.Bytes())
+}
+{{end}}
+type RequiredParamError struct {
+	ParamName string
+}
+
+func (e *RequiredParamError) Error() string {
+	return fmt.Sprintf("Query argument %s is required, but not found", e.ParamName)
+}
+
+type RequiredHeaderError struct {
+	ParamName string
+}
+
+func (e *RequiredHeaderError) Error() string {
+	return fmt.Sprintf("Header parameter %s is required, but not found", e.ParamName)
+}
+
+type InvalidParamFormatError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *InvalidParamFormatError) Error() string {
+	return fmt.Sprintf("Invalid format for parameter %s: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *InvalidParamFormatError) Unwrap() error {
+	return e.Err
+}
+
+// ServeMux is an abstraction of http.ServeMux.
+type ServeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+}
+
+type StdHTTPServerOptions struct {
+	BaseURL          string
+	BaseRouter       ServeMux
+	Middlewares      []MiddlewareFunc
+	ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+	// Env is the deployment environment, deciding which operations restricted
+	// to some environments are registered.
+	Env string
+{{- if roleChecked}}
+	// RoleChecker checks the roles of the users of operations restricted to
+	// roles, whose requests are all forbidden without it.
+	RoleChecker RoleChecker
+{{- end}}
+{{- if cached}}
+	// CacheStore keeps the responses of cached operations{{if not redisCached}}, in memory if nil{{end}}.
+	CacheStore CacheStore
+{{- end}}
+{{- if leased}}
+	// LeaseStore keeps the leases of the operations with lease semantics,
+	// whose acquire, release and renew routes are registered only with one.
+	LeaseStore LeaseStore
+{{- end}}
+}
+
+// Handler creates http.Handler with routing matching OpenAPI spec.
+func Handler(si ServerInterface) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{})
+}
+
+// HandlerFromMux creates http.Handler with routing matching OpenAPI spec based on the provided mux.
+func HandlerFromMux(si ServerInterface, m ServeMux) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseRouter: m,
+	})
+}
+
+func HandlerFromMuxWithBaseURL(si ServerInterface, m ServeMux, baseURL string) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseURL:    baseURL,
+		BaseRouter: m,
+	})
+}
+
+// HandlerWithOptions creates http.Handler with additional options
+func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.Handler {
+	m := options.BaseRouter
+
+	if m == nil {
+		m = http.NewServeMux()
+	}
+	if options.ErrorHandlerFunc == nil {
+		options.ErrorHandlerFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+{{- if cached}}
+	if options.CacheStore == nil {
+{{- if redisCached}}
+		panic("operations cached in Redis need a CacheStore")
+{{- else}}
+		options.CacheStore = NewMemoryCacheStore()
+{{- end}}
+	}
+{{- end}}
+
+	wrapper := ServerInterfaceWrapper{
+		Handler:            si,
+		HandlerMiddlewares: options.Middlewares,
+		ErrorHandlerFunc:   options.ErrorHandlerFunc,
+{{- if roleChecked}}
+		RoleChecker:        options.RoleChecker,
+{{- end}}
+{{- if cached}}
+		CacheStore:         options.CacheStore,
+{{- end}}
+	}
+{{range operations}}
+{{- if .Envs}}
+	if {{envCondition .Envs}} {
+		m.HandleFunc({{quote (print .Method " ")}}+options.BaseURL+{{quote .Path}}, {{handlerFunc .}})
+	}
+{{- else}}
+	m.HandleFunc({{quote (print .Method " ")}}+options.BaseURL+{{quote .Path}}, {{handlerFunc .}})
+{{- end}}
+{{- end}}
+{{- with leased}}
+	if options.LeaseStore != nil {
+		lh := LeaseHandlers{Store: options.LeaseStore}
+{{- range .}}
+{{- if .Envs}}
+		if {{envCondition .Envs}} {
+			m.HandleFunc("POST "+options.BaseURL+{{quote .Lease.Path}}, lh.Acquire{{.ID}})
+			m.HandleFunc("DELETE "+options.BaseURL+{{quote .Lease.Path}}, lh.Release{{.ID}})
+			m.HandleFunc("PUT "+options.BaseURL+{{quote .Lease.RenewPath}}, lh.Renew{{.ID}})
+		}
+{{- else}}
+		m.HandleFunc("POST "+options.BaseURL+{{quote .Lease.Path}}, lh.Acquire{{.ID}})
+		m.HandleFunc("DELETE "+options.BaseURL+{{quote .Lease.Path}}, lh.Release{{.ID}})
+		m.HandleFunc("PUT "+options.BaseURL+{{quote .Lease.RenewPath}}, lh.Renew{{.ID}})
+{{- end}}
+{{- end}}
+	}
+{{- end}}
+
+	return m
+}
+{{range $op := operations}}
+type {{.ID}}RequestObject struct {
+{{- range .PathParams}}
+	{{.Name}} {{.GoType}} ` + "`" + `json:"{{.Key}}"` + "`" + `
+{{- end}}
+{{- if .Params}}
+	Params {{.ID}}Params
+{{- end}}
+{{- if .Body}}
+	Body *{{.ID}}JSONRequestBody
+{{- end}}
+}
+
+type {{.ID}}ResponseObject interface {
+	Visit{{.ID}}Response(w http.ResponseWriter) error
+}
+{{- with .Batch}}
+
+// {{$op.ID}}BatchResponse is the result of a batch {{$op.ID}}: 207 Multi-Status
+// when some items failed, {{.Code}} otherwise.
+type {{$op.ID}}BatchResponse BatchResult[{{.ItemType}}]
+
+func (response {{$op.ID}}BatchResponse) Visit{{$op.ID}}Response(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	if len(response.Errors) > 0 {
+		w.WriteHeader(http.StatusMultiStatus)
+	} else {
+		w.WriteHeader({{.Code}})
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+{{- end}}
+{{range .Responses}}
+{{- if .Stream}}
+// {{$op.ID}}{{.Code}}EventStreamResponse streams the events the function sends.
+type {{$op.ID}}{{.Code}}EventStreamResponse func(events {{$op.ID}}EventEmitter) error
+
+func (response {{$op.ID}}{{.Code}}EventStreamResponse) Visit{{$op.ID}}Response(w http.ResponseWriter) error {
+	return response(New{{$op.ID}}EventEmitter(w))
+}
+{{- else if hasBody .}}
+type {{$op.ID}}{{.Code}}JSONResponse {{.GoType}}
+
+func (response {{$op.ID}}{{.Code}}JSONResponse) Visit{{$op.ID}}Response(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader({{.Code}})
+
+	return json.NewEncoder(w).Encode(response)
+}
+{{- else}}
+type {{$op.ID}}{{.Code}}Response struct {
+}
+
+func (response {{$op.ID}}{{.Code}}Response) Visit{{$op.ID}}Response(w http.ResponseWriter) error {
+	w.WriteHeader({{.Code}})
+	return nil
+}
+{{- end}}
+{{end}}
+{{- end}}
+// StrictServerInterface represents all server handlers.
+type StrictServerInterface interface {
+{{- range operations}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	// ({{.Method}} {{.Path}})
+	{{.ID}}(ctx context.Context, request {{.ID}}RequestObject) ({{.ID}}ResponseObject, error)
+{{- end}}
+}
+
+type StrictHandlerFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (response interface{}, err error)
+
+type StrictMiddlewareFunc func(f StrictHandlerFunc, operationID string) StrictHandlerFunc
+
+type StrictHTTPServerOptions struct {
+	RequestErrorHandlerFunc  func(w http.ResponseWriter, r *http.Request, err error)
+	ResponseErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func NewStrictHandler(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		},
+		ResponseErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		},
+	}}
+}
+
+func NewStrictHandlerWithOptions(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc, options StrictHTTPServerOptions) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: options}
+}
+
+type strictHandler struct {
+	ssi         StrictServerInterface
+	middlewares []StrictMiddlewareFunc
+	options     StrictHTTPServerOptions
+}
+{{range operations}}
+// {{.ID}} operation middleware
+func (sh *strictHandler) {{.ID}}(w http.ResponseWriter, r *http.Request{{range .PathParams}}, {{.Var}} {{.GoType}}{{end}}{{if .Params}}, params {{.ID}}Params{{end}}) {
+	var request {{.ID}}RequestObject
+{{range .PathParams}}
+	request.{{.Name}} = {{.Var}}
+{{- end}}
+{{- if .Params}}
+	request.Params = params
+{{- end}}
+{{- if .Body}}
+
+	var body {{.ID}}JSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+{{- end}}
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.{{.ID}}(ctx, request.({{.ID}}RequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, {{quote .ID}})
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.({{.ID}}ResponseObject); ok {
+		if err := validResponse.Visit{{.ID}}Response(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+{{end}}
+{{- with bulkError}}
+// BulkError is the failure of an item of a batch.
+type BulkError = {{.}}
+
+// BatchResult is the result of a batch whose items may partially fail.
+type BatchResult[T any] struct {
+	Successes []T         ` + "`" + `json:"successes"` + "`" + `
+	Errors    []BulkError ` + "`" + `json:"errors"` + "`" + `
+}
+{{end}}
+{{- with apiKeyAuthenticated}}
+type apiKeyAuth struct {
+	in     string
+	name   string
+	scheme string
+}
+
+var apiKeyAuths = map[string]apiKeyAuth{
+{{- range .}}
+	{{quote .ID}}: {in: {{quote .APIKey.In}}, name: {{quote .APIKey.Name}}, scheme: {{quote .APIKey.Scheme}}},
+{{- end}}
+}
+
+// APIKeyValidator tells if a key is a valid API key of the security scheme.
+type APIKeyValidator func(ctx context.Context, scheme, key string) bool
+
+// APIKeyMiddleware rejects with 401 Unauthorized the requests to operations
+// with API key authentication whose key, read from the header or query
+// parameter the operation declares, is missing or refused by the validator.
+func APIKeyMiddleware(validate APIKeyValidator) StrictMiddlewareFunc {
+	return func(f StrictHandlerFunc, operationID string) StrictHandlerFunc {
+		auth, ok := apiKeyAuths[operationID]
+		if !ok {
+			return f
+		}
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+			var key string
+			if auth.in == "query" {
+				key = r.URL.Query().Get(auth.name)
+			} else {
+				key = r.Header.Get(auth.name)
+			}
+			if key == "" || !validate(ctx, auth.scheme, key) {
+				http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+				return nil, nil
+			}
+			return f(ctx, w, r, request)
+		}
+	}
+}
+{{end}}
+{{- with streaming}}
+{{- range .}}
+// {{.ID}}EventEmitter sends the server-sent events of {{.ID}}.
+type {{.ID}}EventEmitter interface {
+{{- range .Events}}
+	// {{.Method}} sends a {{quote .Name}} event.
+	{{.Method}}(data {{.GoType}}) error
+{{- end}}
+}
+
+// New{{.ID}}EventEmitter starts the event stream of {{.ID}} on w.
+func New{{.ID}}EventEmitter(w http.ResponseWriter) {{.ID}}EventEmitter {
+	startEventStream(w)
+	return {{.ID}}EventStream{w}
+}
+
+// {{.ID}}EventStream is the {{.ID}}EventEmitter writing to a response.
+type {{.ID}}EventStream struct {
+	w http.ResponseWriter
+}
+{{$op := .}}
+{{- range .Events}}
+func (s {{$op.ID}}EventStream) {{.Method}}(data {{.GoType}}) error {
+	return sendEvent(s.w, {{quote .Name}}, data)
+}
+{{end}}
+{{- end}}
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func sendEvent(w http.ResponseWriter, event string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// ServerSentEvent is an event of an event stream, its data left encoded.
+type ServerSentEvent struct {
+	Event string
+	Data  string
+}
+
+// ReadServerSentEvents reads the events of an event stream until its end, as
+// the body of an httptest.ResponseRecorder or of an http.Response.
+func ReadServerSentEvents(r io.Reader) ([]ServerSentEvent, error) {
+	var events []ServerSentEvent
+	var event ServerSentEvent
+	var data []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event.Event != "" || data != nil {
+				event.Data = strings.Join(data, "\n")
+				events = append(events, event)
+			}
+			event, data = ServerSentEvent{}, nil
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return events, scanner.Err()
+}
+{{end}}
+{{- with simulated}}
+var simulate = flag.Bool("simulate", false, "serve simulated responses instead of calling the handlers")
+
+type simulation struct {
+	latency   time.Duration
+	errorRate float64
+	status    int
+	body      string
+}
+
+var simulations = map[string]*simulation{
+{{- range .}}
+	{{quote .ID}}: {latency: {{.Simulation.Latency}}, errorRate: {{.Simulation.ErrorRate}}, status: {{.Simulation.Status}}, body: {{quote .Simulation.Body}}},
+{{- end}}
+}
+
+// SimulationMiddleware serves the simulated response of the operation instead
+// of calling the handler, when the -simulate flag is set and the operation has
+// a simulation. Responses are delayed by the simulated latency, and are 500
+// errors with the simulated error rate.
+func SimulationMiddleware(f StrictHandlerFunc, operationID string) StrictHandlerFunc {
+	sim := simulations[operationID]
+	if sim == nil {
+		return f
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		if !*simulate {
+			return f(ctx, w, r, request)
+		}
+		time.Sleep(sim.latency)
+		if rand.Float64() < sim.errorRate {
+			return simulatedResponse{status: http.StatusInternalServerError, body: ` + "`" + `{"code":500,"message":"simulated error"}` + "`" + `}, nil
+		}
+		return simulatedResponse{status: sim.status, body: sim.body}, nil
+	}
+}
+
+type simulatedResponse struct {
+	status int
+	body   string
+}
+
+func (response simulatedResponse) visit(w http.ResponseWriter) error {
+	if response.body != "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(response.status)
+	_, err := io.WriteString(w, response.body)
+	return err
+}
+{{range .}}
+func (response simulatedResponse) Visit{{.ID}}Response(w http.ResponseWriter) error {
+	return response.visit(w)
+}
+{{end}}
+{{- end}}`
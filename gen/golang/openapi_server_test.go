@@ -0,0 +1,1465 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+// oapiModels stands for the models oapi-codegen generates for the schema.
+const oapiModels = `package sample
+
+type User struct {
+	Id  string ` + "`json:\"id\"`" + `
+	Age *int32 ` + "`json:\"age,omitempty\"`" + `
+}
+
+type Role string
+
+type ResourceError struct {
+	Code    int32  ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+`
+
+func oapiSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Field("id", "String", false, nil, "").
+		Field("age", "Int32", true, nil, "").
+		Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Role").Element("ADMIN", "").Element("GUEST", "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "ResourceError").
+		Field("code", "Int32", false, nil, "").
+		Field("message", "String", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Comment("Get a user").
+		Input("id", "Int64", true, "", "", false, nil, "").
+		Input("role", "Role", false, "role", "", false, nil, "").
+		Input("limit", "Int32", false, "limit", "", true, nil, "").
+		Input("since", "Timestamp", false, "", "If-Modified-Since", true, nil, "").
+		Exception("NOT_FOUND", "ResourceError", "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "PUT", "/users/{id}").
+		Input("id", "Int64", true, "", "", false, nil, "").
+		Input("user", "User", false, "", "", false, nil, "").
+		Expected("NO_CONTENT").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoOpenAPIServer(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(oapiSchema(), &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	fset := token.NewFileSet()
+	server, err := parser.ParseFile(fset, "server.gen.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated OpenAPI server does not parse: %v\n%s", err, buf.String())
+	}
+	models, err := parser.ParseFile(fset, "types.gen.go", oapiModels, 0)
+	if err != nil {
+		test.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{server, models}, nil); err != nil {
+		test.Fatalf("generated OpenAPI server does not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	if strings.Contains(src, "SimulationMiddleware") {
+		test.Errorf("unexpected simulation middleware:\n%s", src)
+	}
+	for _, expected := range []string{
+		"GetUser(w http.ResponseWriter, r *http.Request, id int64, params GetUserParams)",
+		"Limit *int32     `form:\"limit,omitempty\" json:\"limit,omitempty\"`",
+		"Since *time.Time `json:\"If-Modified-Since,omitempty\"`",
+		`siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "role"})`,
+		"params.Role = Role(s)",
+		"type PutUserJSONRequestBody = User",
+		"GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error)",
+		"type GetUser200JSONResponse User",
+		"type GetUser404JSONResponse ResourceError",
+		"type PutUser204Response struct {",
+		`m.HandleFunc("GET "+options.BaseURL+"/users/{id}", wrapper.GetUser)`,
+		"func NewStrictHandler(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc) ServerInterface",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+// simulationTest runs against the simulation of GetUser, with a latency of
+// 20ms and an error rate of 0.5, checking the error rate over 10,000 requests
+// without the latency.
+const simulationTest = `package sample
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	return GetUser200JSONResponse(User{Id: "real"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+func get(h http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/7?role=ADMIN", nil))
+	return rec
+}
+
+func TestSimulation(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, []StrictMiddlewareFunc{SimulationMiddleware}))
+	if rec := get(h); rec.Code != http.StatusOK || rec.Body.String() != "{\"id\":\"real\"}\n" {
+		t.Fatalf("without -simulate: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	*simulate = true
+	defer func() { *simulate = false }()
+	start := time.Now()
+	get(h)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("simulated response served after %v, expected a latency of 20ms", elapsed)
+	}
+
+	simulations["GetUser"].latency = 0
+	const n = 10000
+	errors := 0
+	for i := 0; i < n; i++ {
+		switch rec := get(h); {
+		case rec.Code == http.StatusInternalServerError:
+			errors++
+		case rec.Code != http.StatusOK || rec.Body.String() != ` + "`" + `{"id":"jane"}` + "`" + `:
+			t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+		}
+	}
+	if rate := float64(errors) / n; math.Abs(rate-0.5) > 0.05*0.5 {
+		t.Errorf("error rate %v over %d requests, expected 0.5 within 5%%", rate, n)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerSimulation(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].Simulate = &rdl.SimulationDef{Latency: 20 * time.Millisecond, ErrorRate: 0.5, Response: map[string]interface{}{"id": "jane"}}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`var simulate = flag.Bool("simulate", false,`,
+		"\"GetUser\": {latency: 20 * time.Millisecond, errorRate: 0.5, status: 200, body: \"{\\\"id\\\":\\\"jane\\\"}\"},",
+		"func SimulationMiddleware(f StrictHandlerFunc, operationID string) StrictHandlerFunc",
+		"func (response simulatedResponse) VisitGetUserResponse(w http.ResponseWriter) error",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "VisitPutUserResponse(w http.ResponseWriter) error {\n\treturn response.visit(w)") {
+		test.Errorf("simulated response generated for an operation without a simulation")
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":             "module sample\n\ngo 1.22\n",
+		"server.gen.go":      src,
+		"types.gen.go":       oapiModels,
+		"simulation_test.go": simulationTest,
+	})
+}
+
+func TestGenerateGoOpenAPIServerEnv(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources = append(schema.Resources, rdl.NewResourceBuilder("String", "GET", "/debug/vars").
+		Name("debugVars").
+		Env("development", "test").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	fset := token.NewFileSet()
+	server, err := parser.ParseFile(fset, "server.gen.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated OpenAPI server does not parse: %v\n%s", err, buf.String())
+	}
+	models, err := parser.ParseFile(fset, "types.gen.go", oapiModels, 0)
+	if err != nil {
+		test.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{server, models}, nil); err != nil {
+		test.Fatalf("generated OpenAPI server does not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"\tif options.Env == \"development\" || options.Env == \"test\" {\n\t\tm.HandleFunc(\"GET \"+options.BaseURL+\"/debug/vars\", wrapper.DebugVars)\n\t}\n",
+		"\tm.HandleFunc(\"GET \"+options.BaseURL+\"/users/{id}\", wrapper.GetUser)\n",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+func TestGenerateGoOpenAPIServerCSP(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].CSP = &rdl.CSPDef{DefaultSrc: []string{"self"}}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := "func (siw *ServerInterfaceWrapper) GetUser(w http.ResponseWriter, r *http.Request) {\n\tw.Header().Set(\"Content-Security-Policy\", \"default-src 'self'\")\n"
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+	}
+	if strings.Count(src, "Content-Security-Policy") != 1 {
+		test.Errorf("Content-Security-Policy set for a resource without a policy:\n%s", src)
+	}
+}
+
+func TestCSPHeader(test *testing.T) {
+	for _, c := range []struct {
+		policy   rdl.CSPDef
+		expected string
+	}{
+		{rdl.CSPDef{DefaultSrc: []string{"self"}}, "default-src 'self'"},
+		{rdl.CSPDef{
+			DefaultSrc:              []string{"'none'"},
+			ScriptSrc:               []string{"self", "https://cdn.example.com", "nonce-2726c7f26c"},
+			StyleSrc:                []string{"self", "unsafe-inline"},
+			ImgSrc:                  []string{"*", "data:"},
+			FrameAncestors:          []string{"none"},
+			UpgradeInsecureRequests: true,
+		}, "default-src 'none'; script-src 'self' https://cdn.example.com 'nonce-2726c7f26c'; style-src 'self' 'unsafe-inline'; img-src * data:; frame-ancestors 'none'; upgrade-insecure-requests"},
+		{rdl.CSPDef{}, ""},
+	} {
+		if header := cspHeader(&c.policy); header != c.expected {
+			test.Errorf("real: \n%s\n, expected: \n%s\n", header, c.expected)
+		}
+	}
+}
+
+// apiKeyTest runs against the generated API key middleware.
+const apiKeyTest = `package sample
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+func TestAPIKey(t *testing.T) {
+	validate := func(ctx context.Context, scheme, key string) bool {
+		return scheme == "X-API-Key" && key == "secret"
+	}
+	h := Handler(NewStrictHandler(server{}, []StrictMiddlewareFunc{APIKeyMiddleware(validate)}))
+	for _, c := range []struct {
+		key    string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"guess", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", "/users/7?role=ADMIN", nil)
+		if c.key != "" {
+			r.Header.Set("X-API-Key", c.key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.status {
+			t.Errorf("key %q: status %d, expected %d", c.key, rec.Code, c.status)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("operation without API key authentication: status %d", rec.Code)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerAPIKey(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].APIKeyAuth = &rdl.APIKeyDef{In: "header", Name: "X-API-Key"}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := `"GetUser": {in: "header", name: "X-API-Key", scheme: "X-API-Key"},`
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":          "module sample\n\ngo 1.22\n",
+		"server.gen.go":   src,
+		"types.gen.go":    oapiModels,
+		"api_key_test.go": apiKeyTest,
+	})
+
+	schema.Resources[0].APIKeyAuth.In = "cookie"
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for an API key in a cookie")
+	}
+}
+
+// rolesTest runs against the generated role checks, with a RoleChecker
+// taking the role of the user from a header.
+const rolesTest = `package sample
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+type headerRoleChecker struct{}
+
+func (headerRoleChecker) HasRole(r *http.Request, roles []string) bool {
+	for _, role := range roles {
+		if r.Header.Get("X-Role") == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRoles(t *testing.T) {
+	h := HandlerWithOptions(NewStrictHandler(server{}, nil), StdHTTPServerOptions{RoleChecker: headerRoleChecker{}})
+	for _, c := range []struct {
+		role   string
+		status int
+	}{
+		{"", http.StatusForbidden},
+		{"viewer", http.StatusForbidden},
+		{"admin", http.StatusOK},
+		{"support", http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", "/users/7?role=ADMIN", nil)
+		r.Header.Set("X-Role", c.role)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.status {
+			t.Errorf("role %q: status %d, expected %d", c.role, rec.Code, c.status)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("operation without roles: status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/users/7?role=ADMIN", nil)
+	r.Header.Set("X-Role", "admin")
+	Handler(NewStrictHandler(server{}, nil)).ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("operation with roles and no role checker: status %d", rec.Code)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerRoles(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].Roles = []string{"admin", "support"}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := `"GetUser": {"admin", "support"},`
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": src,
+		"types.gen.go":  oapiModels,
+		"roles_test.go": rolesTest,
+	})
+
+	schema.Resources[0].Roles = []string{""}
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for an empty role")
+	}
+}
+
+const sseTest = `package sample
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetFeed(ctx context.Context, request GetFeedRequestObject) (GetFeedResponseObject, error) {
+	return GetFeed200EventStreamResponse(func(events GetFeedEventEmitter) error {
+		if err := events.UserUpdated(User{Id: "jane"}); err != nil {
+			return err
+		}
+		return events.Deleted("john")
+	}), nil
+}
+
+func TestEventStream(t *testing.T) {
+	ts := httptest.NewServer(Handler(NewStrictHandler(server{}, nil)))
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type %q", ct)
+	}
+	events, err := ReadServerSentEvents(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ServerSentEvent{{Event: "user-updated", Data: ` + "`" + `{"id":"jane"}` + "`" + `}, {Event: "deleted", Data: ` + "`" + `"john"` + "`" + `}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("events %v, expected %v", events, expected)
+	}
+	var user User
+	if err := json.Unmarshal([]byte(events[0].Data), &user); err != nil || user.Id != "jane" {
+		t.Errorf("user %v: %v", user, err)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(NewStrictHandler(server{}, nil)).ServeHTTP(rec, httptest.NewRequest("GET", "/feed", nil))
+	if events, err := ReadServerSentEvents(rec.Body); err != nil || !reflect.DeepEqual(events, expected) {
+		t.Errorf("recorded events %v, expected %v: %v", events, expected, err)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerSSE(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").Field("id", "String", false, nil, "").Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/feed").
+		Name("GetFeed").
+		SSEEvent("user-updated", "User").
+		SSEEvent("deleted", "String").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var server, spec bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &server, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := server.String()
+	for _, expected := range []string{
+		"type GetFeedEventEmitter interface {\n\t// UserUpdated sends a \"user-updated\" event.\n\tUserUpdated(data User) error\n\t// Deleted sends a \"deleted\" event.\n\tDeleted(data string) error\n}",
+		"type GetFeed200EventStreamResponse func(events GetFeedEventEmitter) error",
+		"func ReadServerSentEvents(r io.Reader) ([]ServerSentEvent, error) {",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+	if err := GenerateGoSpecTest(schema, &spec, SpecTestOptions{}); err != nil {
+		test.Fatalf("cannot generate spec tests: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": src,
+		"types.gen.go":  "package sample\n\ntype User struct {\n\tId string `json:\"id\"`\n}\n",
+		"sse_test.go":   sseTest,
+		"spec_test.go":  spec.String(),
+	})
+
+	schema.Resources[0].SSEEvents = append(schema.Resources[0].SSEEvents, &rdl.SSEEventDef{EventName: "user_updated", PayloadType: "User"})
+	if err := GenerateGoOpenAPIServer(schema, &server, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for events sent by the same method")
+	}
+}
+
+const contentDigestTest = `package sample
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+func TestContentDigest(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/7?role=ADMIN", nil))
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	sum := sha256.Sum256(rec.Body.Bytes())
+	if digest, expected := rec.Header().Get("Content-Digest"), "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":"; digest != expected {
+		t.Errorf("content digest %q, expected %q", digest, expected)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != 204 || rec.Header().Get("Content-Digest") != "" {
+		t.Errorf("resource not content-addressed: status %d, content digest %q", rec.Code, rec.Header().Get("Content-Digest"))
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerContentDigest(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].ContentAddressed = true
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := "\tdw := &contentDigestWriter{ResponseWriter: w}\n\tdefer dw.flush()\n\tw = dw\n"
+	if strings.Count(src, expected) != 1 {
+		test.Errorf("generated OpenAPI server does not have %q once:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":                 "module sample\n\ngo 1.22\n",
+		"server.gen.go":          src,
+		"types.gen.go":           oapiModels,
+		"content_digest_test.go": contentDigestTest,
+	})
+
+	schema.Resources[0].SSEEvents = []*rdl.SSEEventDef{{EventName: "updated", PayloadType: "User"}}
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a content-addressed event stream")
+	}
+}
+
+const batchTest = `package sample
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) PostUsers(ctx context.Context, request PostUsersRequestObject) (PostUsersResponseObject, error) {
+	var result BatchResult[User]
+	for i, user := range *request.Body {
+		if user.Id == "" {
+			result.Errors = append(result.Errors, BulkError{Index: int32(i), Error: "missing id"})
+		} else {
+			result.Successes = append(result.Successes, user)
+		}
+	}
+	return PostUsersBatchResponse(result), nil
+}
+
+func TestBatch(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	for _, c := range []struct {
+		body      string
+		status    int
+		successes int
+		errors    int
+	}{
+		{` + "`" + `[{"id":"jane"},{"id":"john"}]` + "`" + `, 201, 2, 0},
+		{` + "`" + `[{"id":"jane"},{"id":""}]` + "`" + `, 207, 1, 1},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/users", strings.NewReader(c.body)))
+		if rec.Code != c.status {
+			t.Errorf("%s: status %d, expected %d", c.body, rec.Code, c.status)
+		}
+		var result BatchResult[User]
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Successes) != c.successes || len(result.Errors) != c.errors {
+			t.Errorf("%s: result %+v", c.body, result)
+		}
+		if c.errors > 0 && (result.Errors[0].Index != 1 || result.Errors[0].Error != "missing id") {
+			t.Errorf("%s: errors %+v", c.body, result.Errors)
+		}
+	}
+}
+`
+
+func batchSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").Field("id", "String", false, nil, "").Build())
+	sb.AddType(rdl.NewArrayTypeBuilder("Array", "UserList").Items("User").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "UserError").
+		Field("index", "Int32", false, nil, "").
+		Field("error", "String", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("UserList", "POST", "/users").
+		Name("PostUsers").
+		Input("users", "UserList", false, "", "", false, nil, "").
+		Expected("CREATED").
+		BulkError("UserError").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoOpenAPIServerBatch(test *testing.T) {
+	schema := batchSchema()
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"type BulkError = UserError",
+		"type BatchResult[T any] struct {\n\tSuccesses []T         `json:\"successes\"`\n\tErrors    []BulkError `json:\"errors\"`\n}",
+		"type PostUsersBatchResponse BatchResult[User]",
+		"w.WriteHeader(http.StatusMultiStatus)",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": src,
+		"types.gen.go":  "package sample\n\ntype User struct {\n\tId string `json:\"id\"`\n}\n\ntype UserList = []User\n\ntype UserError struct {\n\tIndex int32  `json:\"index\"`\n\tError string `json:\"error\"`\n}\n",
+		"batch_test.go": batchTest,
+	})
+
+	schema.Types[2].StructTypeDef.Fields[0].Type = "Int64"
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a bulk error type without an Int32 index")
+	}
+}
+
+const traceContextTest = `package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var received trace.SpanContext
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	received = trace.SpanContextFromContext(ctx)
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	received = trace.SpanContextFromContext(ctx)
+	return PutUser204Response{}, nil
+}
+
+func TestTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ts := httptest.NewServer(Handler(NewStrictHandler(server{}, nil)))
+	defer ts.Close()
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	root := NewRootCommand()
+	root.SetArgs([]string{"getUser", "--base-url", ts.URL, "--id", "7", "--role", "ADMIN"})
+	if err := root.ExecuteContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if received.TraceID() != traceID || !received.IsRemote() || !received.IsSampled() {
+		t.Errorf("trace context not propagated: %+v", received)
+	}
+
+	req, _ := http.NewRequest("PUT", ts.URL+"/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if received.IsValid() {
+		t.Errorf("trace context extracted for a resource not propagating it: %+v", received)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerTraceContext(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].TraceContext = true
+	var server, cli bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &server, OAPICodegenOptions{Package: "main"}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	expected := "\tr = r.WithContext(otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header)))\n"
+	if strings.Count(server.String(), expected) != 1 {
+		test.Errorf("generated OpenAPI server does not have %q once:\n%s", expected, server.String())
+	}
+	if err := GenerateGoCLI(schema, &cli, GoCLIOptions{}); err != nil {
+		test.Fatalf("cannot generate cli: %v", err)
+	}
+	expected = "\t\t\totel.GetTextMapPropagator().Inject(cmd.Context(), propagation.HeaderCarrier(header))\n"
+	if strings.Count(cli.String(), expected) != 1 {
+		test.Errorf("generated cli does not have %q once:\n%s", expected, cli.String())
+	}
+	skipWithoutModule(test, "github.com/spf13/cobra@v1.8.1")
+	skipWithoutModule(test, "go.opentelemetry.io/otel@v1.28.0")
+	runGoTest(test, map[string]string{
+		"go.mod":                "module sample\n\ngo 1.22\n\nrequire (\n\tgithub.com/spf13/cobra v1.8.1\n\tgo.opentelemetry.io/otel v1.28.0\n\tgo.opentelemetry.io/otel/trace v1.28.0\n)\n",
+		"server.gen.go":         server.String(),
+		"cli.gen.go":            cli.String(),
+		"types.gen.go":          strings.Replace(oapiModels, "package sample", "package main", 1),
+		"main.go":               "package main\n\nfunc main() {\n\tNewRootCommand().Execute()\n}\n",
+		"trace_context_test.go": traceContextTest,
+	})
+}
+
+const loadShedTest = `package sample
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type server struct {
+	started chan bool
+	done    chan bool
+}
+
+func (s server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	s.started <- true
+	<-s.done
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+func TestLoadShed(t *testing.T) {
+	s := server{started: make(chan bool), done: make(chan bool)}
+	h := Handler(NewStrictHandler(s, nil))
+	get := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/7?role=ADMIN", nil))
+		return rec.Code
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status := get(); status != 200 {
+				t.Errorf("concurrent request within the limit: status %d", status)
+			}
+		}()
+		<-s.started
+	}
+	if status := get(); status != 503 {
+		t.Errorf("request beyond the limit: status %d, expected 503", status)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != 204 {
+		t.Errorf("operation without load shedding: status %d", rec.Code)
+	}
+	s.done <- true
+	s.done <- true
+	wg.Wait()
+	go func() {
+		<-s.started
+		s.done <- true
+	}()
+	if status := get(); status != 200 {
+		t.Errorf("request after the others ended: status %d", status)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerLoadShed(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].LoadShed = &rdl.LoadShedDef{MaxConcurrent: 2}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := `"GetUser": {running: make(chan struct{}, 2), waiting: make(chan struct{}, 0)},`
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":            "module sample\n\ngo 1.22\n",
+		"server.gen.go":     src,
+		"types.gen.go":      oapiModels,
+		"load_shed_test.go": loadShedTest,
+	})
+
+	schema.Resources[0].LoadShed.MaxConcurrent = 0
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a concurrency of 0")
+	}
+}
+
+// leaseTest runs against the generated lease handlers and timeouts, with
+// an in-memory LeaseStore.
+const leaseTest = `package sample
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	if request.Params.Limit != nil {
+		time.Sleep(time.Duration(*request.Params.Limit) * time.Millisecond)
+	}
+	return GetUser200JSONResponse(User{Id: "jane"}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+type memoryLeaseStore struct {
+	mu        sync.Mutex
+	holders   map[string]string
+	durations map[string]time.Duration
+}
+
+func (s *memoryLeaseStore) Acquire(ctx context.Context, resource string, duration time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.holders {
+		if r == resource {
+			return "", ErrLeaseHeld
+		}
+	}
+	token := fmt.Sprintf("token-%d", len(s.durations))
+	s.holders[token] = resource
+	s.durations[token] = duration
+	return token, nil
+}
+
+func (s *memoryLeaseStore) Release(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.holders[token]; !ok {
+		return ErrLeaseNotHeld
+	}
+	delete(s.holders, token)
+	return nil
+}
+
+func (s *memoryLeaseStore) Renew(ctx context.Context, token string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.holders[token]; !ok {
+		return ErrLeaseNotHeld
+	}
+	s.durations[token] += duration
+	return nil
+}
+
+func TestLeaseTimeout(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	for _, c := range []struct {
+		delay  int
+		status int
+	}{
+		{0, http.StatusOK},
+		{200, http.StatusServiceUnavailable},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/users/7?role=ADMIN&limit=%d", c.delay), nil))
+		if rec.Code != c.status {
+			t.Errorf("delay %dms: status %d, expected %d", c.delay, rec.Code, c.status)
+		}
+		if c.status == http.StatusServiceUnavailable && !strings.Contains(rec.Body.String(), "lease expired") {
+			t.Errorf("delay %dms: unexpected body %q", c.delay, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("operation without a lease: status %d", rec.Code)
+	}
+}
+
+func TestLeaseHandlers(t *testing.T) {
+	store := &memoryLeaseStore{holders: map[string]string{}, durations: map[string]time.Duration{}}
+	lh := LeaseHandlers{Store: store}
+	call := func(handler http.HandlerFunc, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/users/7", nil)
+		r.Header.Set("Lease-Token", token)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		return rec
+	}
+	rec := call(lh.AcquireGetUser, "")
+	token := rec.Header().Get("Lease-Token")
+	if rec.Code != http.StatusCreated || token == "" {
+		t.Fatalf("acquire: status %d, token %q", rec.Code, token)
+	}
+	if d := store.durations[token]; d != 50*time.Millisecond {
+		t.Errorf("acquire: lease of %v, expected 50ms", d)
+	}
+	if rec := call(lh.AcquireGetUser, ""); rec.Code != http.StatusConflict {
+		t.Errorf("acquire of a held lease: status %d", rec.Code)
+	}
+	if rec := call(lh.RenewGetUser, token); rec.Code != http.StatusNoContent || store.durations[token] != 100*time.Millisecond {
+		t.Errorf("renew: status %d, lease of %v", rec.Code, store.durations[token])
+	}
+	if rec := call(lh.ReleaseGetUser, token); rec.Code != http.StatusNoContent {
+		t.Errorf("release: status %d", rec.Code)
+	}
+	if rec := call(lh.RenewGetUser, token); rec.Code != http.StatusConflict {
+		t.Errorf("renew of a released lease: status %d", rec.Code)
+	}
+}
+
+func TestLeaseRoutes(t *testing.T) {
+	store := &memoryLeaseStore{holders: map[string]string{}, durations: map[string]time.Duration{}}
+	h := HandlerWithOptions(NewStrictHandler(server{}, nil), StdHTTPServerOptions{LeaseStore: store})
+	call := func(method, url, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, nil)
+		r.Header.Set("Lease-Token", token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	rec := call("POST", "/users/7/lease", "")
+	token := rec.Header().Get("Lease-Token")
+	if rec.Code != http.StatusCreated || token == "" || store.holders[token] != "/users/7/lease" {
+		t.Fatalf("acquire: status %d, token %q, holders %v", rec.Code, token, store.holders)
+	}
+	if rec := call("PUT", "/users/7/renew", token); rec.Code != http.StatusNoContent || store.durations[token] != 100*time.Millisecond {
+		t.Errorf("renew: status %d, lease of %v", rec.Code, store.durations[token])
+	}
+	if rec := call("DELETE", "/users/7/lease", token); rec.Code != http.StatusNoContent || len(store.holders) != 0 {
+		t.Errorf("release: status %d, holders %v", rec.Code, store.holders)
+	}
+	if rec := call("PUT", "/users/7/renew", token); rec.Code != http.StatusConflict {
+		t.Errorf("renew of a released lease: status %d", rec.Code)
+	}
+
+	h = Handler(NewStrictHandler(server{}, nil))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/users/7/lease", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("acquire without a LeaseStore: status %d", rec.Code)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerLease(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].Lease = &rdl.LeaseDef{MaxDuration: 50 * time.Millisecond, RenewPath: "/users/{id}/renew"}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`m.HandleFunc("GET "+options.BaseURL+"/users/{id}", http.TimeoutHandler(http.HandlerFunc(wrapper.GetUser), 50*time.Millisecond, "lease expired").ServeHTTP)`,
+		`m.HandleFunc("PUT "+options.BaseURL+"/users/{id}", wrapper.PutUser)`,
+		"// RenewGetUser renews the lease of a GetUser resource, served at /users/{id}/renew.\n",
+		`m.HandleFunc("POST "+options.BaseURL+"/users/{id}/lease", lh.AcquireGetUser)`,
+		`m.HandleFunc("DELETE "+options.BaseURL+"/users/{id}/lease", lh.ReleaseGetUser)`,
+		`m.HandleFunc("PUT "+options.BaseURL+"/users/{id}/renew", lh.RenewGetUser)`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": src,
+		"types.gen.go":  oapiModels,
+		"lease_test.go": leaseTest,
+	})
+
+	schema.Resources[0].Lease.RenewPath = ""
+	buf.Reset()
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	if expected := `m.HandleFunc("PUT "+options.BaseURL+"/users/{id}/lease", lh.RenewGetUser)`; !strings.Contains(buf.String(), expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, buf.String())
+	}
+
+	schema.Resources[0].Lease.RenewPath = "renew"
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a relative renew path")
+	}
+	schema.Resources[0].Lease = &rdl.LeaseDef{}
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a lease without a duration")
+	}
+}
+
+// cacheTest runs against the generated response caching, with a CacheStore
+// recording its calls.
+const cacheTest = `package sample
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type server struct {
+	events *[]string
+}
+
+func (s server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	*s.events = append(*s.events, "handler")
+	return GetUser200JSONResponse(User{Id: string(request.Params.Role)}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+type recordingCacheStore struct {
+	events  *[]string
+	entries map[string][]byte
+}
+
+func (s recordingCacheStore) Get(ctx context.Context, key string) ([]byte, bool) {
+	*s.events = append(*s.events, "get")
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+func (s recordingCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	*s.events = append(*s.events, "set "+ttl.String())
+	s.entries[key] = value
+}
+
+func TestCache(t *testing.T) {
+	var events []string
+	store := recordingCacheStore{events: &events, entries: map[string][]byte{}}
+	h := HandlerWithOptions(NewStrictHandler(server{events: &events}, nil), StdHTTPServerOptions{CacheStore: store})
+	for _, c := range []struct {
+		url    string
+		events []string
+		body   string
+	}{
+		{"/users/7?role=ADMIN", []string{"get", "handler", "set 50ms"}, "{\"id\":\"ADMIN\"}\n"},
+		{"/users/7?role=ADMIN", []string{"get"}, "{\"id\":\"ADMIN\"}\n"},
+		{"/users/7?role=GUEST", []string{"get"}, "{\"id\":\"ADMIN\"}\n"},
+		{"/users/8?role=GUEST", []string{"get", "handler", "set 50ms"}, "{\"id\":\"GUEST\"}\n"},
+	} {
+		events = nil
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", c.url, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != c.body || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: status %d, body %q, content type %q", c.url, rec.Code, rec.Body.String(), rec.Header().Get("Content-Type"))
+		}
+		if !reflect.DeepEqual(events, c.events) {
+			t.Errorf("%s: expected %v, got %v", c.url, c.events, events)
+		}
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	var events []string
+	h := Handler(NewStrictHandler(server{events: &events}, nil))
+	get := func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/7?role=ADMIN", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("status %d", rec.Code)
+		}
+	}
+	get()
+	get()
+	if len(events) != 1 {
+		t.Errorf("expected the second request to be served from the cache, got %v", events)
+	}
+	time.Sleep(60 * time.Millisecond)
+	get()
+	if len(events) != 2 {
+		t.Errorf("expected the cached response to expire, got %v", events)
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerCache(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].Cache = &rdl.CacheDef{TTL: 50 * time.Millisecond, KeyFrom: []rdl.Identifier{"id"}}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	src := buf.String()
+	expected := `serveCached(w, r, siw.CacheStore, cacheKey("GetUser", id), 50*time.Millisecond, func(w http.ResponseWriter) {`
+	if !strings.Contains(src, expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, src)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": src,
+		"types.gen.go":  oapiModels,
+		"cache_test.go": cacheTest,
+	})
+
+	schema.Resources[0].Cache = &rdl.CacheDef{TTL: 50 * time.Millisecond, Backend: "redis"}
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	for _, expected := range []string{
+		`cacheKey("GetUser", id, params.Role, optionalCacheKey(params.Limit), optionalCacheKey(params.Since))`,
+		`panic("operations cached in Redis need a CacheStore")`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, buf.String())
+		}
+	}
+
+	schema.Resources[0].Cache = &rdl.CacheDef{TTL: 1500 * time.Microsecond}
+	buf.Reset()
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	if expected := "1500000*time.Nanosecond, func(w http.ResponseWriter) {"; !strings.Contains(buf.String(), expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, buf.String())
+	}
+	for _, c := range []struct {
+		resource int
+		cache    *rdl.CacheDef
+	}{
+		{0, &rdl.CacheDef{TTL: 50 * time.Millisecond, Backend: "memcached"}},
+		{0, &rdl.CacheDef{TTL: 50 * time.Millisecond, KeyFrom: []rdl.Identifier{"user"}}},
+		{0, &rdl.CacheDef{}},
+		{1, &rdl.CacheDef{TTL: 50 * time.Millisecond}},
+	} {
+		schema := oapiSchema()
+		schema.Resources[c.resource].Cache = c.cache
+		if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+			test.Errorf("expected an error for the cache %+v of resource %d", c.cache, c.resource)
+		}
+	}
+}
+
+// tenantTest runs against the tenant extraction of GetUser, taking the
+// tenant from the tid claim of a JWT, and of PutUser, taking it from the
+// X-Tenant-ID header.
+const tenantTest = `package sample
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	tenant, _ := TenantFromContext(ctx)
+	return GetUser200JSONResponse(User{Id: tenant}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	if tenant, ok := TenantFromContext(ctx); !ok || tenant != "acme" {
+		return nil, http.ErrNoLocation
+	}
+	return PutUser204Response{}, nil
+}
+
+func token(claims string) string {
+	enc := base64.RawURLEncoding
+	return "Bearer " + enc.EncodeToString([]byte(` + "`" + `{"alg":"none"}` + "`" + `)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestTenantFromJWT(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	for _, c := range []struct {
+		authorization string
+		status        int
+		body          string
+	}{
+		{token(` + "`" + `{"tid":"acme"}` + "`" + `), http.StatusOK, ` + "`" + `"id":"acme"` + "`" + `},
+		{token(` + "`" + `{"sub":"jane"}` + "`" + `), http.StatusBadRequest, "missing tenant"},
+		{token(` + "`" + `{"tid":7}` + "`" + `), http.StatusBadRequest, "missing tenant"},
+		{"Bearer garbage", http.StatusBadRequest, "missing tenant"},
+		{"", http.StatusBadRequest, "missing tenant"},
+	} {
+		r := httptest.NewRequest("GET", "/users/7?role=ADMIN", nil)
+		r.Header.Set("Authorization", c.authorization)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.status || !strings.Contains(rec.Body.String(), c.body) {
+			t.Errorf("authorization %q: status %d, body %q", c.authorization, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTenantFromHeader(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	for _, c := range []struct {
+		tenant string
+		status int
+	}{
+		{"acme", http.StatusNoContent},
+		{"", http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `))
+		r.Header.Set("X-Tenant-ID", c.tenant)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.status {
+			t.Errorf("tenant %q: status %d, expected %d", c.tenant, rec.Code, c.status)
+		}
+	}
+}
+`
+
+// tenantPathTest runs against the tenant extraction of GetUser, taking the
+// tenant from its id path parameter.
+const tenantPathTest = `package sample
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	tenant, _ := TenantFromContext(ctx)
+	return GetUser200JSONResponse(User{Id: tenant}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	if _, ok := TenantFromContext(ctx); ok {
+		return nil, http.ErrNoLocation
+	}
+	return PutUser204Response{}, nil
+}
+
+func TestTenantFromPath(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/42?role=ADMIN", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), ` + "`" + `"id":"42"` + "`" + `) {
+		t.Errorf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("PUT", "/users/7", strings.NewReader(` + "`" + `{"id":"jane"}` + "`" + `)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("operation without a tenant: status %d", rec.Code)
+	}
+}
+`
+
+// tenantCacheTest runs against GetUser, cached by id and taking the tenant
+// from the X-Tenant-ID header, which must not serve a tenant the cached
+// response of another.
+const tenantCacheTest = `package sample
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type server struct{}
+
+func (server) GetUser(ctx context.Context, request GetUserRequestObject) (GetUserResponseObject, error) {
+	tenant, _ := TenantFromContext(ctx)
+	return GetUser200JSONResponse(User{Id: tenant}), nil
+}
+
+func (server) PutUser(ctx context.Context, request PutUserRequestObject) (PutUserResponseObject, error) {
+	return PutUser204Response{}, nil
+}
+
+func TestTenantCache(t *testing.T) {
+	h := Handler(NewStrictHandler(server{}, nil))
+	for _, tenant := range []string{"acme", "evil", "acme"} {
+		r := httptest.NewRequest("GET", "/users/7?role=ADMIN", nil)
+		r.Header.Set("X-Tenant-ID", tenant)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if expected := "{\"id\":\"" + tenant + "\"}\n"; rec.Code != http.StatusOK || rec.Body.String() != expected {
+			t.Errorf("tenant %q: status %d, body %q, expected %q", tenant, rec.Code, rec.Body.String(), expected)
+		}
+	}
+}
+`
+
+func TestGenerateGoOpenAPIServerMultiTenant(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "jwt", TenantKey: "tid"}
+	schema.Resources[1].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "header", TenantKey: "X-Tenant-ID"}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":         "module sample\n\ngo 1.22\n",
+		"server.gen.go":  buf.String(),
+		"types.gen.go":   oapiModels,
+		"tenant_test.go": tenantTest,
+	})
+
+	schema = oapiSchema()
+	schema.Resources[0].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "path", TenantKey: "id"}
+	buf.Reset()
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	if strings.Contains(buf.String(), "tenantFromJWT") {
+		test.Errorf("tenantFromJWT generated without a JWT tenant")
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":         "module sample\n\ngo 1.22\n",
+		"server.gen.go":  buf.String(),
+		"types.gen.go":   oapiModels,
+		"tenant_test.go": tenantPathTest,
+	})
+
+	schema = oapiSchema()
+	schema.Resources[0].MultiTenant = &rdl.MultiTenantDef{TenantFrom: "header", TenantKey: "X-Tenant-ID"}
+	schema.Resources[0].Cache = &rdl.CacheDef{TTL: time.Minute, KeyFrom: []rdl.Identifier{"id"}}
+	buf.Reset()
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	if expected := `cacheKey("GetUser", tenant, id)`; !strings.Contains(buf.String(), expected) {
+		test.Errorf("generated OpenAPI server is missing %q:\n%s", expected, buf.String())
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":         "module sample\n\ngo 1.22\n",
+		"server.gen.go":  buf.String(),
+		"types.gen.go":   oapiModels,
+		"tenant_test.go": tenantCacheTest,
+	})
+
+	for _, mt := range []*rdl.MultiTenantDef{
+		{TenantFrom: "cookie", TenantKey: "tenant"},
+		{TenantFrom: "path", TenantKey: "role"},
+		{TenantFrom: "header", TenantKey: ""},
+	} {
+		schema = oapiSchema()
+		schema.Resources[0].MultiTenant = mt
+		if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+			test.Errorf("expected an error for the tenant %+v", mt)
+		}
+	}
+}
+
+func TestGenerateGoOpenAPIServerBadSimulation(test *testing.T) {
+	schema := oapiSchema()
+	schema.Resources[0].Simulate = &rdl.SimulationDef{ErrorRate: 1.5}
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for an error rate above 1")
+	}
+}
+
+func TestGenerateGoOpenAPIServerBadParameter(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").Field("id", "String", false, nil, "").Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users").
+		Input("filter", "User", false, "filter", "", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateGoOpenAPIServer(gentest.MustBuild(sb), &buf, OAPICodegenOptions{}); err == nil {
+		test.Errorf("expected an error for a struct query parameter")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GenerateGoSLOReport generates an SLOReport function returning the measured
+// 99th percentile response time, in milliseconds, of every resource with a
+// response time SLO. The percentiles are queried from Prometheus, from the
+// http_request_duration_seconds histogram labelled with the resource name.
+func GenerateGoSLOReport(s *rdl.Schema, w io.Writer) error {
+	var resources []*rdl.Resource
+	for _, r := range s.Resources {
+		if r.ResponseTimeSLO != nil {
+			resources = append(resources, r)
+		}
+	}
+	if len(resources) == 0 {
+		return fmt.Errorf("schema %s has no resources with a response time SLO", s.Name)
+	}
+	funcMap := template.FuncMap{
+		"header":    func() string { return utils.GoGenerationHeader(banner) },
+		"package":   func() string { return packageName(s, "") },
+		"resources": func() []*rdl.Resource { return resources },
+		"name":      methodName,
+		"quote":     func(s string) string { return fmt.Sprintf("%q", s) },
+		"query": func(r *rdl.Resource) string {
+			return fmt.Sprintf("histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{resource=%q}[5m])) by (le))", methodName(r))
+		},
+	}
+	return executeTemplate(w, "slo", goSLOReportTemplate, funcMap, s)
+}
+
+const goSLOReportTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PrometheusURL is the base URL of the Prometheus server SLOReport queries.
+var PrometheusURL = "http://localhost:9090"
+
+// SLOTargets maps each resource to its 99th percentile response time
+// objective, in milliseconds.
+var SLOTargets = map[string]float64{
+{{- range resources}}
+	{{quote (name .)}}: {{.ResponseTimeSLO}},
+{{- end}}
+}
+
+var sloQueries = map[string]string{
+{{- range resources}}
+	{{quote (name .)}}: {{quote (query .)}},
+{{- end}}
+}
+
+// SLOReport returns the current 99th percentile response time of each
+// resource in SLOTargets, in milliseconds. Resources that cannot be queried
+// are left out.
+func SLOReport() map[string]float64 {
+	client := &http.Client{Timeout: 10 * time.Second}
+	report := make(map[string]float64)
+	for resource, query := range sloQueries {
+		if p99, ok := queryPrometheus(client, query); ok {
+			report[resource] = p99 * 1000
+		}
+	}
+	return report
+}
+
+func queryPrometheus(client *http.Client, query string) (float64, bool) {
+	resp, err := client.Get(PrometheusURL + "/api/v1/query?query=" + url.QueryEscape(query))
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Status string
+		Data   struct {
+			Result []struct {
+				Value []interface{}
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Status != "success" {
+		return 0, false
+	}
+	if len(result.Data.Result) == 0 || len(result.Data.Result[0].Value) != 2 {
+		return 0, false
+	}
+	value, ok := result.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false
+	}
+	p99, err := strconv.ParseFloat(value, 64)
+	return p99, err == nil
+}
+`
@@ -0,0 +1,50 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateGoSLOReport(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/ping").Name("ping").ResponseTimeSLO(50).Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/slow").Build())
+	var buf bytes.Buffer
+	if err := GenerateGoSLOReport(gentest.MustBuild(sb), &buf); err != nil {
+		test.Fatalf("cannot generate SLO report: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "slo.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated SLO report does not parse: %v", err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{f}, nil); err != nil {
+		test.Fatalf("generated SLO report does not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`"ping": 50,`,
+		`func SLOReport() map[string]float64`,
+		`http_request_duration_seconds_bucket{resource=\"ping\"}`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated SLO report is missing %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "getString") {
+		test.Errorf("resource without an SLO included in the report")
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// SpecTestOptions controls the generated specification tests.
+type SpecTestOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+	// InProcess serves the test requests by calling the handler with an
+	// httptest.ResponseRecorder, instead of over HTTP with an
+	// httptest.Server.
+	InProcess bool
+}
+
+type specOperation struct {
+	*oapiOperation
+	Target    string
+	Headers   [][2]string
+	Expected  []string
+	BodyValue string
+	Responses []*specResponse
+	Missing   []*specMissing
+}
+
+type specResponse struct {
+	*oapiResponse
+	Object string
+	Value  string
+}
+
+// specMissing is a request lacking a required parameter.
+type specMissing struct {
+	Key     string
+	Target  string
+	Headers [][2]string
+}
+
+// specTimestamp is the example timestamp, as a Go expression and in RFC
+// 3339 format.
+const (
+	specTimestamp       = "time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)"
+	specTimestampString = "2006-01-02T15:04:05Z"
+)
+
+// GenerateGoSpecTest generates a TestAPISpec<Operation> test for every
+// resource, checking the server generated by GenerateGoOpenAPIServer, in
+// the same package, follows the schema. A stub StrictServerInterface
+// implementation receives requests with example values for all the inputs,
+// checking they are decoded as sent, and returns example values for every
+// declared response, checking the client decodes them as returned.
+// Requests lacking a required parameter must fail with 400 Bad Request
+// without reaching the implementation. The models of the schema types are
+// expected to be declared in the package, as for GenerateGoOpenAPIServer.
+func GenerateGoSpecTest(s *rdl.Schema, w io.Writer, opts SpecTestOptions) error {
+	if len(s.Resources) == 0 {
+		return fmt.Errorf("schema %s has no resources", s.Name)
+	}
+	registry := rdl.NewTypeRegistry(s)
+	var ops []*specOperation
+	for _, r := range s.Resources {
+		op, err := newOAPIOperation(registry, r)
+		if err != nil {
+			return fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+		}
+		sop, err := newSpecOperation(registry, op)
+		if err != nil {
+			return fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+		}
+		ops = append(ops, sop)
+	}
+	usesTime := false
+	for _, op := range ops {
+		code := strings.Join(op.Expected, "\n") + op.BodyValue
+		for _, resp := range op.Responses {
+			code += resp.Value
+		}
+		if strings.Contains(code, "time.") {
+			usesTime = true
+		}
+	}
+	funcMap := template.FuncMap{
+		"header":     func() string { return utils.GoGenerationHeader(banner) },
+		"package":    func() string { return packageName(s, opts.Package) },
+		"operations": func() []*specOperation { return ops },
+		"inProcess":  func() bool { return opts.InProcess },
+		"usesTime":   func() bool { return usesTime },
+		"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+		"hasBody":    func(resp *specResponse) bool { return resp.GoType != "" },
+		"field":      utils.Uncapitalize,
+	}
+	return executeTemplate(w, "spec", goSpecTestTemplate, funcMap, s)
+}
+
+func newSpecOperation(registry rdl.TypeRegistry, op *oapiOperation) (*specOperation, error) {
+	sop := &specOperation{oapiOperation: op}
+	path := op.Path
+	for _, p := range op.PathParams {
+		value, form, err := specExample(registry, p.Type, 0)
+		if err != nil {
+			return nil, err
+		}
+		path = strings.Replace(path, "{"+p.Key+"}", url.PathEscape(form), 1)
+		sop.Expected = append(sop.Expected, fmt.Sprintf("expected.%s = %s", p.Name, value))
+	}
+	query := url.Values{}
+	for _, p := range op.Params {
+		value, form, err := specExample(registry, p.Type, 0)
+		if err != nil {
+			return nil, err
+		}
+		if p.Optional {
+			sop.Expected = append(sop.Expected, fmt.Sprintf("%s := %s\nexpected.Params.%s = &%s", p.Var, value, p.Name, p.Var))
+		} else {
+			sop.Expected = append(sop.Expected, fmt.Sprintf("expected.Params.%s = %s", p.Name, value))
+		}
+		if p.Query {
+			query.Add(p.Key, form)
+		} else {
+			sop.Headers = append(sop.Headers, [2]string{p.Key, form})
+		}
+	}
+	target := func(query url.Values) string {
+		if len(query) == 0 {
+			return path
+		}
+		return path + "?" + query.Encode()
+	}
+	sop.Target = target(query)
+	for _, p := range op.Params {
+		if p.Optional {
+			continue
+		}
+		missing := &specMissing{Key: p.Key, Target: sop.Target, Headers: sop.Headers}
+		if p.Query {
+			q := url.Values{}
+			for k, v := range query {
+				if k != p.Key {
+					q[k] = v
+				}
+			}
+			missing.Target = target(q)
+		} else {
+			missing.Headers = nil
+			for _, h := range sop.Headers {
+				if h[0] != p.Key {
+					missing.Headers = append(missing.Headers, h)
+				}
+			}
+		}
+		sop.Missing = append(sop.Missing, missing)
+	}
+	if op.Body != "" {
+		value, _, err := specExample(registry, op.BodyType, 0)
+		if err != nil {
+			return nil, err
+		}
+		sop.BodyValue = value
+	}
+	for _, resp := range op.Responses {
+		sresp := &specResponse{oapiResponse: resp, Object: op.ID + resp.Code + "Response{}"}
+		if resp.Stream {
+			sresp.Object = fmt.Sprintf("%s%sEventStreamResponse(func(events %sEventEmitter) error { return nil })", op.ID, resp.Code, op.ID)
+		} else if resp.GoType != "" {
+			value, _, err := specExample(registry, resp.Type, 0)
+			if err != nil {
+				return nil, err
+			}
+			sresp.Value = value
+			sresp.Object = fmt.Sprintf("%s%sJSONResponse(response)", op.ID, resp.Code)
+		}
+		sop.Responses = append(sop.Responses, sresp)
+	}
+	return sop, nil
+}
+
+// specExample returns a Go expression of an example value of the type, of
+// its Go type as given by oapiGoType, and the value in its URL and header
+// form. Only the required fields of structs are set.
+func specExample(registry rdl.TypeRegistry, ref rdl.TypeRef, depth int) (string, string, error) {
+	goType, err := oapiGoType(registry, ref)
+	if err != nil {
+		return "", "", err
+	}
+	t := registry.FindType(ref)
+	switch registry.BaseType(t) {
+	case rdl.BaseTypeBool:
+		return "true", "true", nil
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		return goType + "(7)", "7", nil
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		return goType + "(1.5)", "1.5", nil
+	case rdl.BaseTypeUUID:
+		return `"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+		value := "example"
+		if t.StringTypeDef != nil && len(t.StringTypeDef.Values) > 0 {
+			value = t.StringTypeDef.Values[0]
+		}
+		return fmt.Sprintf("%q", value), value, nil
+	case rdl.BaseTypeTimestamp:
+		return specTimestamp, specTimestampString, nil
+	case rdl.BaseTypeBytes:
+		return `[]byte("example")`, "example", nil
+	case rdl.BaseTypeEnum:
+		if t.EnumTypeDef == nil || len(t.EnumTypeDef.Elements) == 0 {
+			return "", "", fmt.Errorf("enum %s has no elements", ref)
+		}
+		symbol := string(t.EnumTypeDef.Elements[0].Symbol)
+		return fmt.Sprintf("%s(%q)", goType, symbol), symbol, nil
+	case rdl.BaseTypeStruct:
+		if strings.HasPrefix(goType, "map[") {
+			return "nil", "", nil
+		}
+		if depth > 4 {
+			return goType + "{}", "", nil
+		}
+		var values []string
+		for _, f := range utils.FlattenedFields(registry, t) {
+			if f.Optional {
+				continue
+			}
+			value, _, err := specExample(registry, f.Type, depth+1)
+			if err != nil {
+				return "", "", err
+			}
+			values = append(values, fmt.Sprintf("%s: %s", goName(string(f.Name)), value))
+		}
+		return goType + "{" + strings.Join(values, ", ") + "}", "", nil
+	case rdl.BaseTypeArray:
+		if strings.HasPrefix(goType, "[]") || t.ArrayTypeDef == nil || depth > 4 {
+			return "nil", "", nil
+		}
+		value, _, err := specExample(registry, t.ArrayTypeDef.Items, depth+1)
+		if err != nil {
+			return "", "", err
+		}
+		return goType + "{" + value + "}", "", nil
+	default:
+		if strings.HasPrefix(goType, "map[") || goType == "interface{}" {
+			return "nil", "", nil
+		}
+		return goType + "{}", "", nil
+	}
+}
+
+const goSpecTestTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+{{- if usesTime}}
+	"time"
+{{- end}}
+)
+
+// specServer implements StrictServerInterface with the functions set by
+// the specification tests.
+type specServer struct {
+{{- range operations}}
+	{{field .ID}} func(ctx context.Context, request {{.ID}}RequestObject) ({{.ID}}ResponseObject, error)
+{{- end}}
+}
+{{range operations}}
+func (s *specServer) {{.ID}}(ctx context.Context, request {{.ID}}RequestObject) ({{.ID}}ResponseObject, error) {
+	return s.{{field .ID}}(ctx, request)
+}
+{{end}}
+// specDo sends a request with the body encoded in JSON, if not nil, to the
+// handler, and returns the status and the body of the response.
+func specDo(t *testing.T, h http.Handler, method, target string, header http.Header, body interface{}) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+{{- if inProcess}}
+	r := httptest.NewRequest(method, target, reader)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec.Code, rec.Body.Bytes()
+{{- else}}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	r, err := http.NewRequest(method, srv.URL+target, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	resp, err := srv.Client().Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+{{- end}}
+}
+
+// specEqual compares values by their JSON encoding.
+func specEqual(t *testing.T, what string, real, expected interface{}) {
+	t.Helper()
+	r, err := json.Marshal(real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, e) {
+		t.Errorf("%s: real %s, expected %s", what, r, e)
+	}
+}
+{{range $op := operations}}
+// TestAPISpec{{.ID}} checks {{.Method}} {{.Path}} follows the schema.
+func TestAPISpec{{.ID}}(t *testing.T) {
+	spec := &specServer{}
+	h := Handler(NewStrictHandler(spec, nil))
+
+	var expected {{.ID}}RequestObject
+{{- range .Expected}}
+	{{.}}
+{{- end}}
+{{- if .Body}}
+	var body {{.ID}}JSONRequestBody = {{.BodyValue}}
+	expected.Body = &body
+{{- end}}
+	header := http.Header{}
+{{- range .Headers}}
+	header.Set({{quote (index . 0)}}, {{quote (index . 1)}})
+{{- end}}
+{{range .Responses}}
+	t.Run({{quote .Code}}, func(t *testing.T) {
+{{- if hasBody .}}
+		var response {{.GoType}} = {{.Value}}
+{{- end}}
+		spec.{{field $op.ID}} = func(ctx context.Context, request {{$op.ID}}RequestObject) ({{$op.ID}}ResponseObject, error) {
+			specEqual(t, "request", request, expected)
+			return {{.Object}}, nil
+		}
+		status, data := specDo(t, h, {{quote $op.Method}}, {{quote $op.Target}}, header, {{if $op.Body}}expected.Body{{else}}nil{{end}})
+		if status != {{.Code}} {
+			t.Fatalf("status %d, expected {{.Code}}: %s", status, data)
+		}
+{{- if hasBody .}}
+		var decoded {{.GoType}}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("cannot decode the response: %v", err)
+		}
+		specEqual(t, "response", decoded, response)
+{{- end}}
+	})
+{{end}}
+{{- range .Missing}}
+	t.Run({{quote (print "missing " .Key)}}, func(t *testing.T) {
+		spec.{{field $op.ID}} = func(ctx context.Context, request {{$op.ID}}RequestObject) ({{$op.ID}}ResponseObject, error) {
+			t.Errorf("request without {{.Key}} reached the implementation")
+			return nil, nil
+		}
+		header := http.Header{}
+{{- range .Headers}}
+		header.Set({{quote (index . 0)}}, {{quote (index . 1)}})
+{{- end}}
+		status, data := specDo(t, h, {{quote $op.Method}}, {{quote .Target}}, header, {{if $op.Body}}expected.Body{{else}}nil{{end}})
+		if status != http.StatusBadRequest {
+			t.Errorf("status %d, expected 400: %s", status, data)
+		}
+	})
+{{end}}
+}
+{{end}}`
@@ -0,0 +1,74 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateGoSpecTest(test *testing.T) {
+	for _, inProcess := range []bool{false, true} {
+		var server, spec bytes.Buffer
+		if err := GenerateGoOpenAPIServer(oapiSchema(), &server, OAPICodegenOptions{}); err != nil {
+			test.Fatalf("cannot generate OpenAPI server: %v", err)
+		}
+		if err := GenerateGoSpecTest(oapiSchema(), &spec, SpecTestOptions{InProcess: inProcess}); err != nil {
+			test.Fatalf("cannot generate spec tests: %v", err)
+		}
+		src := spec.String()
+		for _, expected := range []string{
+			"func TestAPISpecGetUser(t *testing.T) {",
+			"\texpected.Id = int64(7)\n\texpected.Params.Role = Role(\"ADMIN\")\n\tlimit := int32(7)\n\texpected.Params.Limit = &limit\n",
+			`status, data := specDo(t, h, "GET", "/users/7?limit=7&role=ADMIN", header, nil)`,
+			`header.Set("If-Modified-Since", "2006-01-02T15:04:05Z")`,
+			`var response ResourceError = ResourceError{Code: int32(7), Message: "example"}`,
+			`status, data := specDo(t, h, "GET", "/users/7?limit=7", header, nil)`,
+			`var body PutUserJSONRequestBody = User{Id: "example"}`,
+			"return PutUser204Response{}, nil",
+		} {
+			if !strings.Contains(src, expected) {
+				test.Errorf("generated spec tests are missing %q:\n%s", expected, src)
+			}
+		}
+		if inProcess != strings.Contains(src, "httptest.NewRecorder()") || inProcess == strings.Contains(src, "httptest.NewServer(h)") {
+			test.Errorf("spec tests not served as expected, in process: %v\n%s", inProcess, src)
+		}
+		runGoTest(test, map[string]string{
+			"go.mod":        "module sample\n\ngo 1.22\n",
+			"server.gen.go": server.String(),
+			"types.gen.go":  oapiModels,
+			"spec_test.go":  src,
+		})
+	}
+}
+
+func TestGenerateGoSpecTestSimpleGet(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Field("id", "String", false, nil, "").
+		Field("age", "Int32", true, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Input("id", "String", true, "", "", false, nil, "").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var server, spec bytes.Buffer
+	if err := GenerateGoOpenAPIServer(schema, &server, OAPICodegenOptions{}); err != nil {
+		test.Fatalf("cannot generate OpenAPI server: %v", err)
+	}
+	if err := GenerateGoSpecTest(schema, &spec, SpecTestOptions{}); err != nil {
+		test.Fatalf("cannot generate spec tests: %v", err)
+	}
+	runGoTest(test, map[string]string{
+		"go.mod":        "module sample\n\ngo 1.22\n",
+		"server.gen.go": server.String(),
+		"types.gen.go":  "package sample\n\ntype User struct {\n\tId  string `json:\"id\"`\n\tAge *int32 `json:\"age,omitempty\"`\n}\n",
+		"spec_test.go":  spec.String(),
+	})
+}
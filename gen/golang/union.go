@@ -0,0 +1,157 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoUnionOptions controls the generated union types.
+type GoUnionOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type unionType struct {
+	Name       string
+	Comment    string
+	Exhaustive bool
+	Variants   []*unionVariant
+}
+
+type unionVariant struct {
+	Name   string
+	GoType string
+}
+
+// GenerateGoUnions generates a Go type for every union type: a struct
+// holding a pointer for each variant and a Variant field telling which one
+// is set, in the way of the ardielle Go generator. Its Value method and its
+// JSON encoding switch on the variant; for exhaustive unions, the switch
+// panics on unhandled variants instead of returning nil. Named variant
+// types are expected to be declared in the same package.
+func GenerateGoUnions(s *rdl.Schema, w io.Writer, opts GoUnionOptions) error {
+	registry := rdl.NewTypeRegistry(s)
+	var unions []*unionType
+	for _, t := range s.Types {
+		if t.UnionTypeDef == nil {
+			continue
+		}
+		ut := &unionType{
+			Name:       typeVarName(rdl.TypeRef(t.UnionTypeDef.Name)),
+			Comment:    t.UnionTypeDef.Comment,
+			Exhaustive: t.UnionTypeDef.Exhaustive,
+		}
+		if len(t.UnionTypeDef.Variants) == 0 {
+			return fmt.Errorf("union %s has no variants", t.UnionTypeDef.Name)
+		}
+		for _, v := range t.UnionTypeDef.Variants {
+			goType, err := oapiGoType(registry, v)
+			if err != nil {
+				return fmt.Errorf("union %s: %v", t.UnionTypeDef.Name, err)
+			}
+			ut.Variants = append(ut.Variants, &unionVariant{Name: typeVarName(v), GoType: goType})
+		}
+		unions = append(unions, ut)
+	}
+	if len(unions) == 0 {
+		return fmt.Errorf("schema %s has no union types", s.Name)
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader(banner) },
+		"package": func() string { return packageName(s, opts.Package) },
+		"unions":  func() []*unionType { return unions },
+		"usesTime": func() bool {
+			for _, ut := range unions {
+				for _, v := range ut.Variants {
+					if v.GoType == "time.Time" {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+	return executeTemplate(w, "union", goUnionsTemplate, funcMap, s)
+}
+
+const goUnionsTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+{{- if usesTime}}
+	"time"
+{{- end}}
+)
+{{range $u := unions}}
+// {{.Name}}Variant tells which variant a {{.Name}} holds.
+type {{.Name}}Variant int
+
+// {{.Name}}Variant constants
+const (
+	_ {{.Name}}Variant = iota
+{{- range .Variants}}
+	{{$u.Name}}Variant{{.Name}}
+{{- end}}
+)
+
+// {{.Name}}{{if .Comment}} - {{.Comment}}{{else}} holds one of its variants.{{end}}
+type {{.Name}} struct {
+	Variant {{.Name}}Variant
+{{- range .Variants}}
+	{{.Name}} *{{.GoType}}
+{{- end}}
+}
+
+// Value returns the variant the {{.Name}} holds.
+func (u *{{.Name}}) Value() interface{} {
+	switch u.Variant {
+{{- range .Variants}}
+	case {{$u.Name}}Variant{{.Name}}:
+		return u.{{.Name}}
+{{- end}}
+{{- if .Exhaustive}}
+	default:
+		panic(fmt.Sprintf("exhaustive: unhandled {{.Name}} variant %d", u.Variant))
+	}
+{{- else}}
+	}
+	return nil
+{{- end}}
+}
+
+// MarshalJSON encodes the variant the {{.Name}} holds.
+func (u {{.Name}}) MarshalJSON() ([]byte, error) {
+	v := u.Value()
+	if v == nil {
+		return nil, fmt.Errorf("{{.Name}}: no variant set")
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the first variant the data is a valid encoding of.
+func (u *{{.Name}}) UnmarshalJSON(b []byte) error {
+{{- range .Variants}}
+	{
+		var v {{.GoType}}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&v); err == nil {
+			*u = {{$u.Name}}{Variant: {{$u.Name}}Variant{{.Name}}, {{.Name}}: &v}
+			return nil
+		}
+	}
+{{- end}}
+	return fmt.Errorf("cannot decode {{.Name}}: %s", b)
+}
+{{end}}`
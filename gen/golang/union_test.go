@@ -0,0 +1,109 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+const unionModels = `package sample
+
+type Circle struct {
+	Radius float64 ` + "`json:\"radius\"`" + `
+}
+
+type Square struct {
+	Side float64 ` + "`json:\"side\"`" + `
+}
+`
+
+// unionRuntimeTest runs against the generated exhaustive union.
+const unionRuntimeTest = `package sample
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestShape(t *testing.T) {
+	data, err := json.Marshal(Shape{Variant: ShapeVariantSquare, Square: &Square{Side: 2}})
+	if err != nil || string(data) != ` + "`" + `{"side":2}` + "`" + ` {
+		t.Fatalf("unexpected encoding %s: %v", data, err)
+	}
+	var shape Shape
+	if err := json.Unmarshal([]byte(` + "`" + `{"radius":1}` + "`" + `), &shape); err != nil || shape.Variant != ShapeVariantCircle {
+		t.Fatalf("unexpected decoding %+v: %v", shape, err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for an unknown variant")
+		}
+	}()
+	(&Shape{Variant: 3}).Value()
+}
+`
+
+func unionSchema(exhaustive bool) *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Circle").Field("radius", "Float64", false, nil, "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Square").Field("side", "Float64", false, nil, "").Build())
+	sb.AddType(rdl.NewUnionTypeBuilder("Union", "Shape").
+		Variant("Circle").
+		Variant("Square").
+		Exhaustive(exhaustive).
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoUnions(test *testing.T) {
+	for _, exhaustive := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := GenerateGoUnions(unionSchema(exhaustive), &buf, GoUnionOptions{}); err != nil {
+			test.Fatalf("cannot generate unions: %v", err)
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "union.go", buf.Bytes(), 0)
+		if err != nil {
+			test.Fatalf("generated unions do not parse: %v\n%s", err, buf.String())
+		}
+		models, err := parser.ParseFile(fset, "models.go", unionModels, 0)
+		if err != nil {
+			test.Fatal(err)
+		}
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		if _, err := conf.Check("sample", fset, []*ast.File{f, models}, nil); err != nil {
+			test.Fatalf("generated unions do not compile: %v\n%s", err, buf.String())
+		}
+		src := buf.String()
+		for _, expected := range []string{
+			"\tcase ShapeVariantCircle:\n\t\treturn u.Circle\n\tcase ShapeVariantSquare:\n\t\treturn u.Square\n",
+			"\tCircle  *Circle\n",
+		} {
+			if !strings.Contains(src, expected) {
+				test.Errorf("generated unions are missing %q:\n%s", expected, src)
+			}
+		}
+		panics := strings.Contains(src, "\tdefault:\n\t\tpanic(fmt.Sprintf(\"exhaustive: unhandled Shape variant %d\", u.Variant))\n")
+		if panics != exhaustive {
+			test.Errorf("exhaustive: %v, but default panic clause present: %v\n%s", exhaustive, panics, src)
+		}
+		if exhaustive {
+			runGoTest(test, map[string]string{
+				"union.go":      src,
+				"models.go":     unionModels,
+				"union_test.go": unionRuntimeTest,
+			})
+		}
+	}
+}
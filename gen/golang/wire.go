@@ -0,0 +1,220 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GoWireOptions controls the generated binary encoding methods.
+type GoWireOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type wireType struct {
+	Name      string
+	ByteOrder string
+	Size      int
+	Fields    []*wireField
+}
+
+type wireField struct {
+	Name   string
+	RDL    string
+	Offset int
+	Width  int
+	Kind   string
+	GoType string
+}
+
+// wireWidths are the widths of the fixed-size base types, with the kind of
+// encoding/binary accessor they use.
+var wireWidths = map[rdl.BaseType]struct {
+	width int
+	kind  string
+}{
+	rdl.BaseTypeBool:    {1, "bool"},
+	rdl.BaseTypeInt8:    {1, "int8"},
+	rdl.BaseTypeInt16:   {2, "Uint16"},
+	rdl.BaseTypeInt32:   {4, "Uint32"},
+	rdl.BaseTypeInt64:   {8, "Uint64"},
+	rdl.BaseTypeFloat32: {4, "Float32"},
+	rdl.BaseTypeFloat64: {8, "Float64"},
+}
+
+// GenerateGoWireFormat generates MarshalBinary and UnmarshalBinary methods,
+// implementing encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, for
+// the struct types with a wire format. The fields are laid out in order:
+// booleans and numbers with their natural width in the declared byte order,
+// strings and bytes zero-padded to the width of the field. The Go types are
+// expected to be declared in the same package, their fields named after the
+// capitalized RDL field names.
+func GenerateGoWireFormat(s *rdl.Schema, w io.Writer, opts GoWireOptions) error {
+	registry := rdl.NewTypeRegistry(s)
+	var types []*wireType
+	for _, t := range s.Types {
+		if t.StructTypeDef == nil || t.StructTypeDef.WireFormat == nil {
+			continue
+		}
+		wt, err := newWireType(registry, t)
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.StructTypeDef.Name, err)
+		}
+		types = append(types, wt)
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("schema %s has no struct types with a wire format", s.Name)
+	}
+	uses := func(kinds ...string) bool {
+		for _, wt := range types {
+			for _, f := range wt.Fields {
+				for _, kind := range kinds {
+					if f.Kind == kind {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	funcMap := template.FuncMap{
+		"header":      func() string { return utils.GoGenerationHeader(banner) },
+		"package":     func() string { return packageName(s, opts.Package) },
+		"types":       func() []*wireType { return types },
+		"usesBinary":  func() bool { return uses("Uint16", "Uint32", "Uint64", "Float32", "Float64") },
+		"usesMath":    func() bool { return uses("Float32", "Float64") },
+		"usesStrings": func() bool { return uses("string") },
+		"limit":       func(f *wireField) int { return f.Offset + f.Width },
+		"bits":        func(f *wireField) string { return "uint" + fmt.Sprint(f.Width*8) },
+	}
+	return executeTemplate(w, "wire", goWireFormatTemplate, funcMap, s)
+}
+
+func newWireType(registry rdl.TypeRegistry, t *rdl.Type) (*wireType, error) {
+	wf := t.StructTypeDef.WireFormat
+	wt := &wireType{Name: typeVarName(rdl.TypeRef(t.StructTypeDef.Name))}
+	switch wf.ByteOrder {
+	case "big":
+		wt.ByteOrder = "binary.BigEndian"
+	case "little":
+		wt.ByteOrder = "binary.LittleEndian"
+	default:
+		return nil, fmt.Errorf("unknown byte order %q, expected \"big\" or \"little\"", wf.ByteOrder)
+	}
+	fields := make(map[string]bool)
+	for _, f := range utils.FlattenedFields(registry, t) {
+		name := string(f.Name)
+		fields[name] = true
+		if f.Optional {
+			return nil, fmt.Errorf("optional field %s has no fixed width", name)
+		}
+		wfield := &wireField{Name: goName(name), RDL: name, Offset: wt.Size}
+		width, hasWidth := wf.FieldWidths[name]
+		switch bt := registry.FindBaseType(f.Type); bt {
+		case rdl.BaseTypeString, rdl.BaseTypeBytes:
+			if !hasWidth || width <= 0 {
+				return nil, fmt.Errorf("field %s needs a positive width", name)
+			}
+			wfield.Width = int(width)
+			wfield.Kind, wfield.GoType = "string", "string"
+			if bt == rdl.BaseTypeBytes {
+				wfield.Kind, wfield.GoType = "bytes", "[]byte"
+			}
+		default:
+			natural, ok := wireWidths[bt]
+			if !ok {
+				return nil, fmt.Errorf("field %s of type %s has no wire format", name, f.Type)
+			}
+			if hasWidth && int(width) != natural.width {
+				return nil, fmt.Errorf("field %s of type %s is %d bytes wide, not %d", name, f.Type, natural.width, width)
+			}
+			wfield.Width, wfield.Kind = natural.width, natural.kind
+			wfield.GoType = strings.ToLower(bt.String())
+		}
+		wt.Size += wfield.Width
+		wt.Fields = append(wt.Fields, wfield)
+	}
+	for name := range wf.FieldWidths {
+		if !fields[name] {
+			return nil, fmt.Errorf("width given for unknown field %s", name)
+		}
+	}
+	return wt, nil
+}
+
+const goWireFormatTemplate = `{{header}}
+
+package {{package}}
+
+import (
+{{- if usesBinary}}
+	"encoding/binary"
+{{- end}}
+	"fmt"
+{{- if usesMath}}
+	"math"
+{{- end}}
+{{- if usesStrings}}
+	"strings"
+{{- end}}
+)
+{{range $t := types}}
+// MarshalBinary encodes the {{.Name}} in its {{.Size}} bytes wire format.
+func (o *{{.Name}}) MarshalBinary() ([]byte, error) {
+	data := make([]byte, {{.Size}})
+{{- range .Fields}}
+{{- if eq .Kind "bool"}}
+	if o.{{.Name}} {
+		data[{{.Offset}}] = 1
+	}
+{{- else if eq .Kind "int8"}}
+	data[{{.Offset}}] = byte(o.{{.Name}})
+{{- else if or (eq .Kind "string") (eq .Kind "bytes")}}
+	if len(o.{{.Name}}) > {{.Width}} {
+		return nil, fmt.Errorf("{{$t.Name}}.{{.RDL}}: %d bytes do not fit in {{.Width}}", len(o.{{.Name}}))
+	}
+	copy(data[{{.Offset}}:{{limit .}}], o.{{.Name}})
+{{- else if eq .Kind "Float32"}}
+	{{$t.ByteOrder}}.PutUint32(data[{{.Offset}}:{{limit .}}], math.Float32bits(o.{{.Name}}))
+{{- else if eq .Kind "Float64"}}
+	{{$t.ByteOrder}}.PutUint64(data[{{.Offset}}:{{limit .}}], math.Float64bits(o.{{.Name}}))
+{{- else}}
+	{{$t.ByteOrder}}.Put{{.Kind}}(data[{{.Offset}}:{{limit .}}], {{bits .}}(o.{{.Name}}))
+{{- end}}
+{{- end}}
+	return data, nil
+}
+
+// UnmarshalBinary decodes the {{.Name}} from its {{.Size}} bytes wire format.
+func (o *{{.Name}}) UnmarshalBinary(data []byte) error {
+	if len(data) != {{.Size}} {
+		return fmt.Errorf("{{.Name}}: %d bytes, expected {{.Size}}", len(data))
+	}
+{{- range .Fields}}
+{{- if eq .Kind "bool"}}
+	o.{{.Name}} = data[{{.Offset}}] != 0
+{{- else if eq .Kind "int8"}}
+	o.{{.Name}} = int8(data[{{.Offset}}])
+{{- else if eq .Kind "string"}}
+	o.{{.Name}} = strings.TrimRight(string(data[{{.Offset}}:{{limit .}}]), "\x00")
+{{- else if eq .Kind "bytes"}}
+	o.{{.Name}} = append([]byte(nil), data[{{.Offset}}:{{limit .}}]...)
+{{- else if eq .Kind "Float32"}}
+	o.{{.Name}} = math.Float32frombits({{$t.ByteOrder}}.Uint32(data[{{.Offset}}:{{limit .}}]))
+{{- else if eq .Kind "Float64"}}
+	o.{{.Name}} = math.Float64frombits({{$t.ByteOrder}}.Uint64(data[{{.Offset}}:{{limit .}}]))
+{{- else}}
+	o.{{.Name}} = {{.GoType}}({{$t.ByteOrder}}.{{.Kind}}(data[{{.Offset}}:{{limit .}}]))
+{{- end}}
+{{- end}}
+	return nil
+}
+{{end}}`
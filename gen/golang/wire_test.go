@@ -0,0 +1,145 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+const wireModels = `package sample
+
+type Header struct {
+	Version  int8
+	Flags    int16
+	Length   int32
+	Sequence int64
+	Ratio    float32
+	Score    float64
+	Urgent   bool
+	Name     string
+	Digest   []byte
+}
+`
+
+// wireRoundTripTest runs against the generated methods.
+const wireRoundTripTest = `package sample
+
+import (
+	"bytes"
+	"encoding"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Header)(nil)
+	_ encoding.BinaryUnmarshaler = (*Header)(nil)
+)
+
+func TestRoundTrip(t *testing.T) {
+	h := &Header{Version: -3, Flags: 0x0102, Length: -70000, Sequence: 1 << 40, Ratio: 0.5, Score: -2.25,
+		Urgent: true, Name: "frame", Digest: []byte{1, 2, 3, 4}}
+	data, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 40 || !bytes.Equal(data[1:3], []byte{0x01, 0x02}) {
+		t.Errorf("unexpected encoding: %x", data)
+	}
+	var decoded Header
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, &decoded) {
+		t.Errorf("round trip changed %+v into %+v", h, decoded)
+	}
+	h.Name = "a name too long"
+	if _, err := h.MarshalBinary(); err == nil {
+		t.Errorf("expected an error for a name longer than its width")
+	}
+	if err := decoded.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("expected an error for truncated data")
+	}
+}
+`
+
+func wireSchema(byteOrder string, widths map[string]int) *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Header").
+		Field("version", "Int8", false, nil, "").
+		Field("flags", "Int16", false, nil, "").
+		Field("length", "Int32", false, nil, "").
+		Field("sequence", "Int64", false, nil, "").
+		Field("ratio", "Float32", false, nil, "").
+		Field("score", "Float64", false, nil, "").
+		Field("urgent", "Bool", false, nil, "").
+		Field("name", "String", false, nil, "").
+		Field("digest", "Bytes", false, nil, "").
+		WireFormat(byteOrder, widths).
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGoWireFormat(test *testing.T) {
+	var buf bytes.Buffer
+	err := GenerateGoWireFormat(wireSchema("big", map[string]int{"name": 8, "digest": 4}), &buf, GoWireOptions{})
+	if err != nil {
+		test.Fatalf("cannot generate wire format: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "wire.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated wire format does not parse: %v\n%s", err, buf.String())
+	}
+	models, err := parser.ParseFile(fset, "models.go", wireModels, 0)
+	if err != nil {
+		test.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, []*ast.File{f, models}, nil); err != nil {
+		test.Fatalf("generated wire format does not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"binary.BigEndian.PutUint16(data[1:3], uint16(o.Flags))",
+		"o.Score = math.Float64frombits(binary.BigEndian.Uint64(data[19:27]))",
+		"copy(data[36:40], o.Digest)",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated wire format is missing %q:\n%s", expected, src)
+		}
+	}
+	runGoTest(test, map[string]string{
+		"wire.go":      src,
+		"models.go":    wireModels,
+		"wire_test.go": wireRoundTripTest,
+	})
+}
+
+func TestGenerateGoWireFormatBadDefinition(test *testing.T) {
+	for _, c := range []struct {
+		byteOrder string
+		widths    map[string]int
+	}{
+		{"middle", map[string]int{"name": 8, "digest": 4}},
+		{"little", map[string]int{"name": 8}},
+		{"little", map[string]int{"name": 8, "digest": 4, "flags": 4}},
+		{"little", map[string]int{"name": 8, "digest": 4, "checksum": 4}},
+	} {
+		var buf bytes.Buffer
+		if err := GenerateGoWireFormat(wireSchema(c.byteOrder, c.widths), &buf, GoWireOptions{}); err == nil {
+			test.Errorf("expected an error for byte order %s and widths %v", c.byteOrder, c.widths)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package graphql generates GraphQL schema definition language (SDL) from RDL
+// schemas.
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+var operationTypes = []string{"query", "mutation", "subscription"}
+
+type graphqlGenerator struct {
+	registry   rdl.TypeRegistry
+	schema     *rdl.Schema
+	buf        bytes.Buffer
+	scalars    map[string]bool
+	inputTypes map[string]bool
+	federation map[string]*rdl.FederationDef
+	err        error
+}
+
+// GenerateGraphQL writes the SDL for the types of the schema, plus the root
+// operation types for every resource that has a GraphQL mapping. The type of
+// a resource with federation metadata is declared as an Apollo Federation
+// entity.
+func GenerateGraphQL(s *rdl.Schema, w io.Writer) error {
+	gen := &graphqlGenerator{
+		registry:   rdl.NewTypeRegistry(s),
+		schema:     s,
+		scalars:    make(map[string]bool),
+		inputTypes: make(map[string]bool),
+		federation: make(map[string]*rdl.FederationDef),
+	}
+	for _, r := range s.Resources {
+		if r.Federation == nil {
+			continue
+		}
+		name := string(r.Type)
+		if fed, ok := gen.federation[name]; ok && *fed != *r.Federation {
+			return fmt.Errorf("conflicting federation metadata for type %s", name)
+		}
+		gen.federation[name] = r.Federation
+	}
+	for _, t := range s.Types {
+		gen.emitType(t)
+	}
+	for _, op := range operationTypes {
+		gen.emitOperation(op)
+	}
+	gen.emitInputTypes()
+	if gen.err != nil {
+		return gen.err
+	}
+	if len(gen.scalars) > 0 {
+		var scalars []string
+		for name := range gen.scalars {
+			scalars = append(scalars, name)
+		}
+		sort.Strings(scalars)
+		for _, name := range scalars {
+			fmt.Fprintf(w, "scalar %s\n", name)
+		}
+		fmt.Fprintln(w)
+	}
+	_, err := w.Write(gen.buf.Bytes())
+	return err
+}
+
+func (gen *graphqlGenerator) emitComment(comment string, indent string) {
+	if comment != "" {
+		fmt.Fprintf(&gen.buf, "%s%q\n", indent, comment)
+	}
+}
+
+func (gen *graphqlGenerator) emitType(t *rdl.Type) {
+	tName, _, tComment := rdl.TypeInfo(t)
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		gen.emitComment(tComment, "")
+		decl := "type " + string(tName)
+		if config := t.StructTypeDef.GraphQLConfig; config != nil && len(config.Interfaces) > 0 {
+			decl += " implements " + strings.Join(config.Interfaces, " & ")
+		}
+		if fed := gen.federation[string(tName)]; fed != nil {
+			if fed.Extendable {
+				decl = "extend " + decl
+			}
+			decl += fmt.Sprintf(" @key(fields: %q)", fed.Key)
+		}
+		fmt.Fprintf(&gen.buf, "%s {\n", decl)
+		gen.emitFields(t, false)
+		gen.buf.WriteString("}\n\n")
+	case rdl.TypeVariantEnumTypeDef:
+		gen.emitComment(tComment, "")
+		fmt.Fprintf(&gen.buf, "enum %s {\n", tName)
+		for _, e := range t.EnumTypeDef.Elements {
+			gen.emitComment(e.Comment, "  ")
+			fmt.Fprintf(&gen.buf, "  %s\n", e.Symbol)
+		}
+		gen.buf.WriteString("}\n\n")
+	case rdl.TypeVariantUnionTypeDef:
+		var variants []string
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, string(v))
+		}
+		gen.emitComment(tComment, "")
+		fmt.Fprintf(&gen.buf, "union %s = %s\n\n", tName, strings.Join(variants, " | "))
+	}
+	//other types have no GraphQL declaration; references to them resolve to
+	//their underlying scalar or list type
+}
+
+func (gen *graphqlGenerator) emitFields(t *rdl.Type, input bool) {
+	//the key fields of an extended entity are owned by another subgraph
+	var external map[string]bool
+	tName, _, _ := rdl.TypeInfo(t)
+	if fed := gen.federation[string(tName)]; fed != nil && fed.Extendable && !input {
+		external = keyFields(fed.Key)
+	}
+	for _, f := range utils.FlattenedFields(gen.registry, t) {
+		gen.emitComment(f.Comment, "  ")
+		ftype := gen.fieldType(f.Type, f.Items, input)
+		if !f.Optional {
+			ftype += "!"
+		}
+		if external[string(f.Name)] {
+			ftype += " @external"
+		}
+		fmt.Fprintf(&gen.buf, "  %s: %s\n", f.Name, ftype)
+	}
+}
+
+// keyFields returns the top level fields of a federation key, leaving out the
+// selections of nested objects.
+func keyFields(key string) map[string]bool {
+	fields := make(map[string]bool)
+	depth := 0
+	for _, token := range strings.Fields(strings.NewReplacer("{", " { ", "}", " } ").Replace(key)) {
+		switch token {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		default:
+			if depth == 0 {
+				fields[token] = true
+			}
+		}
+	}
+	return fields
+}
+
+func (gen *graphqlGenerator) emitOperation(op string) {
+	var resources []*rdl.Resource
+	for _, r := range gen.schema.Resources {
+		if r.GraphQLMapping != nil && strings.ToLower(r.GraphQLMapping.OperationType) == op {
+			resources = append(resources, r)
+		}
+	}
+	if len(resources) == 0 {
+		return
+	}
+	fmt.Fprintf(&gen.buf, "type %s {\n", utils.Capitalize(op))
+	for _, r := range resources {
+		gen.emitComment(r.Comment, "  ")
+		fmt.Fprintf(&gen.buf, "  %s%s: %s\n", r.GraphQLMapping.FieldName, gen.arguments(r), gen.fieldType(r.Type, "", false))
+	}
+	gen.buf.WriteString("}\n\n")
+}
+
+func (gen *graphqlGenerator) arguments(r *rdl.Resource) string {
+	inputs := make(map[string]*rdl.ResourceInput)
+	var names []string
+	for _, in := range r.Inputs {
+		if in.Header != "" || in.Context != "" {
+			continue
+		}
+		inputs[string(in.Name)] = in
+		names = append(names, string(in.Name))
+	}
+	if args := r.GraphQLMapping.Args; len(args) > 0 {
+		names = names[:0]
+		for arg := range args {
+			names = append(names, arg)
+		}
+		sort.Strings(names)
+	}
+	var params []string
+	for _, name := range names {
+		in := inputs[name]
+		if mapped, ok := r.GraphQLMapping.Args[name]; ok {
+			in = inputs[mapped]
+		}
+		if in == nil {
+			gen.err = fmt.Errorf("GraphQL argument %s of %s does not name an input of the resource", name, r.GraphQLMapping.FieldName)
+			return ""
+		}
+		ptype := gen.fieldType(in.Type, "", true)
+		if !in.Optional {
+			ptype += "!"
+		}
+		params = append(params, name+": "+ptype)
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// fieldType returns the GraphQL type for a reference to an RDL type. Struct
+// types referenced from arguments are mapped to their input type.
+func (gen *graphqlGenerator) fieldType(ref rdl.TypeRef, items rdl.TypeRef, input bool) string {
+	t := gen.registry.FindType(ref)
+	if t == nil {
+		gen.err = fmt.Errorf("unknown type: %s", ref)
+		return string(ref)
+	}
+	switch gen.registry.BaseType(t) {
+	case rdl.BaseTypeBool:
+		return "Boolean"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32:
+		return "Int"
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		return "Float"
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+		return "String"
+	case rdl.BaseTypeUUID:
+		return "ID"
+	case rdl.BaseTypeInt64:
+		return gen.scalar("Long")
+	case rdl.BaseTypeTimestamp:
+		return gen.scalar("Timestamp")
+	case rdl.BaseTypeBytes:
+		return gen.scalar("Bytes")
+	case rdl.BaseTypeArray:
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items == "" {
+			items = "Any"
+		}
+		return "[" + gen.fieldType(items, "", input) + "]"
+	case rdl.BaseTypeStruct:
+		tName, _, _ := rdl.TypeInfo(t)
+		if tName == "Struct" {
+			return gen.scalar("JSON")
+		}
+		if input {
+			gen.inputTypes[string(tName)] = true
+			return string(tName) + "Input"
+		}
+		return string(tName)
+	case rdl.BaseTypeEnum, rdl.BaseTypeUnion:
+		tName, _, _ := rdl.TypeInfo(t)
+		return string(tName)
+	default:
+		return gen.scalar("JSON")
+	}
+}
+
+func (gen *graphqlGenerator) scalar(name string) string {
+	gen.scalars[name] = true
+	return name
+}
+
+// emitInputTypes declares an input type for every struct used as an argument,
+// including the structs those reference in turn.
+func (gen *graphqlGenerator) emitInputTypes() {
+	done := make(map[string]bool)
+	for len(done) < len(gen.inputTypes) {
+		var pending []string
+		for name := range gen.inputTypes {
+			if !done[name] {
+				pending = append(pending, name)
+			}
+		}
+		sort.Strings(pending)
+		for _, name := range pending {
+			done[name] = true
+			t := gen.registry.FindType(rdl.TypeRef(name))
+			fmt.Fprintf(&gen.buf, "input %sInput {\n", name)
+			gen.emitFields(t, true)
+			gen.buf.WriteString("}\n\n")
+		}
+	}
+}
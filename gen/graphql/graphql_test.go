@@ -0,0 +1,102 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package graphql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func userSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("users")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Comment("A user").
+		Field("id", "UUID", false, nil, "").
+		Field("name", "String", false, nil, "").
+		Field("logins", "Int64", true, nil, "").
+		ArrayField("tags", "String", true, "").
+		Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Role").Element("ADMIN", "").Element("GUEST", "").Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Input("id", "UUID", true, "", "", false, nil, "").
+		Input("auth", "String", false, "", "Authorization", false, nil, "").
+		GraphQL("query", "getUser").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "POST", "/users").
+		Input("user", "User", false, "", "", false, nil, "").
+		GraphQL("mutation", "createUser").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "DELETE", "/users/{id}").
+		Input("id", "UUID", true, "", "", false, nil, "").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGraphQL(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGraphQL(userSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate graphql: %v", err)
+	}
+	sdl := buf.String()
+	for _, expected := range []string{
+		"scalar Long\n",
+		"\"A user\"\ntype User {\n  id: ID!\n  name: String!\n  logins: Long\n  tags: [String]\n}\n",
+		"enum Role {\n  ADMIN\n  GUEST\n}\n",
+		"type Query {\n  getUser(id: ID!): User\n}\n",
+		"type Mutation {\n  createUser(user: UserInput!): User\n}\n",
+		"input UserInput {\n  id: ID!\n",
+	} {
+		if !strings.Contains(sdl, expected) {
+			test.Errorf("generated SDL is missing %q:\n%s", expected, sdl)
+		}
+	}
+	if strings.Contains(sdl, "Subscription") {
+		test.Errorf("unexpected Subscription type:\n%s", sdl)
+	}
+}
+
+func TestGenerateGraphQLFederation(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("reviews")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Product").
+		Field("upc", "String", false, nil, "").
+		Field("name", "String", true, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Review").
+		Field("id", "UUID", false, nil, "").
+		Field("body", "String", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Product", "GET", "/products/{upc}").
+		Input("upc", "String", true, "", "", false, nil, "").
+		Federation("upc", true).
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Review", "GET", "/reviews/{id}").
+		Input("id", "UUID", true, "", "", false, nil, "").
+		Federation("id", false).
+		GraphQL("query", "review").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateGraphQL(gentest.MustBuild(sb), &buf); err != nil {
+		test.Fatalf("cannot generate graphql: %v", err)
+	}
+	sdl := buf.String()
+	for _, expected := range []string{
+		"extend type Product @key(fields: \"upc\") {\n  upc: String! @external\n  name: String\n}\n",
+		"type Review @key(fields: \"id\") {\n  id: ID!\n  body: String!\n}\n",
+	} {
+		if !strings.Contains(sdl, expected) {
+			test.Errorf("generated SDL is missing %q:\n%s", expected, sdl)
+		}
+	}
+}
+
+func TestKeyFields(test *testing.T) {
+	fields := keyFields("id organization { id name }")
+	if len(fields) != 2 || !fields["id"] || !fields["organization"] {
+		test.Errorf("unexpected key fields: %v", fields)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// ResolverOptions controls the generated resolver stubs.
+type ResolverOptions struct {
+	// Package is the package of the generated file, the schema name if empty.
+	Package string
+}
+
+type resolverType struct {
+	Name   string
+	Fields []*resolverField
+}
+
+type resolverField struct {
+	Name     string
+	Resolver string
+}
+
+// GenerateGraphQLResolvers generates a Go stub for every resolver function
+// named with GraphQLResolver, and a Resolvers map from type and field names
+// to those functions, for the GraphQL server to dispatch to.
+func GenerateGraphQLResolvers(s *rdl.Schema, w io.Writer, opts ResolverOptions) error {
+	var types []*resolverType
+	var stubs []string
+	declared := make(map[string]bool)
+	for _, t := range s.Types {
+		st := t.StructTypeDef
+		if st == nil || st.GraphQLConfig == nil || len(st.GraphQLConfig.ResolveField) == 0 {
+			continue
+		}
+		fields := make(map[string]bool)
+		for _, f := range st.Fields {
+			fields[string(f.Name)] = true
+		}
+		rt := &resolverType{Name: string(st.Name)}
+		for name, fn := range st.GraphQLConfig.ResolveField {
+			if !fields[name] {
+				return fmt.Errorf("%s has no field %s to resolve", st.Name, name)
+			}
+			if !token.IsIdentifier(fn) {
+				return fmt.Errorf("%s.%s: %q is not a valid function name", st.Name, name, fn)
+			}
+			rt.Fields = append(rt.Fields, &resolverField{Name: name, Resolver: fn})
+			if !declared[fn] {
+				declared[fn] = true
+				stubs = append(stubs, fn)
+			}
+		}
+		sort.Slice(rt.Fields, func(i, j int) bool { return rt.Fields[i].Name < rt.Fields[j].Name })
+		types = append(types, rt)
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("schema %s has no GraphQL resolvers", s.Name)
+	}
+	sort.Strings(stubs)
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = utils.GoGenerationPackage(s)
+	}
+	funcMap := template.FuncMap{
+		"header":  func() string { return utils.GoGenerationHeader("parsec-rdl-gen") },
+		"package": func() string { return pkg },
+		"types":   func() []*resolverType { return types },
+		"stubs":   func() []string { return stubs },
+	}
+	tmpl, err := template.New("resolvers").Funcs(funcMap).Parse(resolverTemplate)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const resolverTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"context"
+	"errors"
+)
+
+// ResolverFunc computes the value of a field of source, the object the field
+// belongs to.
+type ResolverFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// Resolvers holds the resolver of every resolved field, by type and field
+// name.
+var Resolvers = map[string]map[string]ResolverFunc{
+{{- range types}}
+	"{{.Name}}": {
+{{- range .Fields}}
+		"{{.Name}}": {{.Resolver}},
+{{- end}}
+	},
+{{- end}}
+}
+{{range stubs}}
+func {{.}}(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return nil, errors.New("{{.}} is not implemented")
+}
+{{end}}`
@@ -0,0 +1,90 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package graphql
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func resolverSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("social")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Field("id", "UUID", false, nil, "").
+		ArrayField("friends", "String", true, "").
+		ArrayField("posts", "String", true, "").
+		GraphQLType("Node", "Entity").
+		GraphQLResolver("posts", "resolveUserPosts").
+		GraphQLResolver("friends", "resolveFriends").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Group").
+		Field("id", "UUID", false, nil, "").
+		ArrayField("members", "String", true, "").
+		GraphQLResolver("members", "resolveFriends").
+		Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestGenerateGraphQLResolvers(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGraphQLResolvers(resolverSchema(), &buf, ResolverOptions{}); err != nil {
+		test.Fatalf("cannot generate resolvers: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "resolvers.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated resolvers do not parse: %v\n%s", err, buf.String())
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("social", fset, []*ast.File{f}, nil); err != nil {
+		test.Fatalf("generated resolvers do not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		"\t\"User\": {\n\t\t\"friends\": resolveFriends,\n\t\t\"posts\":   resolveUserPosts,\n\t},\n",
+		"\t\"Group\": {\n\t\t\"members\": resolveFriends,\n\t},\n",
+		"func resolveUserPosts(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {",
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated resolvers are missing %q:\n%s", expected, src)
+		}
+	}
+	if n := strings.Count(src, "func resolveFriends("); n != 1 {
+		test.Errorf("expected a single resolveFriends stub, got %d", n)
+	}
+}
+
+func TestGenerateGraphQLResolversErrors(test *testing.T) {
+	for name, tb := range map[string]*rdl.StructTypeBuilder{
+		"no resolvers":  rdl.NewStructTypeBuilder("Struct", "User").Field("id", "UUID", false, nil, ""),
+		"unknown field": rdl.NewStructTypeBuilder("Struct", "User").GraphQLResolver("posts", "resolvePosts"),
+		"bad function":  rdl.NewStructTypeBuilder("Struct", "User").Field("id", "UUID", false, nil, "").GraphQLResolver("id", "resolve-id"),
+	} {
+		sb := rdl.NewSchemaBuilder("social")
+		sb.AddType(tb.Build())
+		var buf bytes.Buffer
+		if err := GenerateGraphQLResolvers(gentest.MustBuild(sb), &buf, ResolverOptions{}); err == nil {
+			test.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGenerateGraphQLInterfaces(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateGraphQL(resolverSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate graphql: %v", err)
+	}
+	if expected := "type User implements Node & Entity {\n"; !strings.Contains(buf.String(), expected) {
+		test.Errorf("generated SDL is missing %q:\n%s", expected, buf.String())
+	}
+}
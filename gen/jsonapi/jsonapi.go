@@ -0,0 +1,266 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package jsonapi exports RDL schemas in the shape of JSON:API
+// (https://jsonapi.org) documents, and generates Go middleware wrapping
+// responses in the JSON:API envelope.
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/iancoleman/orderedmap"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// JSONAPIOptions controls the JSON:API export.
+type JSONAPIOptions struct {
+	// Package is the package of the generated middleware, the schema name if
+	// empty.
+	Package string
+	// IDField is the struct field holding the id of resource objects, "id" if
+	// empty.
+	IDField string
+	// BasePath is the path the resources are served under, the root path of
+	// the schema if empty.
+	BasePath string
+}
+
+type resourceObject struct {
+	Type          string
+	ID            string
+	Attributes    *orderedmap.OrderedMap
+	Relationships []*relationship
+}
+
+type relationship struct {
+	Name string
+	Type string
+	Many bool
+}
+
+type endpoint struct {
+	Method     string
+	Path       string
+	Pattern    string
+	Type       string
+	Collection bool
+}
+
+type exporter struct {
+	registry  rdl.TypeRegistry
+	opts      JSONAPIOptions
+	objects   []*resourceObject
+	endpoints []*endpoint
+}
+
+// ExportJSONAPI writes the JSON:API description of the schema: every struct
+// type becomes a resource object, its fields referencing structs with an id
+// becoming relationships and the others attributes, and every resource
+// returning structs becomes an endpoint serving resource objects of that
+// type.
+func ExportJSONAPI(s *rdl.Schema, w io.Writer, opts JSONAPIOptions) error {
+	ex, err := newExporter(s, opts)
+	if err != nil {
+		return err
+	}
+	types := orderedmap.New()
+	for _, obj := range ex.objects {
+		def := orderedmap.New()
+		if obj.ID != "" {
+			def.Set("id", obj.ID)
+		}
+		def.Set("attributes", obj.Attributes)
+		if len(obj.Relationships) > 0 {
+			rels := orderedmap.New()
+			for _, rel := range obj.Relationships {
+				r := orderedmap.New()
+				r.Set("type", rel.Type)
+				r.Set("many", rel.Many)
+				rels.Set(rel.Name, r)
+			}
+			def.Set("relationships", rels)
+		}
+		types.Set(obj.Type, def)
+	}
+	var endpoints []*orderedmap.OrderedMap
+	for _, e := range ex.endpoints {
+		ep := orderedmap.New()
+		ep.Set("method", e.Method)
+		ep.Set("path", e.Path)
+		ep.Set("type", e.Type)
+		ep.Set("collection", e.Collection)
+		endpoints = append(endpoints, ep)
+	}
+	jsonapi := orderedmap.New()
+	jsonapi.Set("version", "1.0")
+	doc := orderedmap.New()
+	doc.Set("jsonapi", jsonapi)
+	doc.Set("types", types)
+	doc.Set("endpoints", endpoints)
+	j, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+func newExporter(s *rdl.Schema, opts JSONAPIOptions) (*exporter, error) {
+	if opts.IDField == "" {
+		opts.IDField = "id"
+	}
+	if opts.BasePath == "" {
+		opts.BasePath = utils.JavaGenerationRootPath(s)
+	}
+	ex := &exporter{registry: rdl.NewTypeRegistry(s), opts: opts}
+	for _, t := range s.Types {
+		if t.StructTypeDef == nil {
+			continue
+		}
+		obj, err := ex.resourceObject(t)
+		if err != nil {
+			return nil, err
+		}
+		ex.objects = append(ex.objects, obj)
+	}
+	if len(ex.objects) == 0 {
+		return nil, fmt.Errorf("schema %s has no struct types", s.Name)
+	}
+	for _, r := range s.Resources {
+		typ, many := ex.structType(r.Type, "")
+		if typ == nil {
+			continue
+		}
+		path := r.Path
+		if i := strings.Index(path, "?"); i >= 0 {
+			path = path[:i]
+		}
+		ex.endpoints = append(ex.endpoints, &endpoint{
+			Method:     strings.ToUpper(r.Method),
+			Path:       path,
+			Pattern:    pathPattern(strings.TrimSuffix(opts.BasePath, "/") + path),
+			Type:       objectType(typ),
+			Collection: many,
+		})
+	}
+	return ex, nil
+}
+
+func (ex *exporter) resourceObject(t *rdl.Type) (*resourceObject, error) {
+	obj := &resourceObject{Type: objectType(t), Attributes: orderedmap.New()}
+	for _, f := range utils.FlattenedFields(ex.registry, t) {
+		if string(f.Name) == ex.opts.IDField {
+			obj.ID = ex.opts.IDField
+			continue
+		}
+		if target, many := ex.structType(f.Type, f.Items); target != nil && ex.hasID(target) {
+			obj.Relationships = append(obj.Relationships, &relationship{Name: string(f.Name), Type: objectType(target), Many: many})
+			continue
+		}
+		attr, err := ex.attribute(f.Type, f.Items)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", t.StructTypeDef.Name, f.Name, err)
+		}
+		obj.Attributes.Set(string(f.Name), attr)
+	}
+	return obj, nil
+}
+
+// structType returns the user-defined struct type of a reference, or of the
+// items of an array reference, in which case many is true.
+func (ex *exporter) structType(ref rdl.TypeRef, items rdl.TypeRef) (*rdl.Type, bool) {
+	t := ex.registry.FindType(ref)
+	if t == nil {
+		return nil, false
+	}
+	many := false
+	if ex.registry.BaseType(t) == rdl.BaseTypeArray {
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if t = ex.registry.FindType(items); t == nil {
+			return nil, false
+		}
+		many = true
+	}
+	if t.StructTypeDef == nil || t.StructTypeDef.Name == "Struct" {
+		return nil, false
+	}
+	return t, many
+}
+
+func (ex *exporter) hasID(t *rdl.Type) bool {
+	for _, f := range utils.FlattenedFields(ex.registry, t) {
+		if string(f.Name) == ex.opts.IDField {
+			return true
+		}
+	}
+	return false
+}
+
+func (ex *exporter) attribute(ref rdl.TypeRef, items rdl.TypeRef) (*orderedmap.OrderedMap, error) {
+	attr := orderedmap.New()
+	t := ex.registry.FindType(ref)
+	if t == nil {
+		return nil, fmt.Errorf("unknown type: %s", ref)
+	}
+	switch ex.registry.BaseType(t) {
+	case rdl.BaseTypeBool:
+		attr.Set("type", "boolean")
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		attr.Set("type", "integer")
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		attr.Set("type", "number")
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeEnum:
+		attr.Set("type", "string")
+	case rdl.BaseTypeUUID:
+		attr.Set("type", "string")
+		attr.Set("format", "uuid")
+	case rdl.BaseTypeTimestamp:
+		attr.Set("type", "string")
+		attr.Set("format", "date-time")
+	case rdl.BaseTypeBytes:
+		attr.Set("type", "string")
+		attr.Set("format", "byte")
+	case rdl.BaseTypeArray:
+		attr.Set("type", "array")
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items != "" && items != "Any" {
+			itemAttr, err := ex.attribute(items, "")
+			if err != nil {
+				return nil, err
+			}
+			attr.Set("items", itemAttr)
+		}
+	default:
+		attr.Set("type", "object")
+	}
+	return attr, nil
+}
+
+// objectType returns the JSON:API type of the resource objects of a struct.
+func objectType(t *rdl.Type) string {
+	return utils.Uncapitalize(string(t.StructTypeDef.Name))
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// pathPattern returns the regular expression matching the path with any
+// value for its parameters.
+func pathPattern(path string) string {
+	var re string
+	last := 0
+	for _, loc := range pathParam.FindAllStringIndex(path, -1) {
+		re += regexp.QuoteMeta(path[last:loc[0]]) + "[^/]+"
+		last = loc[1]
+	}
+	return "^" + re + regexp.QuoteMeta(path[last:]) + "$"
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package jsonapi
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func blogSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("blog")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Person").
+		Field("id", "String", false, nil, "").
+		Field("name", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Location").
+		Field("lat", "Float64", false, nil, "").
+		Field("lon", "Float64", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Article").
+		Field("id", "Int64", false, nil, "").
+		Field("title", "String", false, nil, "").
+		Field("published", "Timestamp", true, nil, "").
+		ArrayField("tags", "String", true, "").
+		Field("author", "Person", false, nil, "").
+		ArrayField("reviewers", "Person", true, "").
+		Field("location", "Location", true, nil, "").
+		Build())
+	sb.AddType(rdl.NewArrayTypeBuilder("Array", "Articles").Items("Article").Build())
+	sb.AddResource(rdl.NewResourceBuilder("Article", "GET", "/articles/{id}").
+		Input("id", "Int64", true, "", "", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Articles", "GET", "/articles?tag={tag}").
+		Input("tag", "String", false, "tag", "", true, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/ping").Build())
+	return gentest.MustBuild(sb)
+}
+
+func TestExportJSONAPI(test *testing.T) {
+	var buf bytes.Buffer
+	err := ExportJSONAPI(blogSchema(), &buf, JSONAPIOptions{})
+	checkErrInTest(err, "cannot export JSON:API", test)
+
+	expected, err := ioutil.ReadFile("../../testdata/jsonapi/blog.json")
+	checkErrInTest(err, "cannot read JSON:API json file", test)
+
+	if buf.String() != string(expected) {
+		test.Errorf("JSON:API description not generated as expected, real: \n%s\n, expected: \n%s\n",
+			buf.String(), string(expected))
+	}
+}
+
+func TestGenerateJSONAPIMiddleware(test *testing.T) {
+	var buf bytes.Buffer
+	err := GenerateJSONAPIMiddleware(blogSchema(), &buf, JSONAPIOptions{BasePath: "/api"})
+	checkErrInTest(err, "cannot generate JSON:API middleware", test)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "jsonapi.go", buf.Bytes(), 0)
+	if err != nil {
+		test.Fatalf("generated middleware does not parse: %v\n%s", err, buf.String())
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("blog", fset, []*ast.File{f}, nil); err != nil {
+		test.Fatalf("generated middleware does not compile: %v\n%s", err, buf.String())
+	}
+	src := buf.String()
+	for _, expected := range []string{
+		`attributes: []string{"title", "published", "tags", "location"},`,
+		`"author":    "person",`,
+		`{method: "GET", path: regexp.MustCompile("^/api/articles/[^/]+$"), typ: "article"},`,
+		`{method: "GET", path: regexp.MustCompile("^/api/articles$"), typ: "article"},`,
+	} {
+		if !strings.Contains(src, expected) {
+			test.Errorf("generated middleware is missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+func checkErrInTest(err error, msg string, test *testing.T) {
+	if err != nil {
+		test.Error(msg, err)
+		os.Exit(1)
+	}
+}
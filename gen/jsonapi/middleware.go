@@ -0,0 +1,257 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package jsonapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"text/template"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// GenerateJSONAPIMiddleware generates JSONAPIMiddleware, a net/http
+// middleware turning the JSON responses of the endpoints described by
+// ExportJSONAPI into JSON:API documents: successful bodies become the
+// primary data, with the objects they relate to included, and error bodies
+// become error objects.
+func GenerateJSONAPIMiddleware(s *rdl.Schema, w io.Writer, opts JSONAPIOptions) error {
+	ex, err := newExporter(s, opts)
+	if err != nil {
+		return err
+	}
+	if len(ex.endpoints) == 0 {
+		return fmt.Errorf("schema %s has no resources returning structs", s.Name)
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = utils.GoGenerationPackage(s)
+	}
+	funcMap := template.FuncMap{
+		"header":    func() string { return utils.GoGenerationHeader("parsec-rdl-gen") },
+		"package":   func() string { return pkg },
+		"objects":   func() []*resourceObject { return ex.objects },
+		"endpoints": func() []*endpoint { return ex.endpoints },
+		"quote":     func(s string) string { return fmt.Sprintf("%q", s) },
+	}
+	t, err := template.New("middleware").Funcs(funcMap).Parse(middlewareTemplate)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const middlewareTemplate = `{{header}}
+
+package {{package}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+type jsonapiResource struct {
+	id            string
+	attributes    []string
+	relationships map[string]string
+}
+
+var jsonapiResources = map[string]*jsonapiResource{
+{{- range objects}}
+	{{quote .Type}}: {
+{{- if .ID}}
+		id: {{quote .ID}},
+{{- end}}
+		attributes: []string{ {{- range $i, $name := .Attributes.Keys}}{{if $i}}, {{end}}{{quote $name}}{{end}}},
+{{- if .Relationships}}
+		relationships: map[string]string{
+{{- range .Relationships}}
+			{{quote .Name}}: {{quote .Type}},
+{{- end}}
+		},
+{{- end}}
+	},
+{{- end}}
+}
+
+type jsonapiEndpoint struct {
+	method string
+	path   *regexp.Regexp
+	typ    string
+}
+
+var jsonapiEndpoints = []*jsonapiEndpoint{
+{{- range endpoints}}
+	{method: {{quote .Method}}, path: regexp.MustCompile({{quote .Pattern}}), typ: {{quote .Type}}},
+{{- end}}
+}
+
+// JSONAPIMiddleware wraps the JSON responses of the endpoints serving
+// resource objects in the JSON:API envelope. Other responses are left as is.
+func JSONAPIMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var typ string
+		for _, e := range jsonapiEndpoints {
+			if e.method == r.Method && e.path.MatchString(r.URL.Path) {
+				typ = e.typ
+				break
+			}
+		}
+		if typ == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &jsonapiRecorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		body, err := jsonapiDocument(typ, rec.status, rec.body.Bytes())
+		if err != nil {
+			//not a JSON body: pass it through
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+type jsonapiRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *jsonapiRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *jsonapiRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *jsonapiRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func jsonapiDocument(typ string, status int, body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	doc := make(map[string]interface{})
+	if status >= http.StatusBadRequest {
+		e := map[string]interface{}{"status": strconv.Itoa(status), "title": http.StatusText(status)}
+		if obj, ok := v.(map[string]interface{}); ok {
+			if message, ok := obj["message"].(string); ok {
+				e["detail"] = message
+			}
+		}
+		doc["errors"] = []interface{}{e}
+		return json.Marshal(doc)
+	}
+	inc := &jsonapiIncluded{seen: make(map[string]bool)}
+	if items, ok := v.([]interface{}); ok {
+		data := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			data = append(data, jsonapiObject(typ, item, inc))
+		}
+		doc["data"] = data
+	} else {
+		doc["data"] = jsonapiObject(typ, v, inc)
+	}
+	if len(inc.objects) > 0 {
+		doc["included"] = inc.objects
+	}
+	return json.Marshal(doc)
+}
+
+// jsonapiObject returns the resource object for a value of the type.
+func jsonapiObject(typ string, v interface{}, inc *jsonapiIncluded) interface{} {
+	value, ok := v.(map[string]interface{})
+	res := jsonapiResources[typ]
+	if !ok || res == nil {
+		return v
+	}
+	obj := map[string]interface{}{"type": typ}
+	if id, ok := value[res.id]; ok && res.id != "" {
+		obj["id"] = fmt.Sprint(id)
+	}
+	attributes := make(map[string]interface{})
+	for _, name := range res.attributes {
+		if attr, ok := value[name]; ok {
+			attributes[name] = attr
+		}
+	}
+	if len(attributes) > 0 {
+		obj["attributes"] = attributes
+	}
+	relationships := make(map[string]interface{})
+	for name, rtyp := range res.relationships {
+		rel, ok := value[name]
+		if !ok {
+			continue
+		}
+		if items, ok := rel.([]interface{}); ok {
+			data := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				data = append(data, inc.link(rtyp, item))
+			}
+			relationships[name] = map[string]interface{}{"data": data}
+		} else {
+			relationships[name] = map[string]interface{}{"data": inc.link(rtyp, rel)}
+		}
+	}
+	if len(relationships) > 0 {
+		obj["relationships"] = relationships
+	}
+	return obj
+}
+
+// jsonapiIncluded collects the related objects of a document.
+type jsonapiIncluded struct {
+	seen    map[string]bool
+	objects []interface{}
+}
+
+// link returns the resource identifier of a related value, including the
+// value in the document when it holds more than its id.
+func (inc *jsonapiIncluded) link(typ string, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	obj, ok := jsonapiObject(typ, v, inc).(map[string]interface{})
+	if !ok || obj["id"] == nil {
+		return nil
+	}
+	key := typ + "/" + obj["id"].(string)
+	if len(obj) > 2 && !inc.seen[key] {
+		inc.seen[key] = true
+		inc.objects = append(inc.objects, obj)
+	}
+	return map[string]interface{}{"type": typ, "id": obj["id"]}
+}
+`
@@ -0,0 +1,222 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package jsonschema exports the types of RDL schemas as JSON Schemas.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const draft07 = "http://json-schema.org/draft-07/schema#"
+
+type object map[string]interface{}
+
+// GenerateJSONSchema writes the types of the schema as a JSON Schema draft-07
+// document, with every type of the schema in $defs and references to them as
+// $ref. String types become strings with their pattern, length and values,
+// number types numbers or integers with their minimum and maximum, structs
+// objects with their fields, inherited ones included, as properties, enums
+// strings with the elements as values, unions a oneOf of their variants, and
+// arrays and maps arrays and objects with items and additionalProperties.
+// Number types holding monetary amounts are objects of format money, with
+// the decimal amount and the currency code as strings.
+//
+// $defs is not a draft-07 keyword, but draft-07 validators resolve $refs to it
+// as to any other location of the document, and it is the keyword of later
+// drafts.
+func GenerateJSONSchema(s *rdl.Schema, w io.Writer) error {
+	registry := rdl.NewTypeRegistry(s)
+	defs := object{}
+	for _, t := range s.Types {
+		tName, _, _ := rdl.TypeInfo(t)
+		defs[string(tName)] = typeSchema(registry, t)
+	}
+	doc := object{"$schema": draft07, "$defs": defs}
+	if s.Name != "" {
+		doc["title"] = string(s.Name)
+	}
+	if s.Comment != "" {
+		doc["description"] = s.Comment
+	}
+	j, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+// typeSchema returns the definition of a type of the schema.
+func typeSchema(registry rdl.TypeRegistry, t *rdl.Type) object {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	var def object
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		def = object{"type": "object"}
+		properties := object{}
+		var required []string
+		for _, f := range utils.FlattenedFields(registry, t) {
+			property := refSchema(registry, f.Type, f.Items, f.Keys)
+			if f.Comment != "" {
+				property = with(property, "description", f.Comment)
+			}
+			if f.Default != nil {
+				property = with(property, "default", f.Default)
+			}
+			properties[string(f.Name)] = property
+			if !f.Optional {
+				required = append(required, string(f.Name))
+			}
+		}
+		def["properties"] = properties
+		if len(required) > 0 {
+			def["required"] = required
+		}
+		if t.StructTypeDef.Closed {
+			def["additionalProperties"] = false
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		var values []string
+		for _, e := range t.EnumTypeDef.Elements {
+			values = append(values, string(e.Symbol))
+		}
+		def = object{"type": "string", "enum": values}
+	case rdl.TypeVariantUnionTypeDef:
+		var variants []object
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, refSchema(registry, v, "", ""))
+		}
+		def = object{"oneOf": variants}
+	case rdl.TypeVariantArrayTypeDef:
+		at := t.ArrayTypeDef
+		def = refSchema(registry, "Array", at.Items, "")
+		if at.MinSize != nil {
+			def["minItems"] = *at.MinSize
+		}
+		if at.MaxSize != nil {
+			def["maxItems"] = *at.MaxSize
+		}
+	case rdl.TypeVariantMapTypeDef:
+		mt := t.MapTypeDef
+		def = refSchema(registry, "Map", mt.Items, mt.Keys)
+		if mt.MinSize != nil {
+			def["minProperties"] = *mt.MinSize
+		}
+		if mt.MaxSize != nil {
+			def["maxProperties"] = *mt.MaxSize
+		}
+	case rdl.TypeVariantStringTypeDef:
+		st := t.StringTypeDef
+		def = extensible(refSchema(registry, tType, "", ""))
+		if st.Pattern != "" {
+			def["pattern"] = st.Pattern
+		}
+		if len(st.Values) > 0 {
+			def["enum"] = st.Values
+		}
+		if st.MinSize != nil {
+			def["minLength"] = *st.MinSize
+		}
+		if st.MaxSize != nil {
+			def["maxLength"] = *st.MaxSize
+		}
+	case rdl.TypeVariantNumberTypeDef:
+		nt := t.NumberTypeDef
+		if utils.IsCurrency(registry, rdl.TypeRef(tName)) {
+			def = moneySchema()
+			break
+		}
+		def = extensible(refSchema(registry, tType, "", ""))
+		if nt.Min != nil {
+			def["minimum"] = json.RawMessage(utils.NumberString(nt.Min))
+		}
+		if nt.Max != nil {
+			def["maximum"] = json.RawMessage(utils.NumberString(nt.Max))
+		}
+	default:
+		def = refSchema(registry, tType, "", "")
+	}
+	if tComment != "" {
+		def = with(def, "description", tComment)
+	}
+	return def
+}
+
+// refSchema returns the schema of a reference to an RDL type: a $ref to the
+// types of the schema, and the JSON Schema equivalent of the base types.
+func refSchema(registry rdl.TypeRegistry, ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) object {
+	switch ref {
+	case "Array":
+		if items == "" || items == "Any" {
+			return object{"type": "array"}
+		}
+		return object{"type": "array", "items": refSchema(registry, items, "", "")}
+	case "Map":
+		schema := object{"type": "object"}
+		if items != "" && items != "Any" {
+			schema["additionalProperties"] = refSchema(registry, items, "", "")
+		}
+		if keys != "" && keys != "String" {
+			schema["propertyNames"] = refSchema(registry, keys, "", "")
+		}
+		return schema
+	case "Struct":
+		return object{"type": "object"}
+	case "Any":
+		return object{}
+	}
+	if !registry.IsBaseTypeName(ref) {
+		return object{"$ref": "#/$defs/" + string(ref)}
+	}
+	switch registry.FindBaseType(ref) {
+	case rdl.BaseTypeBool:
+		return object{"type": "boolean"}
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		return object{"type": "integer"}
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		return object{"type": "number"}
+	case rdl.BaseTypeUUID:
+		return object{"type": "string", "format": "uuid"}
+	case rdl.BaseTypeTimestamp:
+		return object{"type": "string", "format": "date-time"}
+	case rdl.BaseTypeBytes:
+		return object{"type": "string", "contentEncoding": "base64"}
+	default:
+		return object{"type": "string"}
+	}
+}
+
+// moneySchema returns the schema of a monetary amount.
+func moneySchema() object {
+	return object{
+		"type":   "object",
+		"format": "money",
+		"properties": object{
+			"amount":   object{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?$`},
+			"currency": object{"type": "string", "pattern": "^[A-Z]{3}$"},
+		},
+		"required": []string{"amount", "currency"},
+	}
+}
+
+// with returns the schema with the keyword set, extending it if it is a $ref
+// as the other keywords of a schema with a $ref are ignored in draft-07.
+func with(schema object, keyword string, value interface{}) object {
+	schema = extensible(schema)
+	schema[keyword] = value
+	return schema
+}
+
+// extensible returns the schema, or a schema extending it if it is a $ref.
+func extensible(schema object) object {
+	if _, ok := schema["$ref"]; ok {
+		return object{"allOf": []object{schema}}
+	}
+	return schema
+}
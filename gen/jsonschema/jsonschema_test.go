@@ -0,0 +1,51 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateJSONSchema(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateJSONSchema(gentest.SampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate JSON Schema: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/jsonschema/sample.json")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("JSON Schema not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateJSONSchemaCurrency(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewNumberTypeBuilder("Int64", "Price").Currency(true).Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Price", "SalePrice").Build())
+	var buf bytes.Buffer
+	if err := GenerateJSONSchema(gentest.MustBuild(sb), &buf); err != nil {
+		test.Fatalf("cannot generate JSON Schema: %v", err)
+	}
+	var doc struct {
+		Defs map[string]map[string]interface{} `json:"$defs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		test.Fatal(err)
+	}
+	for _, name := range []string{"Price", "SalePrice"} {
+		def := doc.Defs[name]
+		if def["type"] != "object" || def["format"] != "money" || !reflect.DeepEqual(def["required"], []interface{}{"amount", "currency"}) {
+			test.Errorf("%s is not a monetary amount: %v", name, def)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package markdown documents RDL schemas in Markdown.
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+type mdWriter struct {
+	buf bytes.Buffer
+}
+
+// GenerateMarkdown writes the documentation of the schema as a Markdown
+// document, with a section per type and per resource. Type sections list the
+// fields of structs, the elements of enums and the variants of unions in a
+// table, and the constraints of the other types; resource sections list
+// their method, path, inputs, outputs, authorization and exceptions.
+func GenerateMarkdown(s *rdl.Schema, w io.Writer) error {
+	mw := &mdWriter{}
+	fmt.Fprintf(&mw.buf, "<!-- This file generated by %s -->\n\n", banner)
+	name := string(s.Name)
+	if s.Namespace != "" {
+		name = string(s.Namespace) + "." + name
+	}
+	fmt.Fprintf(&mw.buf, "# %s\n", name)
+	if s.Comment != "" {
+		fmt.Fprintf(&mw.buf, "\n%s\n", strings.TrimSpace(s.Comment))
+	}
+	if s.Version != nil {
+		fmt.Fprintf(&mw.buf, "\nVersion %d\n", *s.Version)
+	}
+	for _, t := range s.Types {
+		mw.typeSection(t)
+	}
+	for _, r := range s.Resources {
+		mw.resourceSection(r)
+	}
+	_, err := w.Write(mw.buf.Bytes())
+	return err
+}
+
+// table writes a Markdown table, escaping the cells.
+func (mw *mdWriter) table(header []string, rows [][]string) {
+	mw.buf.WriteString("\n| " + strings.Join(header, " | ") + " |\n|")
+	for range header {
+		mw.buf.WriteString(" --- |")
+	}
+	mw.buf.WriteString("\n")
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = strings.Replace(strings.Replace(strings.TrimSpace(cell), "|", "\\|", -1), "\n", " ", -1)
+		}
+		mw.buf.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+}
+
+func (mw *mdWriter) property(name string, value string) {
+	fmt.Fprintf(&mw.buf, "- %s: %s\n", name, value)
+}
+
+func (mw *mdWriter) typeSection(t *rdl.Type) {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	fmt.Fprintf(&mw.buf, "\n## %s\n", tName)
+	if tComment != "" {
+		fmt.Fprintf(&mw.buf, "\n%s\n", strings.TrimSpace(tComment))
+	}
+	mw.buf.WriteString("\n")
+	mw.property("Type", code(string(tType)))
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		st := t.StructTypeDef
+		if st.Closed {
+			mw.property("Closed", "yes")
+		}
+		var rows [][]string
+		for _, f := range st.Fields {
+			rows = append(rows, []string{string(f.Name), code(typeExpr(f.Type, f.Items, f.Keys)), yesNo(f.Optional), value(f.Default), f.Comment})
+		}
+		if len(rows) > 0 {
+			mw.table([]string{"Field", "Type", "Optional", "Default", "Description"}, rows)
+		}
+		if len(st.ChangeLog) > 0 {
+			mw.buf.WriteString("\n### Changes\n")
+			rows = nil
+			for _, c := range st.ChangeLog {
+				rows = append(rows, []string{fmt.Sprint(c.Version), c.FieldName, c.Change, c.Note})
+			}
+			mw.table([]string{"Version", "Field", "Change", "Note"}, rows)
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		var rows [][]string
+		for _, e := range t.EnumTypeDef.Elements {
+			rows = append(rows, []string{code(string(e.Symbol)), e.Comment})
+		}
+		mw.table([]string{"Element", "Description"}, rows)
+	case rdl.TypeVariantUnionTypeDef:
+		ut := t.UnionTypeDef
+		comments := make(map[rdl.TypeRef]string)
+		for _, v := range ut.VariantsAnnotated {
+			comments[v.Type] = v.Comment
+		}
+		var rows [][]string
+		for _, v := range ut.Variants {
+			rows = append(rows, []string{code(string(v)), comments[v]})
+		}
+		mw.table([]string{"Variant", "Description"}, rows)
+	case rdl.TypeVariantArrayTypeDef:
+		at := t.ArrayTypeDef
+		mw.property("Items", code(string(at.Items)))
+		if at.MinSize != nil {
+			mw.property("Min size", fmt.Sprint(*at.MinSize))
+		}
+		if at.MaxSize != nil {
+			mw.property("Max size", fmt.Sprint(*at.MaxSize))
+		}
+	case rdl.TypeVariantMapTypeDef:
+		mt := t.MapTypeDef
+		mw.property("Keys", code(string(mt.Keys)))
+		mw.property("Items", code(string(mt.Items)))
+		if mt.MinSize != nil {
+			mw.property("Min size", fmt.Sprint(*mt.MinSize))
+		}
+		if mt.MaxSize != nil {
+			mw.property("Max size", fmt.Sprint(*mt.MaxSize))
+		}
+	case rdl.TypeVariantStringTypeDef:
+		st := t.StringTypeDef
+		if st.Pattern != "" {
+			mw.property("Pattern", code(st.Pattern))
+		}
+		if len(st.Values) > 0 {
+			var values []string
+			for _, v := range st.Values {
+				values = append(values, code(v))
+			}
+			mw.property("Values", strings.Join(values, ", "))
+		}
+		if st.MinSize != nil {
+			mw.property("Min size", fmt.Sprint(*st.MinSize))
+		}
+		if st.MaxSize != nil {
+			mw.property("Max size", fmt.Sprint(*st.MaxSize))
+		}
+	case rdl.TypeVariantNumberTypeDef:
+		nt := t.NumberTypeDef
+		if nt.Min != nil {
+			mw.property("Minimum", utils.NumberString(nt.Min))
+		}
+		if nt.Max != nil {
+			mw.property("Maximum", utils.NumberString(nt.Max))
+		}
+	}
+}
+
+func (mw *mdWriter) resourceSection(r *rdl.Resource) {
+	fmt.Fprintf(&mw.buf, "\n## %s %s\n", strings.ToUpper(r.Method), r.Path)
+	if r.Comment != "" {
+		fmt.Fprintf(&mw.buf, "\n%s\n", strings.TrimSpace(r.Comment))
+	}
+	mw.buf.WriteString("\n")
+	if r.Name != "" {
+		mw.property("Name", code(string(r.Name)))
+	}
+	mw.property("Method", code(strings.ToUpper(r.Method)))
+	mw.property("Path", code(r.Path))
+	mw.property("Response", fmt.Sprintf("%s (%s %s)", code(string(r.Type)), rdl.StatusCode(r.Expected), r.Expected))
+	for _, alt := range r.Alternatives {
+		mw.property("Alternative response", fmt.Sprintf("%s %s", rdl.StatusCode(alt), alt))
+	}
+	if r.Auth != nil {
+		mw.property("Authorization", authorization(r.Auth))
+	}
+	if len(r.Inputs) > 0 {
+		mw.buf.WriteString("\n### Inputs\n")
+		var rows [][]string
+		for _, in := range r.Inputs {
+			rows = append(rows, []string{string(in.Name), code(string(in.Type)), location(in), yesNo(in.Optional), value(in.Default), in.Comment})
+		}
+		mw.table([]string{"Input", "Type", "In", "Optional", "Default", "Description"}, rows)
+	}
+	if len(r.Outputs) > 0 {
+		mw.buf.WriteString("\n### Outputs\n")
+		var rows [][]string
+		for _, out := range r.Outputs {
+			rows = append(rows, []string{string(out.Name), code(string(out.Type)), code(out.Header), yesNo(out.Optional), out.Comment})
+		}
+		mw.table([]string{"Output", "Type", "Header", "Optional", "Description"}, rows)
+	}
+	if len(r.Exceptions) > 0 {
+		mw.buf.WriteString("\n### Exceptions\n")
+		var syms []string
+		for sym := range r.Exceptions {
+			syms = append(syms, sym)
+		}
+		sort.Slice(syms, func(i, j int) bool { return rdl.StatusCode(syms[i]) < rdl.StatusCode(syms[j]) })
+		var rows [][]string
+		for _, sym := range syms {
+			ex := r.Exceptions[sym]
+			rows = append(rows, []string{fmt.Sprintf("%s %s", rdl.StatusCode(sym), sym), code(ex.Type), ex.Comment})
+		}
+		mw.table([]string{"Status", "Type", "Description"}, rows)
+	}
+}
+
+// authorization describes the authorization of a resource: the action on
+// the resource it requires, or only authentication.
+func authorization(auth *rdl.ResourceAuth) string {
+	if auth.Action == "" {
+		if auth.Authenticate {
+			return "authentication required"
+		}
+		return "none"
+	}
+	resource := auth.Resource
+	if auth.Domain != "" {
+		resource = auth.Domain + ":" + resource
+	}
+	return fmt.Sprintf("action %s on resource %s", code(auth.Action), code(resource))
+}
+
+// location tells where an input is in the request.
+func location(in *rdl.ResourceInput) string {
+	switch {
+	case in.PathParam:
+		return "path"
+	case in.QueryParam != "":
+		return "query " + code(in.QueryParam)
+	case in.Header != "":
+		return "header " + code(in.Header)
+	case in.Context != "":
+		return "context " + code(in.Context)
+	}
+	return "body"
+}
+
+func typeExpr(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch {
+	case ref == "Array" && items != "":
+		return fmt.Sprintf("Array<%s>", items)
+	case ref == "Map" && items != "":
+		if keys == "" {
+			keys = "String"
+		}
+		return fmt.Sprintf("Map<%s,%s>", keys, items)
+	}
+	return string(ref)
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// value returns the JSON of a default value, if any.
+func value(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return code(string(j))
+}
@@ -0,0 +1,546 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package openapi exports RDL schemas as OpenAPI 3.0 documents, in YAML.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// securityScheme is the name of the scheme resources with an auth block are
+// secured by.
+const securityScheme = "rdl_auth"
+
+type openAPIWriter struct {
+	schema *rdl.Schema
+	buf    bytes.Buffer
+}
+
+// GenerateOpenAPI writes the schema as an OpenAPI 3.0 document: types become
+// component schemas and resources become operations of the path items. Struct
+// fields are object properties, listed in required unless optional, enums
+// are string enums and unions are oneOf the schemas of their variants. Types
+// derived from a type other than a base type are allOf its schema, with their
+// own constraints, except closed structs, which are one object with all
+// their fields. Resources with an auth block are secured by the rdl_auth security scheme,
+// with the action and the resource authorized, if any, in an x-rdl-auth
+// extension. Resources taking an API key are secured by an apiKey security
+// scheme, named after the key unless the API key names its scheme.
+//
+// Resources streaming server-sent events respond with a text/event-stream
+// whose schema is oneOf their events, each an object with the name of the
+// event, which discriminates them, and its data.
+//
+// Structs with an OpenAPI schema override get this schema, as is, instead of
+// the translated one.
+func GenerateOpenAPI(s *rdl.Schema, w io.Writer) error {
+	ow := &openAPIWriter{schema: s}
+	ow.line(0, "openapi: 3.0.3")
+	ow.line(0, "info:")
+	ow.line(1, "title: %s", utils.QuoteYAML(string(s.Name)))
+	if s.Comment != "" {
+		ow.line(1, "description: %s", utils.QuoteYAML(s.Comment))
+	}
+	if s.Version != nil {
+		ow.line(1, "version: %s", utils.QuoteYAML(fmt.Sprint(*s.Version)))
+	} else {
+		ow.line(1, "version: \"0\"")
+	}
+	ow.line(0, "servers:")
+	ow.line(1, "- url: %s", utils.QuoteYAML(utils.JavaGenerationRootPath(s)))
+	if err := ow.paths(); err != nil {
+		return err
+	}
+	if err := ow.components(); err != nil {
+		return err
+	}
+	_, err := w.Write(ow.buf.Bytes())
+	return err
+}
+
+func (ow *openAPIWriter) line(indent int, format string, args ...interface{}) {
+	ow.buf.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&ow.buf, format, args...)
+	ow.buf.WriteString("\n")
+}
+
+func (ow *openAPIWriter) paths() error {
+	var paths []string
+	byPath := make(map[string][]*rdl.Resource)
+	for _, r := range ow.schema.Resources {
+		path := r.Path
+		if i := strings.Index(path, "?"); i >= 0 {
+			path = path[:i]
+		}
+		if _, ok := byPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		byPath[path] = append(byPath[path], r)
+	}
+	if len(paths) == 0 {
+		ow.line(0, "paths: {}")
+		return nil
+	}
+	ow.line(0, "paths:")
+	for _, path := range paths {
+		ow.line(1, "%s:", utils.QuoteYAML(path))
+		methods := make(map[string]bool)
+		for _, r := range byPath[path] {
+			method := strings.ToLower(r.Method)
+			if methods[method] {
+				return fmt.Errorf("duplicate operation %s %s", r.Method, path)
+			}
+			methods[method] = true
+			if err := ow.operation(r); err != nil {
+				return fmt.Errorf("%s %s: %v", r.Method, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (ow *openAPIWriter) operation(r *rdl.Resource) error {
+	ow.line(2, "%s:", strings.ToLower(r.Method))
+	ow.line(3, "operationId: %s", operationID(r))
+	if r.Comment != "" {
+		ow.line(3, "description: %s", utils.QuoteYAML(r.Comment))
+	}
+	var schemes []string
+	if r.Auth != nil {
+		schemes = append(schemes, securityScheme)
+	}
+	if r.APIKeyAuth != nil {
+		if r.APIKeyAuth.In != "header" && r.APIKeyAuth.In != "query" {
+			return fmt.Errorf("API key in %q, expected \"header\" or \"query\"", r.APIKeyAuth.In)
+		}
+		if r.APIKeyAuth.Name == "" {
+			return fmt.Errorf("API key without a name")
+		}
+		schemes = append(schemes, utils.QuoteYAML(apiKeyScheme(r.APIKeyAuth)))
+	}
+	if len(schemes) > 0 {
+		ow.line(3, "security:")
+		for i, scheme := range schemes {
+			prefix := "- "
+			if i > 0 {
+				prefix = "  "
+			}
+			ow.line(4, "%s%s: []", prefix, scheme)
+		}
+	}
+	if r.Auth != nil {
+		if r.Auth.Action != "" {
+			ow.line(3, "x-rdl-auth:")
+			ow.line(4, "action: %s", utils.QuoteYAML(r.Auth.Action))
+			ow.line(4, "resource: %s", utils.QuoteYAML(r.Auth.Resource))
+		}
+	}
+	var params []*rdl.ResourceInput
+	var body *rdl.ResourceInput
+	for _, in := range r.Inputs {
+		switch {
+		case in.PathParam, in.QueryParam != "", in.Header != "":
+			params = append(params, in)
+		case in.Context == "":
+			body = in
+		}
+	}
+	if len(params) > 0 {
+		ow.line(3, "parameters:")
+		for _, in := range params {
+			ow.parameter(in)
+		}
+	}
+	if body != nil {
+		ow.line(3, "requestBody:")
+		if body.Comment != "" {
+			ow.line(4, "description: %s", utils.QuoteYAML(body.Comment))
+		}
+		ow.line(4, "required: %v", !body.Optional)
+		ow.line(4, "content:")
+		ow.line(5, "application/json:")
+		ow.line(6, "schema:")
+		ow.schemaRef(7, body.Type, "", "")
+	}
+	for _, ev := range r.SSEEvents {
+		if ev.EventName == "" || ev.PayloadType == "" {
+			return fmt.Errorf("server-sent event without a name or a payload type")
+		}
+	}
+	ow.line(3, "responses:")
+	ow.response(r.Expected, r.Type, "", r.SSEEvents)
+	for _, alt := range r.Alternatives {
+		ow.response(alt, r.Type, "", nil)
+	}
+	var syms []string
+	for sym := range r.Exceptions {
+		syms = append(syms, sym)
+	}
+	sort.Strings(syms)
+	for _, sym := range syms {
+		e := r.Exceptions[sym]
+		ow.response(sym, rdl.TypeRef(e.Type), e.Comment, nil)
+	}
+	return nil
+}
+
+// apiKeyScheme returns the name of the security scheme of an API key.
+func apiKeyScheme(key *rdl.APIKeyDef) string {
+	if key.Scheme != "" {
+		return key.Scheme
+	}
+	return key.Name
+}
+
+// operationID returns the name of the resource, or its method followed by
+// the type of its body.
+func operationID(r *rdl.Resource) string {
+	if r.Name != "" {
+		return string(r.Name)
+	}
+	bodyType := r.Type
+	for _, in := range r.Inputs {
+		if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+			bodyType = in.Type
+		}
+	}
+	return strings.ToLower(r.Method) + utils.Capitalize(strings.Replace(string(bodyType), ".", "", -1))
+}
+
+func (ow *openAPIWriter) parameter(in *rdl.ResourceInput) {
+	name, where := string(in.Name), "path"
+	switch {
+	case in.QueryParam != "":
+		name, where = in.QueryParam, "query"
+	case in.Header != "":
+		name, where = in.Header, "header"
+	}
+	ow.line(4, "- name: %s", utils.QuoteYAML(name))
+	ow.line(5, "in: %s", where)
+	if in.Comment != "" {
+		ow.line(5, "description: %s", utils.QuoteYAML(in.Comment))
+	}
+	if in.PathParam || !in.Optional && in.Default == nil {
+		ow.line(5, "required: true")
+	}
+	ow.line(5, "schema:")
+	ow.schemaRef(6, in.Type, "", "")
+	if in.Default != nil {
+		if d, err := json.Marshal(in.Default); err == nil {
+			ow.line(6, "default: %s", d)
+		}
+	}
+}
+
+// response writes a response, a stream of the server-sent events if any.
+func (ow *openAPIWriter) response(sym string, t rdl.TypeRef, comment string, events []*rdl.SSEEventDef) {
+	code := rdl.StatusCode(sym)
+	ow.line(4, "%s:", utils.QuoteYAML(code))
+	if comment == "" {
+		comment = sym
+	}
+	ow.line(5, "description: %s", utils.QuoteYAML(comment))
+	if len(events) > 0 {
+		ow.line(5, "content:")
+		ow.line(6, "text/event-stream:")
+		ow.line(7, "schema:")
+		ow.line(8, "oneOf:")
+		for _, ev := range events {
+			ow.line(9, "- type: object")
+			ow.line(10, "required: [\"event\", \"data\"]")
+			ow.line(10, "properties:")
+			ow.line(11, "event:")
+			ow.line(12, "type: string")
+			ow.line(12, "enum: [%s]", utils.QuoteYAML(ev.EventName))
+			ow.line(11, "data:")
+			ow.schemaRef(12, ev.PayloadType, "", "")
+		}
+		ow.line(8, "discriminator:")
+		ow.line(9, "propertyName: event")
+		return
+	}
+	if code == "204" || code == "304" || t == "" {
+		return
+	}
+	ow.line(5, "content:")
+	ow.line(6, "application/json:")
+	ow.line(7, "schema:")
+	ow.schemaRef(8, t, "", "")
+}
+
+func (ow *openAPIWriter) components() error {
+	hasAuth := false
+	var schemes []string
+	keys := make(map[string]*rdl.APIKeyDef)
+	for _, r := range ow.schema.Resources {
+		if r.Auth != nil {
+			hasAuth = true
+		}
+		if key := r.APIKeyAuth; key != nil {
+			scheme := apiKeyScheme(key)
+			if scheme == securityScheme {
+				return fmt.Errorf("API key scheme %s is reserved for auth blocks", scheme)
+			}
+			if other, ok := keys[scheme]; ok {
+				if other.In != key.In || other.Name != key.Name {
+					return fmt.Errorf("API key scheme %s is defined twice", scheme)
+				}
+				continue
+			}
+			keys[scheme] = key
+			schemes = append(schemes, scheme)
+		}
+	}
+	if len(ow.schema.Types) == 0 && !hasAuth && len(schemes) == 0 {
+		return nil
+	}
+	ow.line(0, "components:")
+	if len(ow.schema.Types) > 0 {
+		ow.line(1, "schemas:")
+		for _, t := range ow.schema.Types {
+			if err := ow.typeSchema(t); err != nil {
+				return err
+			}
+		}
+	}
+	if hasAuth || len(schemes) > 0 {
+		ow.line(1, "securitySchemes:")
+	}
+	if hasAuth {
+		ow.line(2, "%s:", securityScheme)
+		ow.line(3, "type: http")
+		ow.line(3, "scheme: bearer")
+		ow.line(3, "description: Authentication, and authorization of the action on the resource if given")
+	}
+	sort.Strings(schemes)
+	for _, scheme := range schemes {
+		ow.line(2, "%s:", utils.QuoteYAML(scheme))
+		ow.line(3, "type: apiKey")
+		ow.line(3, "in: %s", keys[scheme].In)
+		ow.line(3, "name: %s", utils.QuoteYAML(keys[scheme].Name))
+	}
+	return nil
+}
+
+// typeSchema writes the component schema of a type, the override of structs
+// having one as a JSON flow mapping.
+func (ow *openAPIWriter) typeSchema(t *rdl.Type) error {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	if t.StructTypeDef != nil && t.StructTypeDef.OpenAPISchemaOverride != nil {
+		override, err := json.Marshal(t.StructTypeDef.OpenAPISchemaOverride)
+		if err != nil {
+			return fmt.Errorf("%s: invalid OpenAPI schema override: %v", tName, err)
+		}
+		ow.line(2, "%s: %s", tName, override)
+		return nil
+	}
+	ow.line(2, "%s:", tName)
+	description := func(indent int) {
+		if tComment != "" {
+			ow.line(indent, "description: %s", utils.QuoteYAML(tComment))
+		}
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		st := t.StructTypeDef
+		fields := st.Fields
+		if st.Closed {
+			// A closed struct has all its fields in one schema, as
+			// additionalProperties in an allOf member would reject the
+			// inherited ones.
+			fields = utils.FlattenedFields(rdl.NewTypeRegistry(ow.schema), t)
+		}
+		indent := 3
+		if tType != "Struct" && !st.Closed {
+			description(3)
+			ow.line(3, "allOf:")
+			ow.line(4, "- $ref: %s", utils.QuoteYAML(componentRef(tType)))
+			ow.line(4, "- type: object")
+			indent = 5
+		} else {
+			ow.line(3, "type: object")
+			description(3)
+		}
+		var required []string
+		for _, f := range fields {
+			if !f.Optional {
+				required = append(required, utils.QuoteYAML(string(f.Name)))
+			}
+		}
+		if len(required) > 0 {
+			ow.line(indent, "required: [%s]", strings.Join(required, ", "))
+		}
+		if len(fields) > 0 {
+			ow.line(indent, "properties:")
+			for _, f := range fields {
+				ow.line(indent+1, "%s:", f.Name)
+				ow.schemaRef(indent+2, f.Type, f.Items, f.Keys)
+				if f.Comment != "" {
+					ow.line(indent+2, "description: %s", utils.QuoteYAML(f.Comment))
+				}
+				if f.Default != nil {
+					if d, err := json.Marshal(f.Default); err == nil {
+						ow.line(indent+2, "default: %s", d)
+					}
+				}
+			}
+		}
+		if st.Closed {
+			ow.line(indent, "additionalProperties: false")
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		ow.line(3, "type: string")
+		description(3)
+		var symbols []string
+		for _, e := range t.EnumTypeDef.Elements {
+			symbols = append(symbols, utils.QuoteYAML(string(e.Symbol)))
+		}
+		ow.line(3, "enum: [%s]", strings.Join(symbols, ", "))
+	case rdl.TypeVariantUnionTypeDef:
+		description(3)
+		ow.line(3, "oneOf:")
+		for _, v := range t.UnionTypeDef.Variants {
+			ow.line(4, "- $ref: %s", utils.QuoteYAML(componentRef(v)))
+		}
+	case rdl.TypeVariantArrayTypeDef:
+		ow.line(3, "type: array")
+		description(3)
+		ow.line(3, "items:")
+		ow.schemaRef(4, t.ArrayTypeDef.Items, "", "")
+		ow.sizes(t.ArrayTypeDef.MinSize, t.ArrayTypeDef.MaxSize, "minItems", "maxItems")
+	case rdl.TypeVariantMapTypeDef:
+		ow.line(3, "type: object")
+		description(3)
+		ow.line(3, "additionalProperties:")
+		ow.schemaRef(4, t.MapTypeDef.Items, "", "")
+		ow.sizes(t.MapTypeDef.MinSize, t.MapTypeDef.MaxSize, "minProperties", "maxProperties")
+	case rdl.TypeVariantStringTypeDef:
+		st := t.StringTypeDef
+		ow.superSchema(tType, description)
+		if st.Pattern != "" {
+			ow.line(3, "pattern: %s", utils.QuoteYAML(st.Pattern))
+		}
+		if len(st.Values) > 0 {
+			var values []string
+			for _, v := range st.Values {
+				values = append(values, utils.QuoteYAML(v))
+			}
+			ow.line(3, "enum: [%s]", strings.Join(values, ", "))
+		}
+		ow.sizes(st.MinSize, st.MaxSize, "minLength", "maxLength")
+	case rdl.TypeVariantNumberTypeDef:
+		ow.superSchema(tType, description)
+		if t.NumberTypeDef.Min != nil {
+			ow.line(3, "minimum: %s", utils.NumberString(t.NumberTypeDef.Min))
+		}
+		if t.NumberTypeDef.Max != nil {
+			ow.line(3, "maximum: %s", utils.NumberString(t.NumberTypeDef.Max))
+		}
+	case rdl.TypeVariantBytesTypeDef:
+		ow.line(3, "type: string")
+		ow.line(3, "format: byte")
+		description(3)
+	default:
+		ow.superSchema(tType, description)
+	}
+	return nil
+}
+
+// superSchema writes the schema of the super type of a component, the schema
+// of a base type or else allOf the component schema of the super type, and
+// its description.
+func (ow *openAPIWriter) superSchema(super rdl.TypeRef, description func(indent int)) {
+	if rdl.IsBaseType(string(super)) {
+		ow.baseSchema(3, super)
+		description(3)
+		return
+	}
+	description(3)
+	ow.line(3, "allOf:")
+	ow.line(4, "- $ref: %s", utils.QuoteYAML(componentRef(super)))
+}
+
+func (ow *openAPIWriter) sizes(min *int32, max *int32, minName string, maxName string) {
+	if min != nil {
+		ow.line(3, "%s: %d", minName, *min)
+	}
+	if max != nil {
+		ow.line(3, "%s: %d", maxName, *max)
+	}
+}
+
+// schemaRef writes the schema of a reference to a type: the schema of the
+// base types, with the items and keys of arrays and maps, or a $ref to the
+// component schema of the others.
+func (ow *openAPIWriter) schemaRef(indent int, ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) {
+	if !rdl.IsBaseType(string(ref)) {
+		ow.line(indent, "$ref: %s", utils.QuoteYAML(componentRef(ref)))
+		return
+	}
+	ow.baseSchema(indent, ref)
+	switch strings.ToLower(string(ref)) {
+	case "array":
+		ow.line(indent, "items:")
+		if items == "" {
+			items = "Any"
+		}
+		ow.schemaRef(indent+1, items, "", "")
+	case "map":
+		if items == "" {
+			ow.line(indent, "additionalProperties: true")
+		} else {
+			ow.line(indent, "additionalProperties:")
+			ow.schemaRef(indent+1, items, "", "")
+		}
+	}
+}
+
+// baseSchema writes the type and format of the schema of a base type.
+func (ow *openAPIWriter) baseSchema(indent int, ref rdl.TypeRef) {
+	switch strings.ToLower(string(ref)) {
+	case "bool":
+		ow.line(indent, "type: boolean")
+	case "int8", "int16", "int32":
+		ow.line(indent, "type: integer")
+		ow.line(indent, "format: int32")
+	case "int64":
+		ow.line(indent, "type: integer")
+		ow.line(indent, "format: int64")
+	case "float32":
+		ow.line(indent, "type: number")
+		ow.line(indent, "format: float")
+	case "float64":
+		ow.line(indent, "type: number")
+		ow.line(indent, "format: double")
+	case "uuid":
+		ow.line(indent, "type: string")
+		ow.line(indent, "format: uuid")
+	case "timestamp":
+		ow.line(indent, "type: string")
+		ow.line(indent, "format: date-time")
+	case "bytes":
+		ow.line(indent, "type: string")
+		ow.line(indent, "format: byte")
+	case "array":
+		ow.line(indent, "type: array")
+	case "map", "struct":
+		ow.line(indent, "type: object")
+	case "any":
+		ow.line(indent, "nullable: true")
+	default:
+		ow.line(indent, "type: string")
+	}
+}
+
+func componentRef(ref rdl.TypeRef) string {
+	return "#/components/schemas/" + string(ref)
+}
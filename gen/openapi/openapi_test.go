@@ -0,0 +1,196 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package openapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateOpenAPI(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateOpenAPI(gentest.SampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate OpenAPI: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/openapi/sample.yaml")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("OpenAPI not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateOpenAPIDuplicateOperation(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/items").Build())
+	sb.AddResource(rdl.NewResourceBuilder("String", "GET", "/items?all=true").Build())
+	var buf bytes.Buffer
+	if err := GenerateOpenAPI(gentest.MustBuild(sb), &buf); err == nil {
+		test.Error("expected an error for two operations with the same method and path")
+	}
+}
+
+func TestGenerateOpenAPISchemaOverride(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Point").
+		Comment("A point").
+		Field("x", "Int32", false, nil, "").
+		Field("y", "Int32", false, nil, "").
+		OpenAPISchemaOverride(map[string]interface{}{
+			"type":     "array",
+			"items":    map[string]interface{}{"type": "integer"},
+			"minItems": 2,
+			"maxItems": 2,
+		}).
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Line").
+		Field("from", "Point", false, nil, "").
+		Build())
+	schema := gentest.MustBuild(sb)
+	var buf bytes.Buffer
+	if err := GenerateOpenAPI(schema, &buf); err != nil {
+		test.Fatalf("cannot generate OpenAPI: %v", err)
+	}
+	out := buf.String()
+	expected := `    Point: {"items":{"type":"integer"},"maxItems":2,"minItems":2,"type":"array"}
+    Line:
+      type: object
+      required: ["from"]
+`
+	if !strings.Contains(out, expected) {
+		test.Errorf("OpenAPI not generated as expected, real: \n%s\n, expected: \n%s\n", out, expected)
+	}
+	if strings.Contains(out, "A point") || strings.Contains(out, "format: int32") {
+		test.Errorf("translated schema of Point generated along with its override:\n%s", out)
+	}
+
+	schema.Types[0].StructTypeDef.OpenAPISchemaOverride = nil
+	buf.Reset()
+	if err := GenerateOpenAPI(schema, &buf); err != nil {
+		test.Fatalf("cannot generate OpenAPI: %v", err)
+	}
+	expected = `    Point:
+      type: object
+      description: "A point"
+      required: ["x", "y"]
+`
+	if !strings.Contains(buf.String(), expected) {
+		test.Errorf("OpenAPI not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), expected)
+	}
+
+	schema.Types[0].StructTypeDef.OpenAPISchemaOverride = map[string]interface{}{"default": func() {}}
+	if err := GenerateOpenAPI(schema, &buf); err == nil {
+		test.Error("expected an error for an override that cannot be encoded")
+	}
+}
+
+func TestGenerateOpenAPIAPIKey(test *testing.T) {
+	schema := gentest.SampleSchema()
+	schema.Resources[0].APIKeyAuth = &rdl.APIKeyDef{In: "header", Name: "X-API-Key"}
+	schema.Resources[2].APIKeyAuth = &rdl.APIKeyDef{In: "query", Name: "key", Scheme: "query_key"}
+	var buf bytes.Buffer
+	if err := GenerateOpenAPI(schema, &buf); err != nil {
+		test.Fatalf("cannot generate OpenAPI: %v", err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"      security:\n        - rdl_auth: []\n          \"X-API-Key\": []\n      x-rdl-auth:\n",
+		"      operationId: listUsers\n      security:\n        - \"query_key\": []\n      parameters:\n",
+		`  securitySchemes:
+    rdl_auth:
+      type: http
+      scheme: bearer
+      description: Authentication, and authorization of the action on the resource if given
+    "X-API-Key":
+      type: apiKey
+      in: header
+      name: "X-API-Key"
+    "query_key":
+      type: apiKey
+      in: query
+      name: "key"
+`,
+	} {
+		if !strings.Contains(out, expected) {
+			test.Errorf("OpenAPI not generated as expected, real: \n%s\n, expected: \n%s\n", out, expected)
+		}
+	}
+
+	for _, keys := range [][2]*rdl.APIKeyDef{
+		{{In: "cookie", Name: "key"}, nil},
+		{{In: "header"}, nil},
+		{{In: "header", Name: "rdl_auth"}, nil},
+		{{In: "header", Name: "key"}, {In: "query", Name: "key"}},
+	} {
+		schema = gentest.SampleSchema()
+		schema.Resources[0].APIKeyAuth, schema.Resources[2].APIKeyAuth = keys[0], keys[1]
+		if err := GenerateOpenAPI(schema, &buf); err == nil {
+			test.Errorf("expected an error for the API keys %+v and %+v", keys[0], keys[1])
+		}
+	}
+}
+
+func TestGenerateOpenAPISSE(test *testing.T) {
+	schema := gentest.SampleSchema()
+	schema.Resources[0].SSEEvents = []*rdl.SSEEventDef{
+		{EventName: "user_updated", PayloadType: "User"},
+		{EventName: "user_deleted", PayloadType: "UserId"},
+		{EventName: "heartbeat", PayloadType: "Int64"},
+	}
+	var buf bytes.Buffer
+	if err := GenerateOpenAPI(schema, &buf); err != nil {
+		test.Fatalf("cannot generate OpenAPI: %v", err)
+	}
+	expected := `      responses:
+        "200":
+          description: "OK"
+          content:
+            text/event-stream:
+              schema:
+                oneOf:
+                  - type: object
+                    required: ["event", "data"]
+                    properties:
+                      event:
+                        type: string
+                        enum: ["user_updated"]
+                      data:
+                        $ref: "#/components/schemas/User"
+                  - type: object
+                    required: ["event", "data"]
+                    properties:
+                      event:
+                        type: string
+                        enum: ["user_deleted"]
+                      data:
+                        $ref: "#/components/schemas/UserId"
+                  - type: object
+                    required: ["event", "data"]
+                    properties:
+                      event:
+                        type: string
+                        enum: ["heartbeat"]
+                      data:
+                        type: integer
+                        format: int64
+                discriminator:
+                  propertyName: event
+        "304":
+          description: "NOT_MODIFIED"
+`
+	if !strings.Contains(buf.String(), expected) {
+		test.Errorf("OpenAPI not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), expected)
+	}
+
+	schema.Resources[0].SSEEvents = append(schema.Resources[0].SSEEvents, &rdl.SSEEventDef{EventName: "closed"})
+	if err := GenerateOpenAPI(schema, &buf); err == nil {
+		test.Error("expected an error for an event without a payload type")
+	}
+}
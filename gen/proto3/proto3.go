@@ -0,0 +1,257 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package proto3 exports the types of RDL schemas as Protocol Buffers 3
+// message definitions.
+package proto3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+type protoWriter struct {
+	registry rdl.TypeRegistry
+	imports  map[string]bool
+	buf      bytes.Buffer
+}
+
+// GenerateProto3 writes the types of the schema as a proto3 file. Structs
+// become messages, with their inherited fields first and field numbers
+// assigned in declaration order from 1, enums become enums, and unions
+// become messages with a oneof of their variants. Arrays are repeated fields
+// and maps are map fields, while the other types are replaced by the proto3
+// scalar type of their base type. Optional fields are marked with an
+// rdl:optional comment.
+//
+// Protocol Buffers cannot express arrays of arrays or maps, maps of arrays or
+// maps, nor maps keyed by other types than integers and strings: fields of
+// these types are an error.
+func GenerateProto3(s *rdl.Schema, w io.Writer) error {
+	pw := &protoWriter{registry: rdl.NewTypeRegistry(s), imports: make(map[string]bool)}
+	for _, t := range s.Types {
+		var err error
+		switch t.Variant {
+		case rdl.TypeVariantStructTypeDef:
+			err = pw.message(t)
+		case rdl.TypeVariantEnumTypeDef:
+			pw.enum(t)
+		case rdl.TypeVariantUnionTypeDef:
+			err = pw.union(t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\n", utils.GoGenerationHeader(banner))
+	fmt.Fprintf(&out, "syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&out, "package %s;\n", protoPackage(s))
+	if len(pw.imports) > 0 {
+		var imports []string
+		for i := range pw.imports {
+			imports = append(imports, i)
+		}
+		sort.Strings(imports)
+		out.WriteString("\n")
+		for _, i := range imports {
+			fmt.Fprintf(&out, "import %q;\n", i)
+		}
+	}
+	out.Write(pw.buf.Bytes())
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// protoPackage returns the package of the proto file: the schema name,
+// qualified by the namespace of the schema if any.
+func protoPackage(s *rdl.Schema) string {
+	name := strings.ToLower(string(s.Name))
+	if s.Namespace != "" {
+		return string(s.Namespace) + "." + name
+	}
+	return name
+}
+
+func (pw *protoWriter) comment(indent string, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, l := range strings.Split(comment, "\n") {
+		fmt.Fprintf(&pw.buf, "%s// %s\n", indent, strings.TrimSpace(l))
+	}
+}
+
+func (pw *protoWriter) message(t *rdl.Type) error {
+	st := t.StructTypeDef
+	pw.buf.WriteString("\n")
+	pw.comment("", st.Comment)
+	fmt.Fprintf(&pw.buf, "message %s {\n", st.Name)
+	for i, f := range utils.FlattenedFields(pw.registry, t) {
+		fieldType, err := pw.fieldType(f.Type, f.Items, f.Keys)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+		}
+		pw.comment("  ", f.Comment)
+		fmt.Fprintf(&pw.buf, "  %s %s = %d;", fieldType, f.Name, i+1)
+		if f.Optional {
+			pw.buf.WriteString(" // rdl:optional")
+		}
+		pw.buf.WriteString("\n")
+	}
+	pw.buf.WriteString("}\n")
+	return nil
+}
+
+func (pw *protoWriter) enum(t *rdl.Type) {
+	et := t.EnumTypeDef
+	prefix := enumPrefix(string(et.Name))
+	pw.buf.WriteString("\n")
+	pw.comment("", et.Comment)
+	fmt.Fprintf(&pw.buf, "enum %s {\n", et.Name)
+	hasZero := false
+	for _, e := range et.Elements {
+		if e.Value != nil && *e.Value == 0 {
+			hasZero = true
+		}
+	}
+	if !hasZero {
+		fmt.Fprintf(&pw.buf, "  %sUNSPECIFIED = 0;\n", prefix)
+	}
+	for i, e := range et.Elements {
+		value := int32(i + 1)
+		if e.Value != nil {
+			value = *e.Value
+		}
+		pw.comment("  ", e.Comment)
+		fmt.Fprintf(&pw.buf, "  %s%s = %d;\n", prefix, e.Symbol, value)
+	}
+	pw.buf.WriteString("}\n")
+}
+
+// enumPrefix returns the prefix of the values of an enum, its name in upper
+// snake case followed by an underscore, as enum values share the scope of
+// their enum.
+func enumPrefix(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String() + "_"
+}
+
+func (pw *protoWriter) union(t *rdl.Type) error {
+	ut := t.UnionTypeDef
+	pw.buf.WriteString("\n")
+	pw.comment("", ut.Comment)
+	fmt.Fprintf(&pw.buf, "message %s {\n", ut.Name)
+	fmt.Fprintf(&pw.buf, "  oneof value {\n")
+	for i, v := range ut.Variants {
+		fieldType, err := pw.fieldType(v, "", "")
+		if err != nil {
+			return fmt.Errorf("%s: %v", ut.Name, err)
+		}
+		if strings.HasPrefix(fieldType, "repeated ") || strings.HasPrefix(fieldType, "map<") {
+			return fmt.Errorf("%s: variant %s cannot be in a oneof", ut.Name, v)
+		}
+		fmt.Fprintf(&pw.buf, "    %s %s = %d;\n", fieldType, utils.Uncapitalize(string(v)), i+1)
+	}
+	pw.buf.WriteString("  }\n}\n")
+	return nil
+}
+
+// fieldType returns the type of a field referring to an RDL type, with the
+// repeated label for arrays.
+func (pw *protoWriter) fieldType(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) (string, error) {
+	t := pw.registry.FindType(ref)
+	if t == nil {
+		return "", fmt.Errorf("unknown type %s", ref)
+	}
+	switch pw.registry.BaseType(t) {
+	case rdl.BaseTypeArray:
+		if t.ArrayTypeDef != nil {
+			items = t.ArrayTypeDef.Items
+		}
+		if items == "" {
+			items = "Any"
+		}
+		itemType, err := pw.fieldType(items, "", "")
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(itemType, "repeated ") || strings.HasPrefix(itemType, "map<") {
+			return "", fmt.Errorf("array items of type %s cannot be repeated", items)
+		}
+		return "repeated " + itemType, nil
+	case rdl.BaseTypeMap:
+		if t.MapTypeDef != nil {
+			keys, items = t.MapTypeDef.Keys, t.MapTypeDef.Items
+		}
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		keyType, err := pw.fieldType(keys, "", "")
+		if err != nil {
+			return "", err
+		}
+		switch keyType {
+		case "int32", "int64", "string", "bool":
+		default:
+			return "", fmt.Errorf("map keys of type %s are not integers or strings", keys)
+		}
+		itemType, err := pw.fieldType(items, "", "")
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(itemType, "repeated ") || strings.HasPrefix(itemType, "map<") {
+			return "", fmt.Errorf("map items of type %s cannot be map values", items)
+		}
+		return fmt.Sprintf("map<%s, %s>", keyType, itemType), nil
+	case rdl.BaseTypeStruct, rdl.BaseTypeEnum, rdl.BaseTypeUnion:
+		if t.Variant == rdl.TypeVariantBaseType {
+			pw.imports["google/protobuf/struct.proto"] = true
+			return "google.protobuf.Struct", nil
+		}
+		name, super, _ := rdl.TypeInfo(t)
+		if t.Variant == rdl.TypeVariantAliasTypeDef {
+			//aliases of messages and enums are not declared
+			return pw.fieldType(super, items, keys)
+		}
+		return string(name), nil
+	case rdl.BaseTypeBool:
+		return "bool", nil
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32:
+		return "int32", nil
+	case rdl.BaseTypeInt64:
+		return "int64", nil
+	case rdl.BaseTypeFloat32:
+		return "float", nil
+	case rdl.BaseTypeFloat64:
+		return "double", nil
+	case rdl.BaseTypeBytes:
+		return "bytes", nil
+	case rdl.BaseTypeTimestamp:
+		pw.imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp", nil
+	case rdl.BaseTypeAny:
+		pw.imports["google/protobuf/struct.proto"] = true
+		return "google.protobuf.Value", nil
+	default:
+		return "string", nil
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package proto3
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateProto3(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateProto3(gentest.SampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate proto3: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/proto3/sample.proto")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("proto3 not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateProto3Unsupported(test *testing.T) {
+	for name, tb := range map[string]*rdl.StructTypeBuilder{
+		"array of arrays":     rdl.NewStructTypeBuilder("Struct", "Matrix").ArrayField("rows", "Row", false, ""),
+		"map of arrays":       rdl.NewStructTypeBuilder("Struct", "Index").MapField("rows", "String", "Row", false, ""),
+		"map keyed by floats": rdl.NewStructTypeBuilder("Struct", "Index").MapField("rows", "Float64", "String", false, ""),
+	} {
+		sb := rdl.NewSchemaBuilder("sample")
+		sb.AddType(rdl.NewArrayTypeBuilder("Array", "Row").Items("Int32").Build())
+		sb.AddType(tb.Build())
+		var buf bytes.Buffer
+		if err := GenerateProto3(gentest.MustBuild(sb), &buf); err == nil {
+			test.Errorf("expected an error for a %s", name)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package python exports the types of RDL schemas as Python dataclasses.
+package python
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+type pyWriter struct {
+	registry rdl.TypeRegistry
+	imports  map[string]map[string]bool
+	buf      bytes.Buffer
+}
+
+// GeneratePython writes the types of the schema as a Python 3.10 module.
+// Structs become keyword-only dataclasses, subclassing the dataclass of their
+// super type if any, enums become string enums, and the other types type
+// aliases, arrays of type list[T], maps dict[K, V] and unions Union[A, B].
+// Optional fields are Optional and default to None, unless they have a
+// default value. Constrained string and number types get a validate_<type>
+// function raising ValueError, which validates the value against its super
+// type first, called from the __post_init__ method of the dataclasses with
+// fields of these types.
+func GeneratePython(s *rdl.Schema, w io.Writer) error {
+	pw := &pyWriter{registry: rdl.NewTypeRegistry(s), imports: make(map[string]map[string]bool)}
+	for _, t := range s.Types {
+		if err := pw.declaration(t); err != nil {
+			return err
+		}
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\n", strings.Replace(utils.GoGenerationHeader(banner), "//", "#", -1))
+	out.WriteString("from __future__ import annotations\n\n")
+	var modules []string
+	for m := range pw.imports {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	for _, m := range modules {
+		if m == "re" {
+			out.WriteString("import re\n")
+			continue
+		}
+		var names []string
+		for n := range pw.imports[m] {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&out, "from %s import %s\n", m, strings.Join(names, ", "))
+	}
+	out.Write(pw.buf.Bytes())
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (pw *pyWriter) use(module string, name string) {
+	if pw.imports[module] == nil {
+		pw.imports[module] = make(map[string]bool)
+	}
+	pw.imports[module][name] = true
+}
+
+func (pw *pyWriter) line(indent int, format string, args ...interface{}) {
+	pw.buf.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(&pw.buf, format, args...)
+	pw.buf.WriteString("\n")
+}
+
+func (pw *pyWriter) docstring(indent int, comment string) {
+	if comment != "" {
+		pw.line(indent, "\"\"\"%s\"\"\"", strings.Replace(strings.TrimSpace(comment), "\"\"\"", "\\\"\\\"\\\"", -1))
+	}
+}
+
+func (pw *pyWriter) declaration(t *rdl.Type) error {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	pw.buf.WriteString("\n\n")
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		return pw.dataclass(t)
+	case rdl.TypeVariantEnumTypeDef:
+		pw.use("enum", "Enum")
+		pw.line(0, "class %s(str, Enum):", tName)
+		pw.docstring(1, tComment)
+		for _, e := range t.EnumTypeDef.Elements {
+			pw.line(1, "%s = %q", e.Symbol, e.Symbol)
+		}
+		if len(t.EnumTypeDef.Elements) == 0 {
+			pw.line(1, "pass")
+		}
+	case rdl.TypeVariantUnionTypeDef:
+		pw.use("typing", "Union")
+		var variants []string
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, pw.typeExpr(v, "", ""))
+		}
+		pw.comment(tComment)
+		pw.line(0, "%s = Union[%s]", tName, strings.Join(variants, ", "))
+	case rdl.TypeVariantArrayTypeDef:
+		pw.comment(tComment)
+		pw.line(0, "%s = %s", tName, pw.typeExpr("Array", t.ArrayTypeDef.Items, ""))
+	case rdl.TypeVariantMapTypeDef:
+		pw.comment(tComment)
+		pw.line(0, "%s = %s", tName, pw.typeExpr("Map", t.MapTypeDef.Items, t.MapTypeDef.Keys))
+	default:
+		pw.comment(tComment)
+		pw.line(0, "%s = %s", tName, pw.typeExpr(tType, "", ""))
+		pw.validator(t)
+	}
+	return nil
+}
+
+func (pw *pyWriter) comment(comment string) {
+	for _, l := range strings.Split(comment, "\n") {
+		if comment != "" {
+			pw.line(0, "# %s", strings.TrimSpace(l))
+		}
+	}
+}
+
+func (pw *pyWriter) dataclass(t *rdl.Type) error {
+	st := t.StructTypeDef
+	pw.use("dataclasses", "dataclass")
+	pw.line(0, "@dataclass(kw_only=True)")
+	if st.Type == "Struct" {
+		pw.line(0, "class %s:", st.Name)
+	} else {
+		pw.line(0, "class %s(%s):", st.Name, st.Type)
+	}
+	pw.docstring(1, st.Comment)
+	for _, f := range st.Fields {
+		typ := pw.typeExpr(f.Type, f.Items, f.Keys)
+		switch {
+		case f.Default != nil:
+			def, err := pyLiteral(f.Default)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+			}
+			pw.use("dataclasses", "field")
+			if f.Optional {
+				pw.use("typing", "Optional")
+				typ = "Optional[" + typ + "]"
+			}
+			pw.line(1, "%s: %s = field(default=%s)", f.Name, typ, def)
+		case f.Optional:
+			pw.use("typing", "Optional")
+			pw.line(1, "%s: Optional[%s] = None", f.Name, typ)
+		default:
+			pw.line(1, "%s: %s", f.Name, typ)
+		}
+		if f.Comment != "" {
+			pw.docstring(1, f.Comment)
+		}
+	}
+	var checks []*rdl.StructFieldDef
+	for _, f := range utils.FlattenedFields(pw.registry, t) {
+		if pw.constrained(f.Type) {
+			checks = append(checks, f)
+		}
+	}
+	if len(checks) > 0 {
+		pw.buf.WriteString("\n")
+		pw.line(1, "def __post_init__(self) -> None:")
+		for _, f := range checks {
+			if f.Optional {
+				pw.line(2, "if self.%s is not None:", f.Name)
+				pw.line(3, "validate_%s(self.%s)", snakeCase(string(f.Type)), f.Name)
+			} else {
+				pw.line(2, "validate_%s(self.%s)", snakeCase(string(f.Type)), f.Name)
+			}
+		}
+	} else if len(st.Fields) == 0 && st.Comment == "" {
+		pw.line(1, "pass")
+	}
+	return nil
+}
+
+// constrained returns true if the type is a string or number type with
+// constraints, its own or those of its super type, which has a validator.
+func (pw *pyWriter) constrained(ref rdl.TypeRef) bool {
+	t := pw.registry.FindType(ref)
+	if t == nil {
+		return false
+	}
+	if _, super, _ := rdl.TypeInfo(t); super != ref && pw.constrained(super) {
+		return true
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStringTypeDef:
+		st := t.StringTypeDef
+		return st.Pattern != "" || st.MinSize != nil || st.MaxSize != nil || len(st.Values) > 0
+	case rdl.TypeVariantNumberTypeDef:
+		return t.NumberTypeDef.Min != nil || t.NumberTypeDef.Max != nil
+	}
+	return false
+}
+
+// validator writes the validate_<type> function of a constrained type.
+func (pw *pyWriter) validator(t *rdl.Type) {
+	tName, tType, _ := rdl.TypeInfo(t)
+	if !pw.constrained(rdl.TypeRef(tName)) {
+		return
+	}
+	pw.buf.WriteString("\n\n")
+	pw.line(0, "def validate_%s(value: %s) -> None:", snakeCase(string(tName)), tName)
+	pw.docstring(1, fmt.Sprintf("Raises ValueError if the value is not a valid %s.", tName))
+	if pw.constrained(tType) {
+		pw.line(1, "validate_%s(value)", snakeCase(string(tType)))
+	}
+	invalid := fmt.Sprintf("raise ValueError(f\"invalid %s: {value!r}\")", tName)
+	if st := t.StringTypeDef; st != nil {
+		if st.Pattern != "" {
+			pw.use("re", "")
+			pw.line(1, "if re.fullmatch(%s, value) is None:", pyString(st.Pattern))
+			pw.line(2, "%s", invalid)
+		}
+		if st.MinSize != nil {
+			pw.line(1, "if len(value) < %d:", *st.MinSize)
+			pw.line(2, "%s", invalid)
+		}
+		if st.MaxSize != nil {
+			pw.line(1, "if len(value) > %d:", *st.MaxSize)
+			pw.line(2, "%s", invalid)
+		}
+		if len(st.Values) > 0 {
+			var values []string
+			for _, v := range st.Values {
+				values = append(values, pyString(v))
+			}
+			pw.line(1, "if value not in {%s}:", strings.Join(values, ", "))
+			pw.line(2, "%s", invalid)
+		}
+	}
+	if nt := t.NumberTypeDef; nt != nil {
+		if nt.Min != nil {
+			pw.line(1, "if value < %s:", utils.NumberString(nt.Min))
+			pw.line(2, "%s", invalid)
+		}
+		if nt.Max != nil {
+			pw.line(1, "if value > %s:", utils.NumberString(nt.Max))
+			pw.line(2, "%s", invalid)
+		}
+	}
+}
+
+// typeExpr returns the Python type of a reference to an RDL type. Base types
+// map to the Python built-in types, everything else is referenced by name.
+func (pw *pyWriter) typeExpr(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch ref {
+	case "Bool":
+		return "bool"
+	case "Int8", "Int16", "Int32", "Int64":
+		return "int"
+	case "Float32", "Float64":
+		return "float"
+	case "String", "Symbol":
+		return "str"
+	case "Bytes":
+		return "bytes"
+	case "UUID":
+		pw.use("uuid", "UUID")
+		return "UUID"
+	case "Timestamp":
+		pw.use("datetime", "datetime")
+		return "datetime"
+	case "Array":
+		if items == "" {
+			items = "Any"
+		}
+		return "list[" + pw.typeExpr(items, "", "") + "]"
+	case "Map":
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		return "dict[" + pw.typeExpr(keys, "", "") + ", " + pw.typeExpr(items, "", "") + "]"
+	case "Struct":
+		pw.use("typing", "Any")
+		return "dict[str, Any]"
+	case "Any":
+		pw.use("typing", "Any")
+		return "Any"
+	}
+	return string(ref)
+}
+
+// pyLiteral returns the Python literal of a default value.
+func pyLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return "True", nil
+		}
+		return "False", nil
+	case string:
+		return pyString(v), nil
+	case int, int8, int16, int32, int64, float32, float64, json.Number:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unsupported default value %v", v)
+}
+
+// pyString returns s as a Python string literal.
+func pyString(s string) string {
+	q, _ := json.Marshal(s)
+	return string(q)
+}
+
+// snakeCase returns the name in snake case.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,463 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package raml exports RDL schemas as RAML (RESTful API Modeling Language)
+// API definitions.
+package raml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+// securityScheme is the name of the scheme resources with an auth block are
+// secured by.
+const securityScheme = "rdl_auth"
+
+// RAMLOptions controls the exported RAML.
+type RAMLOptions struct {
+	// Version is the RAML version to emit, "0.8" or "1.0" (the default).
+	Version string
+	// BaseURI is the baseUri of the API, http://localhost:8080 followed by
+	// the schema's root path if empty.
+	BaseURI string
+}
+
+type ramlWriter struct {
+	registry rdl.TypeRegistry
+	schema   *rdl.Schema
+	version  string
+	buf      bytes.Buffer
+}
+
+// ExportRAML writes the schema as a RAML document: types become RAML type
+// declarations (or JSON schemas for RAML 0.8), resources become RAML
+// resources and methods, and auth blocks map to securedBy.
+func ExportRAML(s *rdl.Schema, w io.Writer, opts RAMLOptions) error {
+	switch opts.Version {
+	case "":
+		opts.Version = "1.0"
+	case "0.8", "1.0":
+	default:
+		return fmt.Errorf("unsupported RAML version: %s", opts.Version)
+	}
+	if opts.BaseURI == "" {
+		opts.BaseURI = "http://localhost:8080" + utils.JavaGenerationRootPath(s)
+	}
+	rw := &ramlWriter{
+		registry: rdl.NewTypeRegistry(s),
+		schema:   s,
+		version:  opts.Version,
+	}
+	rw.line(0, "#%%RAML %s", opts.Version)
+	rw.line(0, "title: %s", utils.QuoteYAML(string(s.Name)))
+	if s.Version != nil {
+		rw.line(0, "version: v%d", *s.Version)
+	}
+	rw.line(0, "baseUri: %s", utils.QuoteYAML(opts.BaseURI))
+	rw.line(0, "mediaType: application/json")
+	if s.Comment != "" {
+		rw.line(0, "documentation:")
+		rw.line(1, "- title: Overview")
+		rw.line(1, "  content: %s", utils.QuoteYAML(s.Comment))
+	}
+	rw.securitySchemes()
+	if err := rw.types(); err != nil {
+		return err
+	}
+	rw.resources()
+	_, err := w.Write(rw.buf.Bytes())
+	return err
+}
+
+func (rw *ramlWriter) line(indent int, format string, args ...interface{}) {
+	rw.buf.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&rw.buf, format, args...)
+	rw.buf.WriteString("\n")
+}
+
+func (rw *ramlWriter) securitySchemes() {
+	for _, r := range rw.schema.Resources {
+		if r.Auth != nil {
+			rw.line(0, "securitySchemes:")
+			if rw.version == "0.8" {
+				rw.line(1, "- %s:", securityScheme)
+				rw.line(3, "type: x-rdl-auth")
+				rw.line(3, "description: Authentication, and authorization of the action on the resource if given")
+			} else {
+				rw.line(1, "%s:", securityScheme)
+				rw.line(2, "type: x-rdl-auth")
+				rw.line(2, "description: Authentication, and authorization of the action on the resource if given")
+			}
+			return
+		}
+	}
+}
+
+func (rw *ramlWriter) types() error {
+	if len(rw.schema.Types) == 0 {
+		return nil
+	}
+	if rw.version == "0.8" {
+		rw.line(0, "schemas:")
+		for _, t := range rw.schema.Types {
+			tName, _, _ := rdl.TypeInfo(t)
+			js, err := json.MarshalIndent(rw.jsonSchema(t), "", "  ")
+			if err != nil {
+				return err
+			}
+			rw.line(1, "- %s: |", tName)
+			for _, l := range strings.Split(string(js), "\n") {
+				rw.line(3, "%s", l)
+			}
+		}
+		return nil
+	}
+	rw.line(0, "types:")
+	for _, t := range rw.schema.Types {
+		rw.typeDeclaration(t)
+	}
+	return nil
+}
+
+func (rw *ramlWriter) typeDeclaration(t *rdl.Type) {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	rw.line(1, "%s:", tName)
+	description := func() {
+		if tComment != "" {
+			rw.line(2, "description: %s", utils.QuoteYAML(tComment))
+		}
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		if tType == "Struct" {
+			rw.line(2, "type: object")
+		} else {
+			rw.line(2, "type: %s", tType)
+		}
+		description()
+		if len(t.StructTypeDef.Fields) > 0 {
+			rw.line(2, "properties:")
+			for _, f := range t.StructTypeDef.Fields {
+				rw.line(3, "%s:", f.Name)
+				rw.line(4, "type: %s", rw.typeExpr(f.Type, f.Items))
+				if f.Comment != "" {
+					rw.line(4, "description: %s", utils.QuoteYAML(f.Comment))
+				}
+				if f.Optional {
+					rw.line(4, "required: false")
+				}
+			}
+		}
+		if t.StructTypeDef.Closed {
+			rw.line(2, "additionalProperties: false")
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		rw.line(2, "type: string")
+		description()
+		var symbols []string
+		for _, e := range t.EnumTypeDef.Elements {
+			symbols = append(symbols, string(e.Symbol))
+		}
+		rw.line(2, "enum: [%s]", strings.Join(symbols, ", "))
+	case rdl.TypeVariantUnionTypeDef:
+		var variants []string
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, string(v))
+		}
+		rw.line(2, "type: %s", strings.Join(variants, " | "))
+		description()
+	case rdl.TypeVariantArrayTypeDef:
+		rw.line(2, "type: array")
+		description()
+		rw.line(2, "items: %s", rw.typeExpr(t.ArrayTypeDef.Items, ""))
+		rw.sizes(t.ArrayTypeDef.MinSize, t.ArrayTypeDef.MaxSize, "minItems", "maxItems")
+	case rdl.TypeVariantMapTypeDef:
+		rw.line(2, "type: object")
+		description()
+		rw.line(2, "properties:")
+		rw.line(3, "//: %s", rw.typeExpr(t.MapTypeDef.Items, ""))
+	case rdl.TypeVariantStringTypeDef:
+		rw.line(2, "type: string")
+		description()
+		if t.StringTypeDef.Pattern != "" {
+			rw.line(2, "pattern: %s", utils.QuoteYAML(t.StringTypeDef.Pattern))
+		}
+		if len(t.StringTypeDef.Values) > 0 {
+			var values []string
+			for _, v := range t.StringTypeDef.Values {
+				values = append(values, utils.QuoteYAML(v))
+			}
+			rw.line(2, "enum: [%s]", strings.Join(values, ", "))
+		}
+		rw.sizes(t.StringTypeDef.MinSize, t.StringTypeDef.MaxSize, "minLength", "maxLength")
+	case rdl.TypeVariantNumberTypeDef:
+		rw.line(2, "type: %s", rw.typeExpr(t.NumberTypeDef.Type, ""))
+		description()
+		if t.NumberTypeDef.Min != nil {
+			rw.line(2, "minimum: %s", utils.NumberString(t.NumberTypeDef.Min))
+		}
+		if t.NumberTypeDef.Max != nil {
+			rw.line(2, "maximum: %s", utils.NumberString(t.NumberTypeDef.Max))
+		}
+	default:
+		rw.line(2, "type: %s", rw.typeExpr(tType, ""))
+		description()
+	}
+}
+
+func (rw *ramlWriter) sizes(min *int32, max *int32, minName string, maxName string) {
+	if min != nil {
+		rw.line(2, "%s: %d", minName, *min)
+	}
+	if max != nil {
+		rw.line(2, "%s: %d", maxName, *max)
+	}
+}
+
+// typeExpr returns the RAML 1.0 type expression for a reference to an RDL
+// type. Base types map to the RAML built-in types, everything else is
+// referenced by name.
+func (rw *ramlWriter) typeExpr(ref rdl.TypeRef, items rdl.TypeRef) string {
+	switch ref {
+	case "Bool":
+		return "boolean"
+	case "Int8", "Int16", "Int32", "Int64":
+		return "integer"
+	case "Float32", "Float64":
+		return "number"
+	case "String", "Symbol", "UUID", "Bytes":
+		return "string"
+	case "Timestamp":
+		return "datetime"
+	case "Array":
+		if items == "" {
+			return "array"
+		}
+		return rw.typeExpr(items, "") + "[]"
+	case "Map", "Struct", "Any":
+		return "object"
+	}
+	return string(ref)
+}
+
+// paramType returns the type of a RAML 0.8 named parameter, or the type
+// expression of a RAML 1.0 one.
+func (rw *ramlWriter) paramType(ref rdl.TypeRef) string {
+	if rw.version != "0.8" {
+		return rw.typeExpr(ref, "")
+	}
+	switch rw.registry.FindBaseType(ref) {
+	case rdl.BaseTypeBool:
+		return "boolean"
+	case rdl.BaseTypeInt8, rdl.BaseTypeInt16, rdl.BaseTypeInt32, rdl.BaseTypeInt64:
+		return "integer"
+	case rdl.BaseTypeFloat32, rdl.BaseTypeFloat64:
+		return "number"
+	case rdl.BaseTypeTimestamp:
+		return "date"
+	default:
+		return "string"
+	}
+}
+
+func (rw *ramlWriter) resources() {
+	var paths []string
+	byPath := make(map[string][]*rdl.Resource)
+	for _, r := range rw.schema.Resources {
+		path := r.Path
+		if i := strings.Index(path, "?"); i >= 0 {
+			path = path[:i]
+		}
+		if _, ok := byPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		byPath[path] = append(byPath[path], r)
+	}
+	for _, path := range paths {
+		rw.line(0, "%s:", path)
+		for _, r := range byPath[path] {
+			rw.method(r)
+		}
+	}
+}
+
+func (rw *ramlWriter) method(r *rdl.Resource) {
+	rw.line(1, "%s:", strings.ToLower(r.Method))
+	if r.Comment != "" {
+		rw.line(2, "description: %s", utils.QuoteYAML(r.Comment))
+	}
+	if r.Auth != nil {
+		if r.Auth.Action != "" {
+			rw.line(2, "securedBy: [%s: {action: %s, resource: %s}]", securityScheme, utils.QuoteYAML(r.Auth.Action), utils.QuoteYAML(r.Auth.Resource))
+		} else {
+			rw.line(2, "securedBy: [%s]", securityScheme)
+		}
+	}
+	var uriParams, queryParams, headers []*rdl.ResourceInput
+	var body *rdl.ResourceInput
+	for _, in := range r.Inputs {
+		switch {
+		case in.PathParam:
+			uriParams = append(uriParams, in)
+		case in.QueryParam != "":
+			queryParams = append(queryParams, in)
+		case in.Header != "":
+			headers = append(headers, in)
+		case in.Context == "":
+			body = in
+		}
+	}
+	rw.parameters("uriParameters", uriParams, func(in *rdl.ResourceInput) string { return string(in.Name) })
+	rw.parameters("queryParameters", queryParams, func(in *rdl.ResourceInput) string { return in.QueryParam })
+	rw.parameters("headers", headers, func(in *rdl.ResourceInput) string { return in.Header })
+	if body != nil {
+		rw.line(2, "body:")
+		rw.line(3, "application/json:")
+		rw.bodyType(4, body.Type)
+	}
+	rw.line(2, "responses:")
+	rw.response(r.Expected, r.Type, "")
+	for _, alt := range r.Alternatives {
+		rw.response(alt, r.Type, "")
+	}
+	var syms []string
+	for sym := range r.Exceptions {
+		syms = append(syms, sym)
+	}
+	sort.Strings(syms)
+	for _, sym := range syms {
+		e := r.Exceptions[sym]
+		rw.response(sym, rdl.TypeRef(e.Type), e.Comment)
+	}
+}
+
+func (rw *ramlWriter) parameters(section string, params []*rdl.ResourceInput, name func(*rdl.ResourceInput) string) {
+	if len(params) == 0 {
+		return
+	}
+	rw.line(2, "%s:", section)
+	for _, in := range params {
+		rw.line(3, "%s:", name(in))
+		rw.line(4, "type: %s", rw.paramType(in.Type))
+		if in.Comment != "" {
+			rw.line(4, "description: %s", utils.QuoteYAML(in.Comment))
+		}
+		if in.Optional && !in.PathParam {
+			rw.line(4, "required: false")
+		}
+		if in.Default != nil {
+			rw.line(4, "default: %v", in.Default)
+		}
+	}
+}
+
+func (rw *ramlWriter) response(sym string, t rdl.TypeRef, comment string) {
+	code := rdl.StatusCode(sym)
+	rw.line(3, "%s:", code)
+	if comment != "" {
+		rw.line(4, "description: %s", utils.QuoteYAML(comment))
+	}
+	if code == "204" || code == "304" || t == "" {
+		return
+	}
+	rw.line(4, "body:")
+	rw.line(5, "application/json:")
+	rw.bodyType(6, t)
+}
+
+func (rw *ramlWriter) bodyType(indent int, t rdl.TypeRef) {
+	if rw.version == "0.8" {
+		rw.line(indent, "schema: %s", t)
+	} else {
+		rw.line(indent, "type: %s", rw.typeExpr(t, ""))
+	}
+}
+
+// jsonSchema returns the JSON schema used to declare a type in RAML 0.8.
+// References to other schema types are made with $ref to their name.
+func (rw *ramlWriter) jsonSchema(t *rdl.Type) map[string]interface{} {
+	_, _, tComment := rdl.TypeInfo(t)
+	js := map[string]interface{}{"$schema": "http://json-schema.org/draft-04/schema#"}
+	if tComment != "" {
+		js["description"] = tComment
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		js["type"] = "object"
+		properties := make(map[string]interface{})
+		var required []string
+		for _, f := range utils.FlattenedFields(rw.registry, t) {
+			p := rw.jsonSchemaRef(f.Type, f.Items)
+			if f.Comment != "" {
+				p["description"] = f.Comment
+			}
+			properties[string(f.Name)] = p
+			if !f.Optional {
+				required = append(required, string(f.Name))
+			}
+		}
+		js["properties"] = properties
+		if len(required) > 0 {
+			js["required"] = required
+		}
+	case rdl.TypeVariantEnumTypeDef:
+		js["type"] = "string"
+		var symbols []string
+		for _, e := range t.EnumTypeDef.Elements {
+			symbols = append(symbols, string(e.Symbol))
+		}
+		js["enum"] = symbols
+	case rdl.TypeVariantUnionTypeDef:
+		var variants []interface{}
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, map[string]interface{}{"$ref": string(v)})
+		}
+		js["oneOf"] = variants
+	default:
+		for k, v := range rw.jsonSchemaRef(rdl.TypeRef(rw.registry.BaseType(t).String()), "") {
+			js[k] = v
+		}
+		if t.ArrayTypeDef != nil {
+			js["items"] = rw.jsonSchemaRef(t.ArrayTypeDef.Items, "")
+		}
+		if t.MapTypeDef != nil {
+			js["additionalProperties"] = rw.jsonSchemaRef(t.MapTypeDef.Items, "")
+		}
+		if t.StringTypeDef != nil && t.StringTypeDef.Pattern != "" {
+			js["pattern"] = t.StringTypeDef.Pattern
+		}
+	}
+	return js
+}
+
+func (rw *ramlWriter) jsonSchemaRef(ref rdl.TypeRef, items rdl.TypeRef) map[string]interface{} {
+	switch ref {
+	case "Bool":
+		return map[string]interface{}{"type": "boolean"}
+	case "Int8", "Int16", "Int32", "Int64":
+		return map[string]interface{}{"type": "integer"}
+	case "Float32", "Float64":
+		return map[string]interface{}{"type": "number"}
+	case "String", "Symbol", "UUID", "Bytes":
+		return map[string]interface{}{"type": "string"}
+	case "Timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "Array":
+		js := map[string]interface{}{"type": "array"}
+		if items != "" {
+			js["items"] = rw.jsonSchemaRef(items, "")
+		}
+		return js
+	case "Map", "Struct", "Any":
+		return map[string]interface{}{"type": "object"}
+	}
+	return map[string]interface{}{"$ref": string(ref)}
+}
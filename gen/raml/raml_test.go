@@ -0,0 +1,41 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package raml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestExportRAML(test *testing.T) {
+	for version, golden := range map[string]string{
+		"1.0": "../../testdata/raml/sample.raml",
+		"0.8": "../../testdata/raml/sample_0.8.raml",
+	} {
+		var buf bytes.Buffer
+		err := ExportRAML(gentest.SampleSchema(), &buf, RAMLOptions{Version: version})
+		checkErrInTest(err, "cannot export RAML", test)
+		expected, err := ioutil.ReadFile(golden)
+		checkErrInTest(err, "cannot read golden file", test)
+		if buf.String() != string(expected) {
+			test.Errorf("RAML %s not generated as expected, real: \n%s\n, expected: \n%s\n", version, buf.String(), string(expected))
+		}
+	}
+}
+
+func TestExportRAMLVersion(test *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportRAML(gentest.SampleSchema(), &buf, RAMLOptions{Version: "2.0"}); err == nil {
+		test.Error("expected an error for an unsupported RAML version")
+	}
+}
+
+func checkErrInTest(err error, msg string, test *testing.T) {
+	if err != nil {
+		test.Fatalf("%s: %v", msg, err)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package rust exports the types of RDL schemas as Rust types with serde
+// derives.
+package rust
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+var keywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true, "crate": true,
+	"dyn": true, "else": true, "enum": true, "extern": true, "false": true, "fn": true, "for": true,
+	"if": true, "impl": true, "in": true, "let": true, "loop": true, "match": true, "mod": true,
+	"move": true, "mut": true, "pub": true, "ref": true, "return": true, "static": true, "struct": true,
+	"trait": true, "true": true, "type": true, "unsafe": true, "use": true, "where": true, "while": true,
+}
+
+type rustWriter struct {
+	registry rdl.TypeRegistry
+	uses     map[string]bool
+	buf      bytes.Buffer
+}
+
+// GenerateRust writes the types of the schema as a Rust module, using serde
+// for the JSON representation. Structs become structs deriving Serialize and
+// Deserialize, with the fields of their super type inlined as Rust has no
+// inheritance, enums become enums serialized in SCREAMING_SNAKE_CASE, unions
+// untagged enums with a variant per type, and arrays and maps Vec and
+// HashMap type aliases. Optional fields are Option values, and fields with a
+// default value are filled with it when absent.
+//
+// Number types with bounds become newtypes with a validate method checking
+// them, called by the validate method of the structs with fields of these
+// types. The other types are aliases of the Rust type of their base type.
+func GenerateRust(s *rdl.Schema, w io.Writer) error {
+	rw := &rustWriter{registry: rdl.NewTypeRegistry(s), uses: make(map[string]bool)}
+	for _, t := range s.Types {
+		if err := rw.declaration(t); err != nil {
+			return err
+		}
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\n", utils.GoGenerationHeader(banner))
+	rw.uses["serde::{Deserialize, Serialize}"] = true
+	var uses []string
+	for u := range rw.uses {
+		uses = append(uses, u)
+	}
+	sort.Strings(uses)
+	for _, u := range uses {
+		fmt.Fprintf(&out, "use %s;\n", u)
+	}
+	out.Write(rw.buf.Bytes())
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (rw *rustWriter) line(indent int, format string, args ...interface{}) {
+	rw.buf.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(&rw.buf, format, args...)
+	rw.buf.WriteString("\n")
+}
+
+func (rw *rustWriter) comment(indent int, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, l := range strings.Split(strings.TrimSpace(comment), "\n") {
+		rw.line(indent, "/// %s", strings.TrimSpace(l))
+	}
+}
+
+func (rw *rustWriter) declaration(t *rdl.Type) error {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	rw.buf.WriteString("\n")
+	rw.comment(0, tComment)
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		return rw.structDecl(t)
+	case rdl.TypeVariantEnumTypeDef:
+		rw.line(0, "#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]")
+		rw.line(0, "#[serde(rename_all = \"SCREAMING_SNAKE_CASE\")]")
+		rw.line(0, "pub enum %s {", tName)
+		for _, e := range t.EnumTypeDef.Elements {
+			rw.comment(1, e.Comment)
+			variant := pascalCase(string(e.Symbol))
+			if screamingSnakeCase(variant) != string(e.Symbol) {
+				rw.line(1, "#[serde(rename = %s)]", rustString(string(e.Symbol)))
+			}
+			rw.line(1, "%s,", variant)
+		}
+		rw.line(0, "}")
+	case rdl.TypeVariantUnionTypeDef:
+		rw.line(0, "#[derive(Debug, Clone, Serialize, Deserialize)]")
+		rw.line(0, "#[serde(untagged)]")
+		rw.line(0, "pub enum %s {", tName)
+		for _, v := range t.UnionTypeDef.Variants {
+			rw.line(1, "%s(%s),", pascalCase(string(v)), rw.typeExpr(v, "", ""))
+		}
+		rw.line(0, "}")
+	case rdl.TypeVariantArrayTypeDef:
+		rw.line(0, "pub type %s = %s;", tName, rw.typeExpr("Array", t.ArrayTypeDef.Items, ""))
+	case rdl.TypeVariantMapTypeDef:
+		rw.line(0, "pub type %s = %s;", tName, rw.typeExpr("Map", t.MapTypeDef.Items, t.MapTypeDef.Keys))
+	default:
+		if !rw.validated(rdl.TypeRef(tName)) {
+			rw.line(0, "pub type %s = %s;", tName, rw.typeExpr(tType, "", ""))
+			return nil
+		}
+		nt := t.NumberTypeDef
+		rw.line(0, "#[derive(Debug, Clone, Copy, PartialEq, PartialOrd, Serialize, Deserialize)]")
+		rw.line(0, "#[serde(transparent)]")
+		rw.line(0, "pub struct %s(pub %s);", tName, rw.typeExpr(tType, "", ""))
+		rw.buf.WriteString("\n")
+		rw.line(0, "impl %s {", tName)
+		rw.line(1, "pub fn validate(&self) -> Result<(), &str> {")
+		// A super type with bounds is a newtype too, validated first and
+		// unwrapped down to the number for the comparisons
+		value := "self.0"
+		if rw.validated(tType) {
+			rw.line(2, "self.0.validate()?;")
+			for ref := tType; rw.validated(ref); ref = rw.registry.FindType(ref).NumberTypeDef.Type {
+				value += ".0"
+			}
+		}
+		if nt.Min != nil {
+			rw.line(2, "if %s < %s {", value, numberLiteral(nt.Min))
+			rw.line(3, "return Err(%s);", rustString(fmt.Sprintf("%s: less than %s", tName, utils.NumberString(nt.Min))))
+			rw.line(2, "}")
+		}
+		if nt.Max != nil {
+			rw.line(2, "if %s > %s {", value, numberLiteral(nt.Max))
+			rw.line(3, "return Err(%s);", rustString(fmt.Sprintf("%s: greater than %s", tName, utils.NumberString(nt.Max))))
+			rw.line(2, "}")
+		}
+		rw.line(2, "Ok(())")
+		rw.line(1, "}")
+		rw.line(0, "}")
+	}
+	return nil
+}
+
+func (rw *rustWriter) structDecl(t *rdl.Type) error {
+	st := t.StructTypeDef
+	fields := utils.FlattenedFields(rw.registry, t)
+	rw.line(0, "#[derive(Debug, Clone, Serialize, Deserialize)]")
+	rw.line(0, "pub struct %s {", st.Name)
+	var defaults []string
+	var checks []*rdl.StructFieldDef
+	for _, f := range fields {
+		name := fieldName(string(f.Name))
+		typ := rw.typeExpr(f.Type, f.Items, f.Keys)
+		if f.Type == rdl.TypeRef(st.Name) {
+			typ = "Box<" + typ + ">"
+		}
+		if f.Optional {
+			typ = "Option<" + typ + ">"
+		}
+		rw.comment(1, f.Comment)
+		var attrs []string
+		if strings.TrimPrefix(name, "r#") != string(f.Name) {
+			attrs = append(attrs, "rename = "+rustString(string(f.Name)))
+		}
+		if f.Default != nil {
+			def, err := rw.literal(f)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+			}
+			fn := "default_" + strings.TrimPrefix(name, "r#")
+			attrs = append(attrs, fmt.Sprintf("default = \"%s::%s\"", st.Name, fn))
+			defaults = append(defaults, fmt.Sprintf("fn %s() -> %s {\n        %s\n    }", fn, typ, def))
+		} else if f.Optional {
+			attrs = append(attrs, "default")
+		}
+		if f.Optional {
+			attrs = append(attrs, "skip_serializing_if = \"Option::is_none\"")
+		}
+		if len(attrs) > 0 {
+			rw.line(1, "#[serde(%s)]", strings.Join(attrs, ", "))
+		}
+		rw.line(1, "pub %s: %s,", name, typ)
+		if rw.validated(f.Type) {
+			checks = append(checks, f)
+		}
+	}
+	rw.line(0, "}")
+	if len(defaults) == 0 && len(checks) == 0 {
+		return nil
+	}
+	rw.buf.WriteString("\n")
+	rw.line(0, "impl %s {", st.Name)
+	for i, d := range defaults {
+		if i > 0 {
+			rw.buf.WriteString("\n")
+		}
+		rw.line(1, "%s", d)
+	}
+	if len(checks) > 0 {
+		if len(defaults) > 0 {
+			rw.buf.WriteString("\n")
+		}
+		rw.line(1, "pub fn validate(&self) -> Result<(), &str> {")
+		for _, f := range checks {
+			name := fieldName(string(f.Name))
+			if f.Optional {
+				rw.line(2, "if let Some(v) = &self.%s {", name)
+				rw.line(3, "v.validate()?;")
+				rw.line(2, "}")
+			} else {
+				rw.line(2, "self.%s.validate()?;", name)
+			}
+		}
+		rw.line(2, "Ok(())")
+		rw.line(1, "}")
+	}
+	rw.line(0, "}")
+	return nil
+}
+
+// validated returns true if the type is a number type with bounds, which is
+// a newtype with a validate method.
+func (rw *rustWriter) validated(ref rdl.TypeRef) bool {
+	t := rw.registry.FindType(ref)
+	return t != nil && t.NumberTypeDef != nil && (t.NumberTypeDef.Min != nil || t.NumberTypeDef.Max != nil)
+}
+
+// typeExpr returns the Rust type of a reference to an RDL type. Base types
+// map to the Rust primitive and standard types, everything else is referenced
+// by name.
+func (rw *rustWriter) typeExpr(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch ref {
+	case "Bool":
+		return "bool"
+	case "Int8":
+		return "i8"
+	case "Int16":
+		return "i16"
+	case "Int32":
+		return "i32"
+	case "Int64":
+		return "i64"
+	case "Float32":
+		return "f32"
+	case "Float64":
+		return "f64"
+	case "String", "Symbol":
+		return "String"
+	case "Bytes":
+		return "Vec<u8>"
+	case "UUID":
+		rw.uses["uuid::Uuid"] = true
+		return "Uuid"
+	case "Timestamp":
+		rw.uses["chrono::{DateTime, Utc}"] = true
+		return "DateTime<Utc>"
+	case "Array":
+		if items == "" {
+			items = "Any"
+		}
+		return "Vec<" + rw.typeExpr(items, "", "") + ">"
+	case "Map":
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		rw.uses["std::collections::HashMap"] = true
+		return "HashMap<" + rw.typeExpr(keys, "", "") + ", " + rw.typeExpr(items, "", "") + ">"
+	case "Struct":
+		return "serde_json::Map<String, serde_json::Value>"
+	case "Any":
+		return "serde_json::Value"
+	}
+	return string(ref)
+}
+
+// fieldName returns the snake case name of a field, as a raw identifier if
+// it is a keyword.
+func fieldName(name string) string {
+	name = snakeCase(name)
+	if keywords[name] {
+		return "r#" + name
+	}
+	return name
+}
+
+// literal returns the Rust expression of a default value of a field.
+func (rw *rustWriter) literal(f *rdl.StructFieldDef) (string, error) {
+	var lit string
+	switch v := f.Default.(type) {
+	case bool:
+		lit = fmt.Sprint(v)
+	case string:
+		switch rw.registry.FindBaseType(f.Type) {
+		case rdl.BaseTypeEnum:
+			lit = rw.typeExpr(f.Type, "", "") + "::" + pascalCase(v)
+		case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+			lit = rustString(v) + ".to_string()"
+		default:
+			return "", fmt.Errorf("unsupported default value %q for %s", v, f.Type)
+		}
+	case int, int8, int16, int32, int64, float32, float64, json.Number:
+		lit = fmt.Sprint(v)
+		if rw.validated(f.Type) {
+			lit = fmt.Sprintf("%s(%s)", f.Type, lit)
+		}
+	default:
+		return "", fmt.Errorf("unsupported default value %v", v)
+	}
+	if f.Optional {
+		return "Some(" + lit + ")", nil
+	}
+	return lit, nil
+}
+
+// rustString returns s as a Rust string literal.
+func rustString(s string) string {
+	q, _ := json.Marshal(s)
+	return string(q)
+}
+
+// numberLiteral returns the Rust literal of a bound, with a decimal point for
+// floats.
+func numberLiteral(n *rdl.Number) string {
+	s := utils.NumberString(n)
+	if (n.Variant == rdl.NumberVariantFloat32 || n.Variant == rdl.NumberVariantFloat64) && !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// snakeCase returns the name in snake case.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// pascalCase returns the name in pascal case, as serde renames variants:
+// READ_ONLY and readOnly become ReadOnly.
+func pascalCase(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(snakeCase(name), "_") {
+		if word != "" {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// screamingSnakeCase returns the name serde serializes a variant as with
+// rename_all = "SCREAMING_SNAKE_CASE".
+func screamingSnakeCase(variant string) string {
+	var b strings.Builder
+	for i, r := range variant {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package sql exports the struct types of RDL schemas as SQL tables.
+package sql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+type sqlWriter struct {
+	registry rdl.TypeRegistry
+	dialect  string
+	tenants  map[rdl.TypeRef]bool
+	buf      bytes.Buffer
+}
+
+// GenerateSQL writes the struct types of the schema as CREATE TABLE
+// statements of the dialect, "postgres", "mysql" or "sqlite". Every field,
+// inherited ones first, is a column of the SQL type of its base type:
+// strings with a max size are VARCHAR(n), other strings TEXT, monetary
+// amounts DECIMAL(19,4), other numbers the numeric type of their size, and
+// arrays, maps and structs JSON where the dialect has it. Optional fields
+// are NULL, the others NOT NULL, and default values are column defaults,
+// which MySQL 8.0.13 or later takes for TEXT columns. Encrypted fields hold
+// their base64 ciphertext: they are TEXT columns without a default value.
+// The other types have no table, which a comment notes.
+//
+// The tables of the types of multi-tenant resources, and of their request
+// bodies, start with a tenant_id column, which is indexed, to isolate the
+// rows of each tenant.
+func GenerateSQL(s *rdl.Schema, dialect string, w io.Writer) error {
+	switch dialect {
+	case "postgres", "mysql", "sqlite":
+	default:
+		return fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	sw := &sqlWriter{registry: rdl.NewTypeRegistry(s), dialect: dialect, tenants: make(map[rdl.TypeRef]bool)}
+	for _, r := range s.Resources {
+		if r.MultiTenant == nil {
+			continue
+		}
+		refs := []rdl.TypeRef{r.Type}
+		for _, in := range r.Inputs {
+			if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+				refs = append(refs, in.Type)
+			}
+		}
+		for _, ref := range refs {
+			if t := sw.registry.FindType(ref); t != nil && t.ArrayTypeDef != nil {
+				ref = t.ArrayTypeDef.Items
+			}
+			sw.tenants[ref] = true
+		}
+	}
+	fmt.Fprintf(&sw.buf, "-- This file generated by %s for %s\n", banner, dialect)
+	for _, t := range s.Types {
+		tName, _, tComment := rdl.TypeInfo(t)
+		sw.buf.WriteString("\n")
+		if t.StructTypeDef == nil {
+			fmt.Fprintf(&sw.buf, "-- %s is not a struct, it has no table\n", tName)
+			continue
+		}
+		for _, l := range strings.Split(strings.TrimSpace(tComment), "\n") {
+			if l != "" {
+				fmt.Fprintf(&sw.buf, "-- %s\n", strings.TrimSpace(l))
+			}
+		}
+		sw.createTable(t)
+	}
+	_, err := w.Write(sw.buf.Bytes())
+	return err
+}
+
+func (sw *sqlWriter) createTable(t *rdl.Type) {
+	var columns []string
+	tenant := sw.tenants[rdl.TypeRef(t.StructTypeDef.Name)]
+	if tenant {
+		columns = append(columns, "\t"+sw.quote("tenant_id")+" VARCHAR(255) NOT NULL")
+	}
+	for _, f := range utils.FlattenedFields(sw.registry, t) {
+		columns = append(columns, "\t"+sw.column(f))
+	}
+	table := string(t.StructTypeDef.Name)
+	fmt.Fprintf(&sw.buf, "CREATE TABLE %s (\n%s\n);\n", sw.quote(table), strings.Join(columns, ",\n"))
+	if tenant {
+		fmt.Fprintf(&sw.buf, "CREATE INDEX %s ON %s (%s);\n", sw.quote(table+"_tenant_id"), sw.quote(table), sw.quote("tenant_id"))
+	}
+}
+
+func (sw *sqlWriter) quote(name string) string {
+	if sw.dialect == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+func (sw *sqlWriter) column(f *rdl.StructFieldDef) string {
+	sqlType := sw.sqlType(f.Type)
+	if f.Encrypted {
+		sqlType = "TEXT"
+	}
+	col := sw.quote(string(f.Name)) + " " + sqlType
+	if f.Optional {
+		col += " NULL"
+	} else {
+		col += " NOT NULL"
+	}
+	if f.Encrypted {
+		return col
+	}
+	var def string
+	switch v := f.Default.(type) {
+	case string:
+		def = "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case bool, int, int8, int16, int32, int64, float32, float64, json.Number:
+		def = fmt.Sprint(v)
+	default:
+		return col
+	}
+	//MySQL only takes literal defaults for columns of fixed size
+	if sw.dialect == "mysql" && (sqlType == "TEXT" || sqlType == "BLOB" || sqlType == "JSON") {
+		def = "(" + def + ")"
+	}
+	return col + " DEFAULT " + def
+}
+
+// sqlTypes are the column types of the RDL base types, by dialect.
+var sqlTypes = map[rdl.BaseType]map[string]string{
+	rdl.BaseTypeBool:      {"postgres": "BOOLEAN", "mysql": "BOOLEAN", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt8:      {"postgres": "SMALLINT", "mysql": "TINYINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt16:     {"postgres": "SMALLINT", "mysql": "SMALLINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt32:     {"postgres": "INTEGER", "mysql": "INT", "sqlite": "INTEGER"},
+	rdl.BaseTypeInt64:     {"postgres": "BIGINT", "mysql": "BIGINT", "sqlite": "INTEGER"},
+	rdl.BaseTypeFloat32:   {"postgres": "REAL", "mysql": "FLOAT", "sqlite": "REAL"},
+	rdl.BaseTypeFloat64:   {"postgres": "DOUBLE PRECISION", "mysql": "DOUBLE", "sqlite": "REAL"},
+	rdl.BaseTypeBytes:     {"postgres": "BYTEA", "mysql": "BLOB", "sqlite": "BLOB"},
+	rdl.BaseTypeTimestamp: {"postgres": "TIMESTAMP WITH TIME ZONE", "mysql": "DATETIME(3)", "sqlite": "TEXT"},
+	rdl.BaseTypeUUID:      {"postgres": "UUID", "mysql": "CHAR(36)", "sqlite": "TEXT"},
+}
+
+func (sw *sqlWriter) sqlType(ref rdl.TypeRef) string {
+	if utils.IsCurrency(sw.registry, ref) {
+		return "DECIMAL(19,4)"
+	}
+	bt := sw.registry.FindBaseType(ref)
+	if types, ok := sqlTypes[bt]; ok {
+		return types[sw.dialect]
+	}
+	switch bt {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol, rdl.BaseTypeEnum:
+		if t := sw.registry.FindType(ref); t != nil && t.StringTypeDef != nil && t.StringTypeDef.MaxSize != nil {
+			return fmt.Sprintf("VARCHAR(%d)", *t.StringTypeDef.MaxSize)
+		}
+		return "TEXT"
+	}
+	switch sw.dialect {
+	case "postgres":
+		return "JSONB"
+	case "mysql":
+		return "JSON"
+	}
+	return "TEXT"
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package sql
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateSQL(test *testing.T) {
+	for _, dialect := range []string{"postgres", "mysql", "sqlite"} {
+		var buf bytes.Buffer
+		if err := GenerateSQL(gentest.SampleSchema(), dialect, &buf); err != nil {
+			test.Fatalf("cannot generate %s SQL: %v", dialect, err)
+		}
+		expected, err := ioutil.ReadFile("../../testdata/sql/sample." + dialect + ".sql")
+		if err != nil {
+			test.Fatalf("cannot read golden file: %v", err)
+		}
+		if buf.String() != string(expected) {
+			test.Errorf("%s SQL not generated as expected, real: \n%s\n, expected: \n%s\n", dialect, buf.String(), string(expected))
+		}
+	}
+}
+
+func TestGenerateSQLDialect(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateSQL(gentest.SampleSchema(), "oracle", &buf); err == nil || err.Error() != `unsupported SQL dialect "oracle"` {
+		test.Errorf("expected an error for an unsupported dialect, got %v", err)
+	}
+}
+
+func TestGenerateSQLEncrypted(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStringTypeBuilder("Ssn").MaxSize(11).Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Patient").
+		Field("name", "String", false, nil, "").
+		Field("ssn", "Ssn", false, nil, "").
+		Field("notes", "String", true, "none", "").
+		EncryptedField("ssn").
+		EncryptedField("notes").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateSQL(gentest.MustBuild(sb), "postgres", &buf); err != nil {
+		test.Fatalf("cannot generate SQL: %v", err)
+	}
+	expected := "CREATE TABLE \"Patient\" (\n\t\"name\" TEXT NOT NULL,\n\t\"ssn\" TEXT NOT NULL,\n\t\"notes\" TEXT NULL\n);\n"
+	if !strings.Contains(buf.String(), expected) {
+		test.Errorf("encrypted columns not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), expected)
+	}
+}
+
+func TestGenerateSQLCurrency(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewNumberTypeBuilder("Int64", "Price").Currency(true).Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Price", "SalePrice").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Item").
+		Field("price", "Price", false, nil, "").
+		Field("sale", "SalePrice", true, nil, "").
+		Field("quantity", "Int32", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateSQL(gentest.MustBuild(sb), "postgres", &buf); err != nil {
+		test.Fatalf("cannot generate SQL: %v", err)
+	}
+	expected := "CREATE TABLE \"Item\" (\n\t\"price\" DECIMAL(19,4) NOT NULL,\n\t\"sale\" DECIMAL(19,4) NULL,\n\t\"quantity\" INTEGER NOT NULL\n);\n"
+	if !strings.Contains(buf.String(), expected) {
+		test.Errorf("currency columns not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), expected)
+	}
+}
+
+func TestGenerateSQLMultiTenant(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("sample")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Order").
+		Field("id", "String", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewArrayTypeBuilder("Array", "Orders").Items("Order").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Invoice").
+		Field("total", "Int64", false, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Product").
+		Field("name", "String", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Orders", "GET", "/orders").
+		MultiTenant("header", "X-Tenant-ID").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Order", "POST", "/invoices").
+		Input("invoice", "Invoice", false, "", "", false, nil, "").
+		MultiTenant("jwt", "tid").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Product", "GET", "/products").Build())
+	var buf bytes.Buffer
+	if err := GenerateSQL(gentest.MustBuild(sb), "mysql", &buf); err != nil {
+		test.Fatalf("cannot generate SQL: %v", err)
+	}
+	for _, expected := range []string{
+		"CREATE TABLE `Order` (\n\t`tenant_id` VARCHAR(255) NOT NULL,\n\t`id` TEXT NOT NULL\n);\nCREATE INDEX `Order_tenant_id` ON `Order` (`tenant_id`);\n",
+		"CREATE TABLE `Invoice` (\n\t`tenant_id` VARCHAR(255) NOT NULL,\n\t`total` BIGINT NOT NULL\n);\nCREATE INDEX `Invoice_tenant_id` ON `Invoice` (`tenant_id`);\n",
+		"CREATE TABLE `Product` (\n\t`name` TEXT NOT NULL\n);\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			test.Errorf("tenant columns not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), expected)
+		}
+	}
+}
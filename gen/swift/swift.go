@@ -0,0 +1,693 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package swift exports RDL schemas as Swift Codable types.
+package swift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+var keywords = map[string]bool{
+	"associatedtype": true, "break": true, "case": true, "catch": true, "class": true, "continue": true,
+	"default": true, "defer": true, "deinit": true, "do": true, "else": true, "enum": true,
+	"extension": true, "fallthrough": true, "false": true, "fileprivate": true, "for": true, "func": true,
+	"guard": true, "if": true, "import": true, "in": true, "init": true, "inout": true, "internal": true,
+	"is": true, "let": true, "nil": true, "operator": true, "private": true, "protocol": true,
+	"public": true, "repeat": true, "rethrows": true, "return": true, "self": true, "static": true,
+	"struct": true, "subscript": true, "super": true, "switch": true, "throw": true, "throws": true,
+	"true": true, "try": true, "typealias": true, "var": true, "where": true, "while": true,
+}
+
+type swiftWriter struct {
+	registry rdl.TypeRegistry
+	json     bool
+	indirect bool
+	buf      bytes.Buffer
+}
+
+// GenerateSwift writes the schema as Swift 5.5 source. Structs become Codable
+// structs with a public memberwise initializer, with the fields of their
+// super type inlined as Swift structs have no inheritance. The fields through
+// which a struct contains itself are kept on the heap in an Indirect box,
+// generated along, behind a computed property of the field type. Enums become
+// String enums, unions enums with a case per variant decoding the first
+// variant matching, and the other types type aliases, arrays of type [T] and
+// maps [K: V]. Optional fields are optionals, and fields with a default value
+// take it when missing from the decoded JSON and when omitted from the
+// initializer. Any and Struct are represented by a JSONValue enum generated
+// along.
+//
+// The resources get a static method of URLRequest building their request,
+// taking the base URL of the service and the inputs, optional ones last and
+// defaulting to nil, with the body encoded with dates in ISO 8601. Resources
+// with outputs get a struct reading the output headers from the response.
+func GenerateSwift(s *rdl.Schema, w io.Writer) error {
+	sw := &swiftWriter{registry: rdl.NewTypeRegistry(s)}
+	for _, t := range s.Types {
+		if err := sw.declaration(t); err != nil {
+			return err
+		}
+	}
+	if len(s.Resources) > 0 {
+		sw.buf.WriteString("\nextension URLRequest {\n")
+		for i, r := range s.Resources {
+			if i > 0 {
+				sw.buf.WriteString("\n")
+			}
+			sw.requestBuilder(r)
+		}
+		sw.buf.WriteString("}\n")
+		for _, r := range s.Resources {
+			sw.outputs(r)
+		}
+		sw.buf.WriteString(pathSegmentFunc)
+	}
+	if sw.indirect {
+		sw.buf.WriteString(indirectClass)
+	}
+	if sw.json {
+		sw.buf.WriteString(jsonValueEnum)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\nimport Foundation\n", utils.GoGenerationHeader(banner))
+	out.Write(sw.buf.Bytes())
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (sw *swiftWriter) line(indent int, format string, args ...interface{}) {
+	sw.buf.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(&sw.buf, format, args...)
+	sw.buf.WriteString("\n")
+}
+
+func (sw *swiftWriter) comment(indent int, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, l := range strings.Split(strings.TrimSpace(comment), "\n") {
+		sw.line(indent, "/// %s", strings.TrimSpace(l))
+	}
+}
+
+func (sw *swiftWriter) declaration(t *rdl.Type) error {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	sw.buf.WriteString("\n")
+	sw.comment(0, tComment)
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		return sw.structDeclaration(t)
+	case rdl.TypeVariantEnumTypeDef:
+		sw.line(0, "public enum %s: String, Codable {", tName)
+		for _, e := range t.EnumTypeDef.Elements {
+			sw.comment(1, e.Comment)
+			sw.line(1, "case %s = %q", identifier(camelCase(string(e.Symbol))), e.Symbol)
+		}
+		sw.line(0, "}")
+	case rdl.TypeVariantUnionTypeDef:
+		sw.union(t.UnionTypeDef)
+	case rdl.TypeVariantArrayTypeDef:
+		sw.line(0, "public typealias %s = %s", tName, sw.typeExpr("Array", t.ArrayTypeDef.Items, ""))
+	case rdl.TypeVariantMapTypeDef:
+		sw.line(0, "public typealias %s = %s", tName, sw.typeExpr("Map", t.MapTypeDef.Items, t.MapTypeDef.Keys))
+	default:
+		sw.line(0, "public typealias %s = %s", tName, sw.typeExpr(tType, "", ""))
+	}
+	return nil
+}
+
+// swiftField is a field of a struct as declared in Swift.
+type swiftField struct {
+	name     string
+	key      string
+	typ      string
+	def      string
+	comment  string
+	optional bool
+	boxed    bool
+}
+
+// structDeclaration writes a struct, with its memberwise initializer, and
+// its decoding when fields have default values or are boxed.
+func (sw *swiftWriter) structDeclaration(t *rdl.Type) error {
+	st := t.StructTypeDef
+	var fields []*swiftField
+	var defaults, boxed bool
+	for _, f := range utils.FlattenedFields(sw.registry, t) {
+		sf := &swiftField{
+			name:     identifier(string(f.Name)),
+			key:      string(f.Name),
+			typ:      sw.typeExpr(f.Type, f.Items, f.Keys),
+			comment:  f.Comment,
+			optional: f.Optional,
+			boxed:    sw.contains(f.Type, st.Name, make(map[rdl.TypeRef]bool)),
+		}
+		if f.Default != nil {
+			def, err := sw.literal(f)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", st.Name, f.Name, err)
+			}
+			sf.def = def
+			defaults = true
+		}
+		boxed = boxed || sf.boxed
+		fields = append(fields, sf)
+	}
+	sw.line(0, "public struct %s: Codable {", st.Name)
+	for _, f := range fields {
+		typ := f.typ
+		if f.optional {
+			typ += "?"
+		}
+		sw.comment(1, f.comment)
+		if f.boxed {
+			sw.indirect = true
+			sw.line(1, "public var %s: %s {", f.name, typ)
+			sw.line(2, "get { _%s.value }", f.key)
+			sw.line(2, "set { _%s = Indirect(newValue) }", f.key)
+			sw.line(1, "}")
+			sw.line(1, "private var _%s: Indirect<%s>", f.key, typ)
+		} else {
+			sw.line(1, "public var %s: %s", f.name, typ)
+		}
+	}
+	var params []string
+	for _, f := range fields {
+		param := f.name + ": " + f.typ
+		switch {
+		case f.optional && f.def != "":
+			param += "? = " + f.def
+		case f.optional:
+			param += "? = nil"
+		case f.def != "":
+			param += " = " + f.def
+		}
+		params = append(params, param)
+	}
+	sw.buf.WriteString("\n")
+	sw.line(1, "public init(%s) {", strings.Join(params, ", "))
+	for _, f := range fields {
+		if f.boxed {
+			sw.line(2, "_%s = Indirect(%s)", f.key, f.name)
+		} else {
+			sw.line(2, "self.%s = %s", f.name, f.name)
+		}
+	}
+	sw.line(1, "}")
+	if boxed {
+		keys := make([]string, len(fields))
+		for i, f := range fields {
+			keys[i] = f.name
+		}
+		sw.buf.WriteString("\n")
+		sw.line(1, "private enum CodingKeys: String, CodingKey {")
+		sw.line(2, "case %s", strings.Join(keys, ", "))
+		sw.line(1, "}")
+	}
+	if defaults || boxed {
+		sw.buf.WriteString("\n")
+		sw.line(1, "public init(from decoder: Decoder) throws {")
+		sw.line(2, "let container = try decoder.container(keyedBy: CodingKeys.self)")
+		for _, f := range fields {
+			decode := fmt.Sprintf("container.decode(%s.self, forKey: .%s)", f.typ, f.key)
+			if f.optional || f.def != "" {
+				decode = fmt.Sprintf("container.decodeIfPresent(%s.self, forKey: .%s)", f.typ, f.key)
+			}
+			if f.def != "" {
+				decode += " ?? " + f.def
+			}
+			if f.boxed {
+				sw.line(2, "_%s = try Indirect(%s)", f.key, decode)
+			} else {
+				sw.line(2, "%s = try %s", f.name, decode)
+			}
+		}
+		sw.line(1, "}")
+	}
+	if boxed {
+		sw.buf.WriteString("\n")
+		sw.line(1, "public func encode(to encoder: Encoder) throws {")
+		sw.line(2, "var container = encoder.container(keyedBy: CodingKeys.self)")
+		for _, f := range fields {
+			encode := "encode"
+			if f.optional {
+				encode = "encodeIfPresent"
+			}
+			sw.line(2, "try container.%s(%s, forKey: .%s)", encode, f.name, f.key)
+		}
+		sw.line(1, "}")
+	}
+	sw.line(0, "}")
+	return nil
+}
+
+// contains tells if a value of the type contains a value of the struct
+// inline, through the fields of structs and the variants of unions but not
+// through arrays and maps, which keep their elements on the heap.
+func (sw *swiftWriter) contains(ref rdl.TypeRef, name rdl.TypeName, visited map[rdl.TypeRef]bool) bool {
+	if visited[ref] {
+		return false
+	}
+	visited[ref] = true
+	t := sw.registry.FindType(ref)
+	if t == nil {
+		return false
+	}
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		if t.StructTypeDef.Name == name {
+			return true
+		}
+		for _, f := range utils.FlattenedFields(sw.registry, t) {
+			if sw.contains(f.Type, name, visited) {
+				return true
+			}
+		}
+	case rdl.TypeVariantUnionTypeDef:
+		for _, v := range t.UnionTypeDef.Variants {
+			if sw.contains(v, name, visited) {
+				return true
+			}
+		}
+	case rdl.TypeVariantAliasTypeDef:
+		return sw.contains(t.AliasTypeDef.Type, name, visited)
+	}
+	return false
+}
+
+// literal returns the Swift expression of the default value of a field.
+func (sw *swiftWriter) literal(f *rdl.StructFieldDef) (string, error) {
+	switch v := f.Default.(type) {
+	case bool:
+		return fmt.Sprint(v), nil
+	case string:
+		switch sw.registry.FindBaseType(f.Type) {
+		case rdl.BaseTypeEnum:
+			return "." + identifier(camelCase(v)), nil
+		case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+			return swiftString(v), nil
+		}
+		return "", fmt.Errorf("unsupported default value %q for %s", v, f.Type)
+	case int, int8, int16, int32, int64, float32, float64, json.Number:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unsupported default value %v", f.Default)
+}
+
+// swiftString returns s as a Swift string literal.
+func swiftString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// union writes an enum with a case per variant, decoded as the first variant
+// the value decodes as.
+func (sw *swiftWriter) union(ut *rdl.UnionTypeDef) {
+	sw.line(0, "public enum %s: Codable {", ut.Name)
+	for _, v := range ut.Variants {
+		sw.line(1, "case %s(%s)", identifier(camelCase(string(v))), sw.typeExpr(v, "", ""))
+	}
+	sw.buf.WriteString("\n")
+	sw.line(1, "public init(from decoder: Decoder) throws {")
+	sw.line(2, "let container = try decoder.singleValueContainer()")
+	for i, v := range ut.Variants {
+		keyword := "} else if"
+		if i == 0 {
+			keyword = "if"
+		}
+		sw.line(2, "%s let value = try? container.decode(%s.self) {", keyword, sw.typeExpr(v, "", ""))
+		sw.line(3, "self = .%s(value)", identifier(camelCase(string(v))))
+	}
+	if len(ut.Variants) > 0 {
+		sw.line(2, "} else {")
+		sw.line(3, "throw DecodingError.dataCorruptedError(in: container, debugDescription: %q)", "no variant of "+string(ut.Name)+" matches")
+		sw.line(2, "}")
+	} else {
+		sw.line(2, "throw DecodingError.dataCorruptedError(in: container, debugDescription: %q)", string(ut.Name)+" has no variants")
+	}
+	sw.line(1, "}")
+	sw.buf.WriteString("\n")
+	sw.line(1, "public func encode(to encoder: Encoder) throws {")
+	sw.line(2, "var container = encoder.singleValueContainer()")
+	sw.line(2, "switch self {")
+	for _, v := range ut.Variants {
+		sw.line(2, "case .%s(let value):", identifier(camelCase(string(v))))
+		sw.line(3, "try container.encode(value)")
+	}
+	sw.line(2, "}")
+	sw.line(1, "}")
+	sw.line(0, "}")
+}
+
+// requestBuilder writes the static method of URLRequest building the request
+// of a resource.
+func (sw *swiftWriter) requestBuilder(r *rdl.Resource) {
+	var params, optionalParams []string
+	var body *rdl.ResourceInput
+	for _, in := range r.Inputs {
+		if in.Context != "" {
+			continue
+		}
+		typ := sw.typeExpr(in.Type, "", "")
+		if in.Optional || in.Default != nil {
+			optionalParams = append(optionalParams, fmt.Sprintf("%s: %s? = nil", identifier(string(in.Name)), typ))
+		} else {
+			params = append(params, fmt.Sprintf("%s: %s", identifier(string(in.Name)), typ))
+		}
+		if !in.PathParam && in.QueryParam == "" && in.Header == "" {
+			body = in
+		}
+	}
+	params = append(append([]string{"baseURL: URL"}, params...), optionalParams...)
+	comment := fmt.Sprintf("%s %s", strings.ToUpper(r.Method), r.Path)
+	if r.Comment != "" {
+		comment = r.Comment + "\n" + comment
+	}
+	sw.comment(1, comment)
+	sw.line(1, "public static func %s(%s) throws -> URLRequest {", identifier(functionName(r)), strings.Join(params, ", "))
+	sw.line(2, "var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!")
+	sw.line(2, "components.percentEncodedPath += %s", sw.pathExpr(r))
+	var queryParams []*rdl.ResourceInput
+	for _, in := range r.Inputs {
+		if in.QueryParam != "" {
+			queryParams = append(queryParams, in)
+		}
+	}
+	if len(queryParams) > 0 {
+		sw.line(2, "var queryItems: [URLQueryItem] = []")
+		for _, in := range queryParams {
+			name := identifier(string(in.Name))
+			item := fmt.Sprintf("queryItems.append(URLQueryItem(name: %q, value: %s))", in.QueryParam, sw.stringExpr(in.Type, name))
+			if in.Optional || in.Default != nil {
+				sw.line(2, "if let %s = %s {", name, name)
+				sw.line(3, "%s", item)
+				sw.line(2, "}")
+			} else {
+				sw.line(2, "%s", item)
+			}
+		}
+		sw.line(2, "if !queryItems.isEmpty {")
+		sw.line(3, "components.queryItems = queryItems")
+		sw.line(2, "}")
+	}
+	sw.line(2, "var request = URLRequest(url: components.url!)")
+	sw.line(2, "request.httpMethod = %q", strings.ToUpper(r.Method))
+	for _, in := range r.Inputs {
+		if in.Header == "" {
+			continue
+		}
+		name := identifier(string(in.Name))
+		set := fmt.Sprintf("request.setValue(%s, forHTTPHeaderField: %q)", sw.stringExpr(in.Type, name), in.Header)
+		if in.Optional || in.Default != nil {
+			sw.line(2, "if let %s = %s {", name, name)
+			sw.line(3, "%s", set)
+			sw.line(2, "}")
+		} else {
+			sw.line(2, "%s", set)
+		}
+	}
+	if body != nil {
+		name := identifier(string(body.Name))
+		indent := 2
+		if body.Optional || body.Default != nil {
+			sw.line(2, "if let %s = %s {", name, name)
+			indent = 3
+		}
+		sw.line(indent, "let encoder = JSONEncoder()")
+		sw.line(indent, "encoder.dateEncodingStrategy = .iso8601")
+		sw.line(indent, "request.httpBody = try encoder.encode(%s)", name)
+		sw.line(indent, "request.setValue(\"application/json\", forHTTPHeaderField: \"Content-Type\")")
+		if indent == 3 {
+			sw.line(2, "}")
+		}
+	}
+	sw.line(2, "return request")
+	sw.line(1, "}")
+}
+
+// outputs writes the struct reading the output headers of a resource from
+// its response, as strings.
+func (sw *swiftWriter) outputs(r *rdl.Resource) {
+	if len(r.Outputs) == 0 {
+		return
+	}
+	name := utils.Capitalize(functionName(r)) + "Outputs"
+	sw.buf.WriteString("\n")
+	sw.comment(0, fmt.Sprintf("The output headers of %s %s.", strings.ToUpper(r.Method), r.Path))
+	sw.line(0, "public struct %s {", name)
+	for _, out := range r.Outputs {
+		sw.comment(1, out.Comment)
+		sw.line(1, "public var %s: String?", identifier(string(out.Name)))
+	}
+	sw.buf.WriteString("\n")
+	sw.line(1, "public init(response: HTTPURLResponse) {")
+	for _, out := range r.Outputs {
+		sw.line(2, "%s = response.value(forHTTPHeaderField: %q)", identifier(string(out.Name)), out.Header)
+	}
+	sw.line(1, "}")
+	sw.line(0, "}")
+}
+
+// pathExpr returns the Swift expression of the path of a resource, with the
+// path parameters substituted.
+func (sw *swiftWriter) pathExpr(r *rdl.Resource) string {
+	path := r.Path
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	types := make(map[string]rdl.TypeRef)
+	for _, in := range r.Inputs {
+		if in.PathParam {
+			types[string(in.Name)] = in.Type
+		}
+	}
+	var parts []string
+	for {
+		i := strings.Index(path, "{")
+		j := strings.Index(path, "}")
+		if i < 0 || j < i {
+			break
+		}
+		if i > 0 {
+			parts = append(parts, fmt.Sprintf("%q", path[:i]))
+		}
+		name := path[i+1 : j]
+		if k := strings.Index(name, ":"); k >= 0 {
+			name = name[:k]
+		}
+		parts = append(parts, fmt.Sprintf("pathSegment(%s)", sw.stringExpr(types[name], identifier(name))))
+		path = path[j+1:]
+	}
+	if path != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", path))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// stringExpr returns the Swift expression of the string representation of a
+// value of the type in paths, queries and headers.
+func (sw *swiftWriter) stringExpr(ref rdl.TypeRef, expr string) string {
+	switch sw.registry.FindBaseType(ref) {
+	case rdl.BaseTypeString, rdl.BaseTypeSymbol:
+		return expr
+	case rdl.BaseTypeEnum:
+		return expr + ".rawValue"
+	case rdl.BaseTypeUUID:
+		return expr + ".uuidString"
+	case rdl.BaseTypeTimestamp:
+		return "ISO8601DateFormatter().string(from: " + expr + ")"
+	}
+	return "String(describing: " + expr + ")"
+}
+
+// typeExpr returns the Swift type of a reference to an RDL type. Base types
+// map to the Swift standard and Foundation types, everything else is
+// referenced by name.
+func (sw *swiftWriter) typeExpr(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch ref {
+	case "Bool":
+		return "Bool"
+	case "Int8", "Int16", "Int32", "Int64":
+		return string(ref)
+	case "Float32":
+		return "Float"
+	case "Float64":
+		return "Double"
+	case "String", "Symbol":
+		return "String"
+	case "Bytes":
+		return "Data"
+	case "UUID":
+		return "UUID"
+	case "Timestamp":
+		return "Date"
+	case "Array":
+		if items == "" {
+			items = "Any"
+		}
+		return "[" + sw.typeExpr(items, "", "") + "]"
+	case "Map":
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		return "[" + sw.typeExpr(keys, "", "") + ": " + sw.typeExpr(items, "", "") + "]"
+	case "Struct":
+		sw.json = true
+		return "[String: JSONValue]"
+	case "Any":
+		sw.json = true
+		return "JSONValue"
+	}
+	return string(ref)
+}
+
+// functionName returns the name of the resource, or its method followed by
+// the type of its body.
+func functionName(r *rdl.Resource) string {
+	if r.Name != "" {
+		return utils.Uncapitalize(string(r.Name))
+	}
+	bodyType := r.Type
+	for _, in := range r.Inputs {
+		if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+			bodyType = in.Type
+		}
+	}
+	return strings.ToLower(r.Method) + utils.Capitalize(strings.Replace(string(bodyType), ".", "", -1))
+}
+
+// identifier returns the name, escaped with backticks if it is a keyword.
+func identifier(name string) string {
+	if keywords[name] {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// camelCase returns the name in lower camel case: READ_ONLY and ReadOnly
+// become readOnly.
+func camelCase(name string) string {
+	var b strings.Builder
+	for i, word := range strings.Split(snakeCase(name), "_") {
+		if i > 0 && word != "" {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// snakeCase returns the name in snake case.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+const pathSegmentFunc = `
+/// pathSegment percent-encodes a path parameter.
+fileprivate func pathSegment(_ value: String) -> String {
+    var allowed = CharacterSet.urlPathAllowed
+    allowed.remove("/")
+    return value.addingPercentEncoding(withAllowedCharacters: allowed) ?? value
+}
+`
+
+const indirectClass = `
+/// Indirect keeps a value on the heap, so that structs can contain themselves.
+public final class Indirect<Value> {
+    public let value: Value
+
+    public init(_ value: Value) {
+        self.value = value
+    }
+}
+`
+
+const jsonValueEnum = `
+/// JSONValue is any JSON value.
+public enum JSONValue: Codable {
+    case null
+    case bool(Bool)
+    case number(Double)
+    case string(String)
+    case array([JSONValue])
+    case object([String: JSONValue])
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.singleValueContainer()
+        if container.decodeNil() {
+            self = .null
+        } else if let value = try? container.decode(Bool.self) {
+            self = .bool(value)
+        } else if let value = try? container.decode(Double.self) {
+            self = .number(value)
+        } else if let value = try? container.decode(String.self) {
+            self = .string(value)
+        } else if let value = try? container.decode([JSONValue].self) {
+            self = .array(value)
+        } else {
+            self = .object(try container.decode([String: JSONValue].self))
+        }
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.singleValueContainer()
+        switch self {
+        case .null:
+            try container.encodeNil()
+        case .bool(let value):
+            try container.encode(value)
+        case .number(let value):
+            try container.encode(value)
+        case .string(let value):
+            try container.encode(value)
+        case .array(let value):
+            try container.encode(value)
+        case .object(let value):
+            try container.encode(value)
+        }
+    }
+}
+`
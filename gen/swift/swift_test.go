@@ -0,0 +1,55 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package swift
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateSwift(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateSwift(gentest.SampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate Swift: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/swift/sample.swift")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("Swift not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
+
+func TestGenerateSwiftIndirect(test *testing.T) {
+	sb := rdl.NewSchemaBuilder("tree")
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Node").
+		Field("label", "String", false, "a \"b\"\n", "").
+		Field("edge", "Edge", true, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Edge").
+		Field("to", "Node", false, nil, "").
+		Build())
+	var buf bytes.Buffer
+	if err := GenerateSwift(gentest.MustBuild(sb), &buf); err != nil {
+		test.Fatalf("cannot generate Swift: %v", err)
+	}
+	for _, expected := range []string{
+		"    private var _edge: Indirect<Edge?>\n",
+		"    private var _to: Indirect<Node>\n",
+		"    public init(label: String = \"a \\\"b\\\"\\n\", edge: Edge? = nil) {\n",
+		"        label = try container.decodeIfPresent(String.self, forKey: .label) ?? \"a \\\"b\\\"\\n\"\n",
+		"        _to = try Indirect(container.decode(Node.self, forKey: .to))\n",
+		"public final class Indirect<Value> {\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			test.Errorf("generated Swift is missing %q:\n%s", expected, buf.String())
+		}
+	}
+}
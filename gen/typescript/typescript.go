@@ -0,0 +1,184 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package typescript exports RDL schemas as TypeScript declarations.
+package typescript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ardielle/ardielle-go/rdl"
+	"github.com/yahoo/parsec-rdl-gen/utils"
+)
+
+const banner = "parsec-rdl-gen"
+
+type tsWriter struct {
+	buf bytes.Buffer
+}
+
+// GenerateTypeScript writes the schema as a TypeScript declaration file, in
+// the style of a .d.ts file. Structs become interfaces, extending their super
+// type if any, with optional fields marked with ?; enums become string enums,
+// unions union types, and the other types type aliases, arrays of type
+// Array<T> and maps Record<K, V>. Every resource gets the signature of a
+// function performing its request with fetch, taking the inputs, required
+// ones first, and the RequestInit of the request, and resolving to the
+// response.
+func GenerateTypeScript(s *rdl.Schema, w io.Writer) error {
+	tw := &tsWriter{}
+	fmt.Fprintf(&tw.buf, "%s\n", utils.GoGenerationHeader(banner))
+	for _, t := range s.Types {
+		tw.declaration(t)
+	}
+	for _, r := range s.Resources {
+		tw.function(r)
+	}
+	_, err := w.Write(tw.buf.Bytes())
+	return err
+}
+
+func (tw *tsWriter) comment(indent string, comment string) {
+	if comment == "" {
+		return
+	}
+	lines := strings.Split(comment, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(&tw.buf, "%s/** %s */\n", indent, strings.TrimSpace(comment))
+		return
+	}
+	fmt.Fprintf(&tw.buf, "%s/**\n", indent)
+	for _, l := range lines {
+		fmt.Fprintf(&tw.buf, "%s * %s\n", indent, strings.TrimSpace(l))
+	}
+	fmt.Fprintf(&tw.buf, "%s */\n", indent)
+}
+
+func (tw *tsWriter) declaration(t *rdl.Type) {
+	tName, tType, tComment := rdl.TypeInfo(t)
+	tw.buf.WriteString("\n")
+	tw.comment("", tComment)
+	switch t.Variant {
+	case rdl.TypeVariantStructTypeDef:
+		if tType == "Struct" {
+			fmt.Fprintf(&tw.buf, "export interface %s {\n", tName)
+		} else {
+			fmt.Fprintf(&tw.buf, "export interface %s extends %s {\n", tName, tType)
+		}
+		for _, f := range t.StructTypeDef.Fields {
+			tw.comment("  ", f.Comment)
+			optional := ""
+			if f.Optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&tw.buf, "  %s%s: %s;\n", f.Name, optional, tw.typeExpr(f.Type, f.Items, f.Keys))
+		}
+		tw.buf.WriteString("}\n")
+	case rdl.TypeVariantEnumTypeDef:
+		fmt.Fprintf(&tw.buf, "export enum %s {\n", tName)
+		for _, e := range t.EnumTypeDef.Elements {
+			tw.comment("  ", e.Comment)
+			fmt.Fprintf(&tw.buf, "  %s = %q,\n", e.Symbol, e.Symbol)
+		}
+		tw.buf.WriteString("}\n")
+	case rdl.TypeVariantUnionTypeDef:
+		var variants []string
+		for _, v := range t.UnionTypeDef.Variants {
+			variants = append(variants, tw.typeExpr(v, "", ""))
+		}
+		fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, strings.Join(variants, " | "))
+	case rdl.TypeVariantArrayTypeDef:
+		fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, tw.typeExpr("Array", t.ArrayTypeDef.Items, ""))
+	case rdl.TypeVariantMapTypeDef:
+		fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, tw.typeExpr("Map", t.MapTypeDef.Items, t.MapTypeDef.Keys))
+	case rdl.TypeVariantStringTypeDef:
+		if values := t.StringTypeDef.Values; len(values) > 0 {
+			var literals []string
+			for _, v := range values {
+				literals = append(literals, fmt.Sprintf("%q", v))
+			}
+			fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, strings.Join(literals, " | "))
+			return
+		}
+		fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, tw.typeExpr(tType, "", ""))
+	default:
+		fmt.Fprintf(&tw.buf, "export type %s = %s;\n", tName, tw.typeExpr(tType, "", ""))
+	}
+}
+
+// typeExpr returns the TypeScript type of a reference to an RDL type. Base
+// types map to the TypeScript primitive types, everything else is referenced
+// by name.
+func (tw *tsWriter) typeExpr(ref rdl.TypeRef, items rdl.TypeRef, keys rdl.TypeRef) string {
+	switch ref {
+	case "Bool":
+		return "boolean"
+	case "Int8", "Int16", "Int32", "Int64", "Float32", "Float64":
+		return "number"
+	case "String", "Symbol", "UUID", "Timestamp", "Bytes":
+		return "string"
+	case "Array":
+		if items == "" {
+			items = "Any"
+		}
+		return "Array<" + tw.typeExpr(items, "", "") + ">"
+	case "Map":
+		if keys == "" {
+			keys = "String"
+		}
+		if items == "" {
+			items = "Any"
+		}
+		return "Record<" + tw.typeExpr(keys, "", "") + ", " + tw.typeExpr(items, "", "") + ">"
+	case "Struct":
+		return "Record<string, any>"
+	case "Any":
+		return "any"
+	}
+	return string(ref)
+}
+
+// function writes the signature of the fetch wrapper of a resource.
+func (tw *tsWriter) function(r *rdl.Resource) {
+	var params, optionalParams []string
+	for _, in := range r.Inputs {
+		if in.Context != "" {
+			continue
+		}
+		if in.Optional || in.Default != nil {
+			optionalParams = append(optionalParams, fmt.Sprintf("%s?: %s", in.Name, tw.typeExpr(in.Type, "", "")))
+		} else {
+			params = append(params, fmt.Sprintf("%s: %s", in.Name, tw.typeExpr(in.Type, "", "")))
+		}
+	}
+	params = append(append(params, optionalParams...), "init?: RequestInit")
+	result := tw.typeExpr(r.Type, "", "")
+	if code := rdl.StatusCode(r.Expected); code == "204" || code == "304" {
+		result = "void"
+	}
+	tw.buf.WriteString("\n")
+	comment := fmt.Sprintf("%s %s", strings.ToUpper(r.Method), r.Path)
+	if r.Comment != "" {
+		comment = r.Comment + "\n" + comment
+	}
+	tw.comment("", comment)
+	fmt.Fprintf(&tw.buf, "export function %s(%s): Promise<%s>;\n", functionName(r), strings.Join(params, ", "), result)
+}
+
+// functionName returns the name of the resource, or its method followed by
+// the type of its body.
+func functionName(r *rdl.Resource) string {
+	if r.Name != "" {
+		return utils.Uncapitalize(string(r.Name))
+	}
+	bodyType := r.Type
+	for _, in := range r.Inputs {
+		if in.QueryParam == "" && !in.PathParam && in.Header == "" && in.Context == "" {
+			bodyType = in.Type
+		}
+	}
+	return strings.ToLower(r.Method) + utils.Capitalize(strings.Replace(string(bodyType), ".", "", -1))
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+package typescript
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/yahoo/parsec-rdl-gen/internal/gentest"
+)
+
+func TestGenerateTypeScript(test *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateTypeScript(gentest.SampleSchema(), &buf); err != nil {
+		test.Fatalf("cannot generate TypeScript: %v", err)
+	}
+	expected, err := ioutil.ReadFile("../../testdata/typescript/sample.d.ts")
+	if err != nil {
+		test.Fatalf("cannot read golden file: %v", err)
+	}
+	if buf.String() != string(expected) {
+		test.Errorf("TypeScript not generated as expected, real: \n%s\n, expected: \n%s\n", buf.String(), string(expected))
+	}
+}
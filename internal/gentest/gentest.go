@@ -0,0 +1,101 @@
+// Copyright 2016 Yahoo Inc.
+// Licensed under the terms of the Apache license. Please see LICENSE.md file distributed with this work for terms.
+
+// Package gentest holds the schema fixtures shared by the generator tests.
+package gentest
+
+import (
+	"github.com/ardielle/ardielle-go/rdl"
+)
+
+// MustBuild builds a test schema, panicking on errors.
+func MustBuild(sb *rdl.SchemaBuilder) *rdl.Schema {
+	schema, err := sb.Build()
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// SampleSchema returns the schema the generator goldens in testdata/<gen>/sample.*
+// are generated from. It covers every kind of type and resource input the
+// generators handle, so a change to it means regenerating all the goldens.
+func SampleSchema() *rdl.Schema {
+	sb := rdl.NewSchemaBuilder("sample").Namespace("com.example").Version(2).Comment("The sample service")
+	sb.AddType(rdl.NewStringTypeBuilder("UserId").Pattern("[a-z][a-z0-9]*").MaxSize(32).Build())
+	sb.AddType(rdl.NewStringTypeBuilder("Region").Values("us-east", "us-west").Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Int32", "Age").Min(rdl.NewNumber(int32(0))).Max(rdl.NewNumber(int32(150))).Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Age", "Adult").Min(rdl.NewNumber(int32(18))).Build())
+	sb.AddType(rdl.NewNumberTypeBuilder("Float64", "Score").Min(rdl.NewNumber(0.5)).Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Role").Element("ADMIN", "").Element("MEMBER", "a regular user").Element("READ_ONLY", "").Build())
+	sb.AddType(rdl.NewEnumTypeBuilder("Enum", "Consent").Element("YES", "").Element("NO", "").Element("NULL", "").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "User").
+		Comment("A user of the service").
+		Field("id", "UserId", false, nil, "the user id").
+		Field("role", "Role", false, "MEMBER", "").
+		Field("age", "Age", true, nil, "the age").
+		Field("score", "Score", false, nil, "").
+		Field("active", "Bool", false, true, "").
+		Field("nickname", "String", true, "o'brien", "").
+		Field("type", "String", true, nil, "").
+		Field("default", "Bool", true, nil, "").
+		ArrayField("tags", "String", true, "").
+		MapField("labels", "String", "Int64", true, "").
+		Field("created", "Timestamp", false, nil, "").
+		Field("avatar", "Bytes", true, nil, "").
+		Field("extra", "Any", true, nil, "").
+		Field("manager", "User", true, nil, "").
+		LogChange(2, "tags", "added", "").
+		Build())
+	admin := rdl.NewStructTypeBuilder("User", "Admin").
+		Field("level", "Int32", false, nil, "").
+		Field("region", "Region", false, nil, "").
+		Field("sessionKey", "UUID", false, nil, "").
+		Build()
+	admin.StructTypeDef.Closed = true
+	sb.AddType(admin)
+	sb.AddType(rdl.NewArrayTypeBuilder("Array", "Users").Items("User").MaxSize(100).Build())
+	sb.AddType(rdl.NewMapTypeBuilder("Map", "UserIndex").Keys("UserId").Items("User").Build())
+	sb.AddType(rdl.NewUnionTypeBuilder("Union", "Member").VariantWithComment("User", "a user").Variant("Admin").Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "Group").
+		Field("name", "String", false, nil, "").
+		Field("members", "Users", false, nil, "").
+		Field("owner", "Member", true, nil, "").
+		Field("parent", "Group", true, nil, "").
+		Build())
+	sb.AddType(rdl.NewStructTypeBuilder("Struct", "ResourceError").
+		Field("code", "Int32", false, nil, "").
+		Field("message", "String", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "GET", "/users/{id}").
+		Comment("Get a user").
+		Input("id", "UserId", true, "", "", false, nil, "the user id").
+		Input("fields", "String", false, "fields", "", true, nil, "fields to return").
+		Header("ifNoneMatch", "String", "If-None-Match", true, "").
+		Output("etag", "String", "ETag", false, "the version of the user").
+		Auth("read", "sample:users", false, "").
+		Alternative("NOT_MODIFIED").
+		Exception("NOT_FOUND", "ResourceError", "no such user").
+		Exception("BAD_REQUEST", "ResourceError", "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "POST", "/users").
+		Input("user", "User", false, "", "", false, nil, "the user").
+		Input("requestId", "String", false, "", "X-Request-Id", true, nil, "").
+		Auth("", "", true, "").
+		Expected("CREATED").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("Users", "GET", "/users?limit={limit}").
+		Name("listUsers").
+		Input("limit", "Int32", false, "limit", "", true, 20, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "PUT", "/users/{id}").
+		Input("id", "UserId", true, "", "", false, nil, "").
+		Input("user", "User", false, "", "", false, nil, "").
+		Build())
+	sb.AddResource(rdl.NewResourceBuilder("User", "DELETE", "/users/{id}").
+		Name("RemoveUser").
+		Input("id", "UserId", true, "", "", false, nil, "").
+		Expected("NO_CONTENT").
+		Build())
+	return MustBuild(sb)
+}
@@ -0,0 +1,151 @@
+//
+// This file generated by parsec-rdl-gen
+//
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/titanous/json5"
+)
+
+// UnmarshalJSON5 decodes a JSON5 document, which may have comments, trailing
+// commas, unquoted keys and single-quoted strings, into the Server.
+func (self *Server) UnmarshalJSON5(data []byte) error {
+	j, err := json5ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, self)
+}
+
+// MarshalJSON5 encodes the Server as an indented JSON5 document.
+func (self *Server) MarshalJSON5() ([]byte, error) {
+	j, err := json.Marshal(self)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToJSON5(j)
+}
+
+// json5ToJSON translates a JSON5 document to JSON, keeping its numbers as
+// written.
+func json5ToJSON(data []byte) ([]byte, error) {
+	dec := json5.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(json5Numbers(v))
+}
+
+// json5Numbers replaces the JSON5 numbers of a decoded value with JSON ones.
+func json5Numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json5.Number:
+		return json.Number(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = json5Numbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = json5Numbers(item)
+		}
+	}
+	return v
+}
+
+// jsonToJSON5 translates a JSON document to indented JSON5, in the order of
+// the document.
+func jsonToJSON5(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeJSON5(&buf, dec, tok, 0); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON document: data after the value")
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeJSON5(buf *bytes.Buffer, dec *json.Decoder, tok json.Token, depth int) error {
+	switch tok := tok.(type) {
+	case json.Delim:
+		buf.WriteByte(byte(tok))
+		empty := true
+		for dec.More() {
+			if empty {
+				buf.WriteByte('\n')
+				empty = false
+			}
+			buf.WriteString(strings.Repeat("  ", depth+1))
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writeJSON5Key(buf, key.(string))
+				buf.WriteString(": ")
+			}
+			item, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeJSON5(buf, dec, item, depth+1); err != nil {
+				return err
+			}
+			buf.WriteString(",\n")
+		}
+		end, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			buf.WriteString(strings.Repeat("  ", depth))
+		}
+		buf.WriteByte(byte(end.(json.Delim)))
+	case string:
+		s, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		buf.Write(s)
+	case nil:
+		buf.WriteString("null")
+	default:
+		fmt.Fprint(buf, tok)
+	}
+	return nil
+}
+
+// writeJSON5Key writes an object key, unquoted when it is an identifier.
+func writeJSON5Key(buf *bytes.Buffer, key string) {
+	ident := key != ""
+	for i, r := range key {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || r == '_' || r == '$' || i > 0 && unicode.IsDigit(r)) {
+			ident = false
+			break
+		}
+	}
+	if ident {
+		buf.WriteString(key)
+		return
+	}
+	s, _ := json.Marshal(key)
+	buf.Write(s)
+}
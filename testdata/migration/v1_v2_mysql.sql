@@ -0,0 +1,23 @@
+-- Migration of the accounts schema from version 1 to version 2, generated by parsec-rdl-gen
+
+-- +migrate Up
+CREATE TABLE `Admin` (
+	`id` CHAR(36) NOT NULL,
+	`name` TEXT NOT NULL,
+	`age` BIGINT NOT NULL DEFAULT 0,
+	`email` VARCHAR(254) NOT NULL DEFAULT '',
+	`verified` BOOLEAN NULL,
+	`roles` JSON NOT NULL,
+	`since` DATETIME(3) NOT NULL
+);
+ALTER TABLE `User` ADD COLUMN `email` VARCHAR(254) NOT NULL DEFAULT '';
+ALTER TABLE `User` ADD COLUMN `verified` BOOLEAN NULL;
+ALTER TABLE `User` MODIFY COLUMN `age` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `User` DROP COLUMN `nickname`;
+
+-- +migrate Down
+ALTER TABLE `User` ADD COLUMN `nickname` TEXT NULL;
+ALTER TABLE `User` MODIFY COLUMN `age` INT NULL;
+ALTER TABLE `User` DROP COLUMN `verified`;
+ALTER TABLE `User` DROP COLUMN `email`;
+DROP TABLE `Admin`;
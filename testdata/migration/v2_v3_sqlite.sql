@@ -0,0 +1,4 @@
+-- Migration of the accounts schema from version 2 to version 3, generated by parsec-rdl-gen
+
+-- +migrate Up
+DROP TABLE "Admin";
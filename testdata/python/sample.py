@@ -0,0 +1,138 @@
+#
+# This file generated by parsec-rdl-gen
+#
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+from datetime import datetime
+from enum import Enum
+import re
+from typing import Any, Optional, Union
+from uuid import UUID
+
+
+UserId = str
+
+
+def validate_user_id(value: UserId) -> None:
+    """Raises ValueError if the value is not a valid UserId."""
+    if re.fullmatch("[a-z][a-z0-9]*", value) is None:
+        raise ValueError(f"invalid UserId: {value!r}")
+    if len(value) > 32:
+        raise ValueError(f"invalid UserId: {value!r}")
+
+
+Region = str
+
+
+def validate_region(value: Region) -> None:
+    """Raises ValueError if the value is not a valid Region."""
+    if value not in {"us-east", "us-west"}:
+        raise ValueError(f"invalid Region: {value!r}")
+
+
+Age = int
+
+
+def validate_age(value: Age) -> None:
+    """Raises ValueError if the value is not a valid Age."""
+    if value < 0:
+        raise ValueError(f"invalid Age: {value!r}")
+    if value > 150:
+        raise ValueError(f"invalid Age: {value!r}")
+
+
+Adult = Age
+
+
+def validate_adult(value: Adult) -> None:
+    """Raises ValueError if the value is not a valid Adult."""
+    validate_age(value)
+    if value < 18:
+        raise ValueError(f"invalid Adult: {value!r}")
+
+
+Score = float
+
+
+def validate_score(value: Score) -> None:
+    """Raises ValueError if the value is not a valid Score."""
+    if value < 0.5:
+        raise ValueError(f"invalid Score: {value!r}")
+
+
+class Role(str, Enum):
+    ADMIN = "ADMIN"
+    MEMBER = "MEMBER"
+    READ_ONLY = "READ_ONLY"
+
+
+class Consent(str, Enum):
+    YES = "YES"
+    NO = "NO"
+    NULL = "NULL"
+
+
+@dataclass(kw_only=True)
+class User:
+    """A user of the service"""
+    id: UserId
+    """the user id"""
+    role: Role = field(default="MEMBER")
+    age: Optional[Age] = None
+    """the age"""
+    score: Score
+    active: bool = field(default=True)
+    nickname: Optional[str] = field(default="o'brien")
+    type: Optional[str] = None
+    default: Optional[bool] = None
+    tags: Optional[list[str]] = None
+    labels: Optional[dict[str, int]] = None
+    created: datetime
+    avatar: Optional[bytes] = None
+    extra: Optional[Any] = None
+    manager: Optional[User] = None
+
+    def __post_init__(self) -> None:
+        validate_user_id(self.id)
+        if self.age is not None:
+            validate_age(self.age)
+        validate_score(self.score)
+
+
+@dataclass(kw_only=True)
+class Admin(User):
+    level: int
+    region: Region
+    sessionKey: UUID
+
+    def __post_init__(self) -> None:
+        validate_user_id(self.id)
+        if self.age is not None:
+            validate_age(self.age)
+        validate_score(self.score)
+        validate_region(self.region)
+
+
+Users = list[User]
+
+
+UserIndex = dict[UserId, User]
+
+
+Member = Union[User, Admin]
+
+
+@dataclass(kw_only=True)
+class Group:
+    name: str
+    members: Users
+    owner: Optional[Member] = None
+    parent: Optional[Group] = None
+
+
+@dataclass(kw_only=True)
+class ResourceError:
+    code: int
+    message: str
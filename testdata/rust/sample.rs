@@ -0,0 +1,210 @@
+//
+// This file generated by parsec-rdl-gen
+//
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+use uuid::Uuid;
+
+pub type UserId = String;
+
+pub type Region = String;
+
+#[derive(Debug, Clone, Copy, PartialEq, PartialOrd, Serialize, Deserialize)]
+#[serde(transparent)]
+pub struct Age(pub i32);
+
+impl Age {
+    pub fn validate(&self) -> Result<(), &str> {
+        if self.0 < 0 {
+            return Err("Age: less than 0");
+        }
+        if self.0 > 150 {
+            return Err("Age: greater than 150");
+        }
+        Ok(())
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, PartialOrd, Serialize, Deserialize)]
+#[serde(transparent)]
+pub struct Adult(pub Age);
+
+impl Adult {
+    pub fn validate(&self) -> Result<(), &str> {
+        self.0.validate()?;
+        if self.0.0 < 18 {
+            return Err("Adult: less than 18");
+        }
+        Ok(())
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, PartialOrd, Serialize, Deserialize)]
+#[serde(transparent)]
+pub struct Score(pub f64);
+
+impl Score {
+    pub fn validate(&self) -> Result<(), &str> {
+        if self.0 < 0.5 {
+            return Err("Score: less than 0.5");
+        }
+        Ok(())
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "SCREAMING_SNAKE_CASE")]
+pub enum Role {
+    Admin,
+    /// a regular user
+    Member,
+    ReadOnly,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "SCREAMING_SNAKE_CASE")]
+pub enum Consent {
+    Yes,
+    No,
+    Null,
+}
+
+/// A user of the service
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct User {
+    /// the user id
+    pub id: UserId,
+    #[serde(default = "User::default_role")]
+    pub role: Role,
+    /// the age
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub age: Option<Age>,
+    pub score: Score,
+    #[serde(default = "User::default_active")]
+    pub active: bool,
+    #[serde(default = "User::default_nickname", skip_serializing_if = "Option::is_none")]
+    pub nickname: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub r#type: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tags: Option<Vec<String>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub labels: Option<HashMap<String, i64>>,
+    pub created: DateTime<Utc>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub avatar: Option<Vec<u8>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub extra: Option<serde_json::Value>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub manager: Option<Box<User>>,
+}
+
+impl User {
+    fn default_role() -> Role {
+        Role::Member
+    }
+
+    fn default_active() -> bool {
+        true
+    }
+
+    fn default_nickname() -> Option<String> {
+        Some("o'brien".to_string())
+    }
+
+    pub fn validate(&self) -> Result<(), &str> {
+        if let Some(v) = &self.age {
+            v.validate()?;
+        }
+        self.score.validate()?;
+        Ok(())
+    }
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Admin {
+    /// the user id
+    pub id: UserId,
+    #[serde(default = "Admin::default_role")]
+    pub role: Role,
+    /// the age
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub age: Option<Age>,
+    pub score: Score,
+    #[serde(default = "Admin::default_active")]
+    pub active: bool,
+    #[serde(default = "Admin::default_nickname", skip_serializing_if = "Option::is_none")]
+    pub nickname: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub r#type: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tags: Option<Vec<String>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub labels: Option<HashMap<String, i64>>,
+    pub created: DateTime<Utc>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub avatar: Option<Vec<u8>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub extra: Option<serde_json::Value>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub manager: Option<User>,
+    pub level: i32,
+    pub region: Region,
+    #[serde(rename = "sessionKey")]
+    pub session_key: Uuid,
+}
+
+impl Admin {
+    fn default_role() -> Role {
+        Role::Member
+    }
+
+    fn default_active() -> bool {
+        true
+    }
+
+    fn default_nickname() -> Option<String> {
+        Some("o'brien".to_string())
+    }
+
+    pub fn validate(&self) -> Result<(), &str> {
+        if let Some(v) = &self.age {
+            v.validate()?;
+        }
+        self.score.validate()?;
+        Ok(())
+    }
+}
+
+pub type Users = Vec<User>;
+
+pub type UserIndex = HashMap<UserId, User>;
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(untagged)]
+pub enum Member {
+    User(User),
+    Admin(Admin),
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Group {
+    pub name: String,
+    pub members: Users,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub owner: Option<Member>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub parent: Option<Box<Group>>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ResourceError {
+    pub code: i32,
+    pub message: String,
+}
@@ -0,0 +1,71 @@
+-- This file generated by parsec-rdl-gen for mysql
+
+-- UserId is not a struct, it has no table
+
+-- Region is not a struct, it has no table
+
+-- Age is not a struct, it has no table
+
+-- Adult is not a struct, it has no table
+
+-- Score is not a struct, it has no table
+
+-- Role is not a struct, it has no table
+
+-- Consent is not a struct, it has no table
+
+-- A user of the service
+CREATE TABLE `User` (
+	`id` VARCHAR(32) NOT NULL,
+	`role` TEXT NOT NULL DEFAULT ('MEMBER'),
+	`age` INT NULL,
+	`score` DOUBLE NOT NULL,
+	`active` BOOLEAN NOT NULL DEFAULT true,
+	`nickname` TEXT NULL DEFAULT ('o''brien'),
+	`type` TEXT NULL,
+	`default` BOOLEAN NULL,
+	`tags` JSON NULL,
+	`labels` JSON NULL,
+	`created` DATETIME(3) NOT NULL,
+	`avatar` BLOB NULL,
+	`extra` JSON NULL,
+	`manager` JSON NULL
+);
+
+CREATE TABLE `Admin` (
+	`id` VARCHAR(32) NOT NULL,
+	`role` TEXT NOT NULL DEFAULT ('MEMBER'),
+	`age` INT NULL,
+	`score` DOUBLE NOT NULL,
+	`active` BOOLEAN NOT NULL DEFAULT true,
+	`nickname` TEXT NULL DEFAULT ('o''brien'),
+	`type` TEXT NULL,
+	`default` BOOLEAN NULL,
+	`tags` JSON NULL,
+	`labels` JSON NULL,
+	`created` DATETIME(3) NOT NULL,
+	`avatar` BLOB NULL,
+	`extra` JSON NULL,
+	`manager` JSON NULL,
+	`level` INT NOT NULL,
+	`region` TEXT NOT NULL,
+	`sessionKey` CHAR(36) NOT NULL
+);
+
+-- Users is not a struct, it has no table
+
+-- UserIndex is not a struct, it has no table
+
+-- Member is not a struct, it has no table
+
+CREATE TABLE `Group` (
+	`name` TEXT NOT NULL,
+	`members` JSON NOT NULL,
+	`owner` JSON NULL,
+	`parent` JSON NULL
+);
+
+CREATE TABLE `ResourceError` (
+	`code` INT NOT NULL,
+	`message` TEXT NOT NULL
+);
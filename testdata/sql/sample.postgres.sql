@@ -0,0 +1,71 @@
+-- This file generated by parsec-rdl-gen for postgres
+
+-- UserId is not a struct, it has no table
+
+-- Region is not a struct, it has no table
+
+-- Age is not a struct, it has no table
+
+-- Adult is not a struct, it has no table
+
+-- Score is not a struct, it has no table
+
+-- Role is not a struct, it has no table
+
+-- Consent is not a struct, it has no table
+
+-- A user of the service
+CREATE TABLE "User" (
+	"id" VARCHAR(32) NOT NULL,
+	"role" TEXT NOT NULL DEFAULT 'MEMBER',
+	"age" INTEGER NULL,
+	"score" DOUBLE PRECISION NOT NULL,
+	"active" BOOLEAN NOT NULL DEFAULT true,
+	"nickname" TEXT NULL DEFAULT 'o''brien',
+	"type" TEXT NULL,
+	"default" BOOLEAN NULL,
+	"tags" JSONB NULL,
+	"labels" JSONB NULL,
+	"created" TIMESTAMP WITH TIME ZONE NOT NULL,
+	"avatar" BYTEA NULL,
+	"extra" JSONB NULL,
+	"manager" JSONB NULL
+);
+
+CREATE TABLE "Admin" (
+	"id" VARCHAR(32) NOT NULL,
+	"role" TEXT NOT NULL DEFAULT 'MEMBER',
+	"age" INTEGER NULL,
+	"score" DOUBLE PRECISION NOT NULL,
+	"active" BOOLEAN NOT NULL DEFAULT true,
+	"nickname" TEXT NULL DEFAULT 'o''brien',
+	"type" TEXT NULL,
+	"default" BOOLEAN NULL,
+	"tags" JSONB NULL,
+	"labels" JSONB NULL,
+	"created" TIMESTAMP WITH TIME ZONE NOT NULL,
+	"avatar" BYTEA NULL,
+	"extra" JSONB NULL,
+	"manager" JSONB NULL,
+	"level" INTEGER NOT NULL,
+	"region" TEXT NOT NULL,
+	"sessionKey" UUID NOT NULL
+);
+
+-- Users is not a struct, it has no table
+
+-- UserIndex is not a struct, it has no table
+
+-- Member is not a struct, it has no table
+
+CREATE TABLE "Group" (
+	"name" TEXT NOT NULL,
+	"members" JSONB NOT NULL,
+	"owner" JSONB NULL,
+	"parent" JSONB NULL
+);
+
+CREATE TABLE "ResourceError" (
+	"code" INTEGER NOT NULL,
+	"message" TEXT NOT NULL
+);
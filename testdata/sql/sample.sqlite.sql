@@ -0,0 +1,71 @@
+-- This file generated by parsec-rdl-gen for sqlite
+
+-- UserId is not a struct, it has no table
+
+-- Region is not a struct, it has no table
+
+-- Age is not a struct, it has no table
+
+-- Adult is not a struct, it has no table
+
+-- Score is not a struct, it has no table
+
+-- Role is not a struct, it has no table
+
+-- Consent is not a struct, it has no table
+
+-- A user of the service
+CREATE TABLE "User" (
+	"id" VARCHAR(32) NOT NULL,
+	"role" TEXT NOT NULL DEFAULT 'MEMBER',
+	"age" INTEGER NULL,
+	"score" REAL NOT NULL,
+	"active" INTEGER NOT NULL DEFAULT true,
+	"nickname" TEXT NULL DEFAULT 'o''brien',
+	"type" TEXT NULL,
+	"default" INTEGER NULL,
+	"tags" TEXT NULL,
+	"labels" TEXT NULL,
+	"created" TEXT NOT NULL,
+	"avatar" BLOB NULL,
+	"extra" TEXT NULL,
+	"manager" TEXT NULL
+);
+
+CREATE TABLE "Admin" (
+	"id" VARCHAR(32) NOT NULL,
+	"role" TEXT NOT NULL DEFAULT 'MEMBER',
+	"age" INTEGER NULL,
+	"score" REAL NOT NULL,
+	"active" INTEGER NOT NULL DEFAULT true,
+	"nickname" TEXT NULL DEFAULT 'o''brien',
+	"type" TEXT NULL,
+	"default" INTEGER NULL,
+	"tags" TEXT NULL,
+	"labels" TEXT NULL,
+	"created" TEXT NOT NULL,
+	"avatar" BLOB NULL,
+	"extra" TEXT NULL,
+	"manager" TEXT NULL,
+	"level" INTEGER NOT NULL,
+	"region" TEXT NOT NULL,
+	"sessionKey" TEXT NOT NULL
+);
+
+-- Users is not a struct, it has no table
+
+-- UserIndex is not a struct, it has no table
+
+-- Member is not a struct, it has no table
+
+CREATE TABLE "Group" (
+	"name" TEXT NOT NULL,
+	"members" TEXT NOT NULL,
+	"owner" TEXT NULL,
+	"parent" TEXT NULL
+);
+
+CREATE TABLE "ResourceError" (
+	"code" INTEGER NOT NULL,
+	"message" TEXT NOT NULL
+);
@@ -0,0 +1,397 @@
+//
+// This file generated by parsec-rdl-gen
+//
+
+import Foundation
+
+public typealias UserId = String
+
+public typealias Region = String
+
+public typealias Age = Int32
+
+public typealias Adult = Age
+
+public typealias Score = Double
+
+public enum Role: String, Codable {
+    case admin = "ADMIN"
+    /// a regular user
+    case member = "MEMBER"
+    case readOnly = "READ_ONLY"
+}
+
+public enum Consent: String, Codable {
+    case yes = "YES"
+    case no = "NO"
+    case null = "NULL"
+}
+
+/// A user of the service
+public struct User: Codable {
+    /// the user id
+    public var id: UserId
+    public var role: Role
+    /// the age
+    public var age: Age?
+    public var score: Score
+    public var active: Bool
+    public var nickname: String?
+    public var type: String?
+    public var `default`: Bool?
+    public var tags: [String]?
+    public var labels: [String: Int64]?
+    public var created: Date
+    public var avatar: Data?
+    public var extra: JSONValue?
+    public var manager: User? {
+        get { _manager.value }
+        set { _manager = Indirect(newValue) }
+    }
+    private var _manager: Indirect<User?>
+
+    public init(id: UserId, role: Role = .member, age: Age? = nil, score: Score, active: Bool = true, nickname: String? = "o'brien", type: String? = nil, `default`: Bool? = nil, tags: [String]? = nil, labels: [String: Int64]? = nil, created: Date, avatar: Data? = nil, extra: JSONValue? = nil, manager: User? = nil) {
+        self.id = id
+        self.role = role
+        self.age = age
+        self.score = score
+        self.active = active
+        self.nickname = nickname
+        self.type = type
+        self.`default` = `default`
+        self.tags = tags
+        self.labels = labels
+        self.created = created
+        self.avatar = avatar
+        self.extra = extra
+        _manager = Indirect(manager)
+    }
+
+    private enum CodingKeys: String, CodingKey {
+        case id, role, age, score, active, nickname, type, `default`, tags, labels, created, avatar, extra, manager
+    }
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.container(keyedBy: CodingKeys.self)
+        id = try container.decode(UserId.self, forKey: .id)
+        role = try container.decodeIfPresent(Role.self, forKey: .role) ?? .member
+        age = try container.decodeIfPresent(Age.self, forKey: .age)
+        score = try container.decode(Score.self, forKey: .score)
+        active = try container.decodeIfPresent(Bool.self, forKey: .active) ?? true
+        nickname = try container.decodeIfPresent(String.self, forKey: .nickname) ?? "o'brien"
+        type = try container.decodeIfPresent(String.self, forKey: .type)
+        `default` = try container.decodeIfPresent(Bool.self, forKey: .default)
+        tags = try container.decodeIfPresent([String].self, forKey: .tags)
+        labels = try container.decodeIfPresent([String: Int64].self, forKey: .labels)
+        created = try container.decode(Date.self, forKey: .created)
+        avatar = try container.decodeIfPresent(Data.self, forKey: .avatar)
+        extra = try container.decodeIfPresent(JSONValue.self, forKey: .extra)
+        _manager = try Indirect(container.decodeIfPresent(User.self, forKey: .manager))
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.container(keyedBy: CodingKeys.self)
+        try container.encode(id, forKey: .id)
+        try container.encode(role, forKey: .role)
+        try container.encodeIfPresent(age, forKey: .age)
+        try container.encode(score, forKey: .score)
+        try container.encode(active, forKey: .active)
+        try container.encodeIfPresent(nickname, forKey: .nickname)
+        try container.encodeIfPresent(type, forKey: .type)
+        try container.encodeIfPresent(`default`, forKey: .default)
+        try container.encodeIfPresent(tags, forKey: .tags)
+        try container.encodeIfPresent(labels, forKey: .labels)
+        try container.encode(created, forKey: .created)
+        try container.encodeIfPresent(avatar, forKey: .avatar)
+        try container.encodeIfPresent(extra, forKey: .extra)
+        try container.encodeIfPresent(manager, forKey: .manager)
+    }
+}
+
+public struct Admin: Codable {
+    /// the user id
+    public var id: UserId
+    public var role: Role
+    /// the age
+    public var age: Age?
+    public var score: Score
+    public var active: Bool
+    public var nickname: String?
+    public var type: String?
+    public var `default`: Bool?
+    public var tags: [String]?
+    public var labels: [String: Int64]?
+    public var created: Date
+    public var avatar: Data?
+    public var extra: JSONValue?
+    public var manager: User?
+    public var level: Int32
+    public var region: Region
+    public var sessionKey: UUID
+
+    public init(id: UserId, role: Role = .member, age: Age? = nil, score: Score, active: Bool = true, nickname: String? = "o'brien", type: String? = nil, `default`: Bool? = nil, tags: [String]? = nil, labels: [String: Int64]? = nil, created: Date, avatar: Data? = nil, extra: JSONValue? = nil, manager: User? = nil, level: Int32, region: Region, sessionKey: UUID) {
+        self.id = id
+        self.role = role
+        self.age = age
+        self.score = score
+        self.active = active
+        self.nickname = nickname
+        self.type = type
+        self.`default` = `default`
+        self.tags = tags
+        self.labels = labels
+        self.created = created
+        self.avatar = avatar
+        self.extra = extra
+        self.manager = manager
+        self.level = level
+        self.region = region
+        self.sessionKey = sessionKey
+    }
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.container(keyedBy: CodingKeys.self)
+        id = try container.decode(UserId.self, forKey: .id)
+        role = try container.decodeIfPresent(Role.self, forKey: .role) ?? .member
+        age = try container.decodeIfPresent(Age.self, forKey: .age)
+        score = try container.decode(Score.self, forKey: .score)
+        active = try container.decodeIfPresent(Bool.self, forKey: .active) ?? true
+        nickname = try container.decodeIfPresent(String.self, forKey: .nickname) ?? "o'brien"
+        type = try container.decodeIfPresent(String.self, forKey: .type)
+        `default` = try container.decodeIfPresent(Bool.self, forKey: .default)
+        tags = try container.decodeIfPresent([String].self, forKey: .tags)
+        labels = try container.decodeIfPresent([String: Int64].self, forKey: .labels)
+        created = try container.decode(Date.self, forKey: .created)
+        avatar = try container.decodeIfPresent(Data.self, forKey: .avatar)
+        extra = try container.decodeIfPresent(JSONValue.self, forKey: .extra)
+        manager = try container.decodeIfPresent(User.self, forKey: .manager)
+        level = try container.decode(Int32.self, forKey: .level)
+        region = try container.decode(Region.self, forKey: .region)
+        sessionKey = try container.decode(UUID.self, forKey: .sessionKey)
+    }
+}
+
+public typealias Users = [User]
+
+public typealias UserIndex = [UserId: User]
+
+public enum Member: Codable {
+    case user(User)
+    case admin(Admin)
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.singleValueContainer()
+        if let value = try? container.decode(User.self) {
+            self = .user(value)
+        } else if let value = try? container.decode(Admin.self) {
+            self = .admin(value)
+        } else {
+            throw DecodingError.dataCorruptedError(in: container, debugDescription: "no variant of Member matches")
+        }
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.singleValueContainer()
+        switch self {
+        case .user(let value):
+            try container.encode(value)
+        case .admin(let value):
+            try container.encode(value)
+        }
+    }
+}
+
+public struct Group: Codable {
+    public var name: String
+    public var members: Users
+    public var owner: Member?
+    public var parent: Group? {
+        get { _parent.value }
+        set { _parent = Indirect(newValue) }
+    }
+    private var _parent: Indirect<Group?>
+
+    public init(name: String, members: Users, owner: Member? = nil, parent: Group? = nil) {
+        self.name = name
+        self.members = members
+        self.owner = owner
+        _parent = Indirect(parent)
+    }
+
+    private enum CodingKeys: String, CodingKey {
+        case name, members, owner, parent
+    }
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.container(keyedBy: CodingKeys.self)
+        name = try container.decode(String.self, forKey: .name)
+        members = try container.decode(Users.self, forKey: .members)
+        owner = try container.decodeIfPresent(Member.self, forKey: .owner)
+        _parent = try Indirect(container.decodeIfPresent(Group.self, forKey: .parent))
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.container(keyedBy: CodingKeys.self)
+        try container.encode(name, forKey: .name)
+        try container.encode(members, forKey: .members)
+        try container.encodeIfPresent(owner, forKey: .owner)
+        try container.encodeIfPresent(parent, forKey: .parent)
+    }
+}
+
+public struct ResourceError: Codable {
+    public var code: Int32
+    public var message: String
+
+    public init(code: Int32, message: String) {
+        self.code = code
+        self.message = message
+    }
+}
+
+extension URLRequest {
+    /// Get a user
+    /// GET /users/{id}
+    public static func getUser(baseURL: URL, id: UserId, fields: String? = nil, ifNoneMatch: String? = nil) throws -> URLRequest {
+        var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!
+        components.percentEncodedPath += "/users/" + pathSegment(id)
+        var queryItems: [URLQueryItem] = []
+        if let fields = fields {
+            queryItems.append(URLQueryItem(name: "fields", value: fields))
+        }
+        if !queryItems.isEmpty {
+            components.queryItems = queryItems
+        }
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "GET"
+        if let ifNoneMatch = ifNoneMatch {
+            request.setValue(ifNoneMatch, forHTTPHeaderField: "If-None-Match")
+        }
+        return request
+    }
+
+    /// POST /users
+    public static func postUser(baseURL: URL, user: User, requestId: String? = nil) throws -> URLRequest {
+        var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!
+        components.percentEncodedPath += "/users"
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "POST"
+        if let requestId = requestId {
+            request.setValue(requestId, forHTTPHeaderField: "X-Request-Id")
+        }
+        let encoder = JSONEncoder()
+        encoder.dateEncodingStrategy = .iso8601
+        request.httpBody = try encoder.encode(user)
+        request.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        return request
+    }
+
+    /// GET /users?limit={limit}
+    public static func listUsers(baseURL: URL, limit: Int32? = nil) throws -> URLRequest {
+        var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!
+        components.percentEncodedPath += "/users"
+        var queryItems: [URLQueryItem] = []
+        if let limit = limit {
+            queryItems.append(URLQueryItem(name: "limit", value: String(describing: limit)))
+        }
+        if !queryItems.isEmpty {
+            components.queryItems = queryItems
+        }
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "GET"
+        return request
+    }
+
+    /// PUT /users/{id}
+    public static func putUser(baseURL: URL, id: UserId, user: User) throws -> URLRequest {
+        var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!
+        components.percentEncodedPath += "/users/" + pathSegment(id)
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "PUT"
+        let encoder = JSONEncoder()
+        encoder.dateEncodingStrategy = .iso8601
+        request.httpBody = try encoder.encode(user)
+        request.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        return request
+    }
+
+    /// DELETE /users/{id}
+    public static func removeUser(baseURL: URL, id: UserId) throws -> URLRequest {
+        var components = URLComponents(url: baseURL, resolvingAgainstBaseURL: false)!
+        components.percentEncodedPath += "/users/" + pathSegment(id)
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "DELETE"
+        return request
+    }
+}
+
+/// The output headers of GET /users/{id}.
+public struct GetUserOutputs {
+    /// the version of the user
+    public var etag: String?
+
+    public init(response: HTTPURLResponse) {
+        etag = response.value(forHTTPHeaderField: "ETag")
+    }
+}
+
+/// pathSegment percent-encodes a path parameter.
+fileprivate func pathSegment(_ value: String) -> String {
+    var allowed = CharacterSet.urlPathAllowed
+    allowed.remove("/")
+    return value.addingPercentEncoding(withAllowedCharacters: allowed) ?? value
+}
+
+/// Indirect keeps a value on the heap, so that structs can contain themselves.
+public final class Indirect<Value> {
+    public let value: Value
+
+    public init(_ value: Value) {
+        self.value = value
+    }
+}
+
+/// JSONValue is any JSON value.
+public enum JSONValue: Codable {
+    case null
+    case bool(Bool)
+    case number(Double)
+    case string(String)
+    case array([JSONValue])
+    case object([String: JSONValue])
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.singleValueContainer()
+        if container.decodeNil() {
+            self = .null
+        } else if let value = try? container.decode(Bool.self) {
+            self = .bool(value)
+        } else if let value = try? container.decode(Double.self) {
+            self = .number(value)
+        } else if let value = try? container.decode(String.self) {
+            self = .string(value)
+        } else if let value = try? container.decode([JSONValue].self) {
+            self = .array(value)
+        } else {
+            self = .object(try container.decode([String: JSONValue].self))
+        }
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.singleValueContainer()
+        switch self {
+        case .null:
+            try container.encodeNil()
+        case .bool(let value):
+            try container.encode(value)
+        case .number(let value):
+            try container.encode(value)
+        case .string(let value):
+            try container.encode(value)
+        case .array(let value):
+            try container.encode(value)
+        case .object(let value):
+            try container.encode(value)
+        }
+    }
+}
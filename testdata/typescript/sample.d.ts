@@ -0,0 +1,88 @@
+//
+// This file generated by parsec-rdl-gen
+//
+
+export type UserId = string;
+
+export type Region = "us-east" | "us-west";
+
+export type Age = number;
+
+export type Adult = Age;
+
+export type Score = number;
+
+export enum Role {
+  ADMIN = "ADMIN",
+  /** a regular user */
+  MEMBER = "MEMBER",
+  READ_ONLY = "READ_ONLY",
+}
+
+export enum Consent {
+  YES = "YES",
+  NO = "NO",
+  NULL = "NULL",
+}
+
+/** A user of the service */
+export interface User {
+  /** the user id */
+  id: UserId;
+  role: Role;
+  /** the age */
+  age?: Age;
+  score: Score;
+  active: boolean;
+  nickname?: string;
+  type?: string;
+  default?: boolean;
+  tags?: Array<string>;
+  labels?: Record<string, number>;
+  created: string;
+  avatar?: string;
+  extra?: any;
+  manager?: User;
+}
+
+export interface Admin extends User {
+  level: number;
+  region: Region;
+  sessionKey: string;
+}
+
+export type Users = Array<User>;
+
+export type UserIndex = Record<UserId, User>;
+
+export type Member = User | Admin;
+
+export interface Group {
+  name: string;
+  members: Users;
+  owner?: Member;
+  parent?: Group;
+}
+
+export interface ResourceError {
+  code: number;
+  message: string;
+}
+
+/**
+ * Get a user
+ * GET /users/{id}
+ */
+export function getUser(id: UserId, fields?: string, ifNoneMatch?: string, init?: RequestInit): Promise<User>;
+
+/** POST /users */
+export function postUser(user: User, requestId?: string, init?: RequestInit): Promise<User>;
+
+/** GET /users?limit={limit} */
+export function listUsers(limit?: number, init?: RequestInit): Promise<Users>;
+
+/** PUT /users/{id} */
+export function putUser(id: UserId, user: User, init?: RequestInit): Promise<User>;
+
+/** DELETE /users/{id} */
+export function removeUser(id: UserId, init?: RequestInit): Promise<void>;
@@ -6,6 +6,7 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/ardielle/ardielle-go/rdl"
 	"os"
@@ -125,6 +126,45 @@ func FlattenedFields(reg rdl.TypeRegistry, t *rdl.Type) []*rdl.StructFieldDef {
 	return addFields(reg, make([]*rdl.StructFieldDef, 0), t)
 }
 
+// IsCurrency reports whether the type is a number type holding monetary
+// amounts, or derives from one.
+func IsCurrency(reg rdl.TypeRegistry, ref rdl.TypeRef) bool {
+	for t := reg.FindType(ref); t != nil && t.NumberTypeDef != nil; t = reg.FindType(t.NumberTypeDef.Type) {
+		if t.NumberTypeDef.Currency {
+			return true
+		}
+		if reg.IsBaseTypeName(rdl.TypeRef(t.NumberTypeDef.Name)) {
+			break
+		}
+	}
+	return false
+}
+
+// NumberString returns the number as written in a schema or in source code.
+func NumberString(n *rdl.Number) string {
+	switch n.Variant {
+	case rdl.NumberVariantInt8:
+		return fmt.Sprint(*n.Int8)
+	case rdl.NumberVariantInt16:
+		return fmt.Sprint(*n.Int16)
+	case rdl.NumberVariantInt32:
+		return fmt.Sprint(*n.Int32)
+	case rdl.NumberVariantInt64:
+		return fmt.Sprint(*n.Int64)
+	case rdl.NumberVariantFloat32:
+		return fmt.Sprint(*n.Float32)
+	default:
+		return fmt.Sprint(*n.Float64)
+	}
+}
+
+// QuoteYAML returns s as a YAML double-quoted scalar, which is never read as
+// another type than a string.
+func QuoteYAML(s string) string {
+	q, _ := json.Marshal(s)
+	return string(q)
+}
+
 func Capitalize(text string) string {
 	return strings.ToUpper(text[0:1]) + text[1:]
 }
@@ -171,11 +211,11 @@ func GetOutputPathInfo(outdir string, name string, ext string) (string, string)
 	return sname, path
 }
 
-func generationHeader(banner string) string {
+func GoGenerationHeader(banner string) string {
 	return fmt.Sprintf("//\n// This file generated by %s\n//", banner)
 }
 
-func generationPackage(schema *rdl.Schema) string {
+func GoGenerationPackage(schema *rdl.Schema) string {
 	pkg := "main"
 	if schema.Name != "" {
 		pkg = strings.ToLower(string(schema.Name))
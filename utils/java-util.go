@@ -285,37 +285,23 @@ public class ResourceException extends RuntimeException {
 
 }
 `
-func GetUserDefinedTypeAnnotations(userDefinedType rdl.TypeRef, schemaTypes []*rdl.Type) map[rdl.ExtendedAnnotation]string {
-	for _, schemaType := range schemaTypes {
+func GetUserDefinedTypeAnnotations(userDefinedType rdl.TypeRef, schema *rdl.Schema) map[rdl.ExtendedAnnotation]string {
+	if schemaType := schema.TypeByName(string(userDefinedType)); schemaType != nil {
 		switch schemaType.Variant {
 		case rdl.TypeVariantStructTypeDef:
-			if string(userDefinedType) == string(schemaType.StructTypeDef.Name) {
-				return schemaType.StructTypeDef.Annotations
-			}
+			return schemaType.StructTypeDef.Annotations
 		case rdl.TypeVariantStringTypeDef:
-			if string(userDefinedType) == string(schemaType.StringTypeDef.Name) {
-				return schemaType.StringTypeDef.Annotations
-			}
+			return schemaType.StringTypeDef.Annotations
 		case rdl.TypeVariantMapTypeDef:
-			if string(userDefinedType) == string(schemaType.MapTypeDef.Name) {
-				return schemaType.MapTypeDef.Annotations
-			}
+			return schemaType.MapTypeDef.Annotations
 		case rdl.TypeVariantArrayTypeDef:
-			if string(userDefinedType) == string(schemaType.ArrayTypeDef.Name) {
-				return schemaType.ArrayTypeDef.Annotations
-			}
+			return schemaType.ArrayTypeDef.Annotations
 		case rdl.TypeVariantBytesTypeDef:
-			if string(userDefinedType) == string(schemaType.BytesTypeDef.Name) {
-				return schemaType.BytesTypeDef.Annotations
-			}
+			return schemaType.BytesTypeDef.Annotations
 		case rdl.TypeVariantNumberTypeDef:
-			if string(userDefinedType) == string(schemaType.NumberTypeDef.Name) {
-				return schemaType.NumberTypeDef.Annotations
-			}
+			return schemaType.NumberTypeDef.Annotations
 		case rdl.TypeVariantUnionTypeDef:
-			if string(userDefinedType) == string(schemaType.UnionTypeDef.Name) {
-				return schemaType.UnionTypeDef.Annotations
-			}
+			return schemaType.UnionTypeDef.Annotations
 		}
 	}
 	return make(map[rdl.ExtendedAnnotation]string, 0)